@@ -7,16 +7,25 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"todo_app_go/internal/admin"
+	"todo_app_go/internal/auth"
 	"todo_app_go/internal/cache"
 	"todo_app_go/internal/config"
 	"todo_app_go/internal/database"
 	"todo_app_go/internal/events"
+	"todo_app_go/internal/eventsourcing"
 	"todo_app_go/internal/handlers"
+	"todo_app_go/internal/health"
 	"todo_app_go/internal/logger"
 	"todo_app_go/internal/middleware"
 	"todo_app_go/internal/models"
+	"todo_app_go/internal/notify"
+	"todo_app_go/internal/policy"
+	"todo_app_go/internal/projections"
 	"todo_app_go/internal/services"
+	"todo_app_go/internal/tracing"
 
 	// Swagger
 	_ "todo_app_go/docs"
@@ -25,7 +34,6 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 
 	"github.com/gin-gonic/gin"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
@@ -35,6 +43,14 @@ import (
 // @host localhost:8080
 // @BasePath /api/v1
 // @schemes http
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT bearer token or API key: "Bearer <token>"
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key created with POST /apikeys
 func main() {
 	// Загружаем конфигурацию
 	cfg, err := config.Load()
@@ -44,7 +60,7 @@ func main() {
 	}
 
 	// Инициализируем логгер
-	if err := logger.Init(cfg.Log.Level, cfg.Log.Format); err != nil {
+	if err := logger.Init(cfg.Log); err != nil {
 		fmt.Printf("Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
@@ -52,9 +68,18 @@ func main() {
 
 	logger.Info("Starting Todo Application")
 
+	// Инициализируем трассировку
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+
 	// Инициализируем базу данных
 	db, err := database.NewSQLiteDB(database.Config{
-		Path: cfg.Database.Path,
+		Path:            cfg.Database.Path,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
 	})
 	if err != nil {
 		logger.Fatal("Failed to initialize database", zap.Error(err))
@@ -65,10 +90,32 @@ func main() {
 	if err := database.EnsureTodosTableAndColumn(db); err != nil {
 		logger.Fatal("Failed to ensure todos table and column", zap.Error(err))
 	}
+	if err := database.EnsureShareTables(db); err != nil {
+		logger.Fatal("Failed to ensure share tables", zap.Error(err))
+	}
+
+	// Таблица outbox для транзакционной публикации событий
+	if cfg.Outbox.Enabled {
+		if err := database.EnsureOutboxTable(db); err != nil {
+			logger.Fatal("Failed to ensure outbox table", zap.Error(err))
+		}
+	}
 
 	// Инициализируем репозиторий
 	repo := models.NewSQLiteTodoRepository(db)
 
+	// Event sourcing: журнал events — источник истины, todos — его проекция
+	var esRepo *eventsourcing.Repository
+	if cfg.Database.EventSourcing {
+		if err := database.EnsureEventsTable(db); err != nil {
+			logger.Fatal("Failed to ensure events table", zap.Error(err))
+		}
+		esRepo = eventsourcing.NewRepository(db, cfg.Outbox.Enabled)
+		if _, err := esRepo.Bootstrap(context.Background()); err != nil {
+			logger.Fatal("Failed to reconcile event log", zap.Error(err))
+		}
+	}
+
 	// Инициализируем Redis кэш (опционально)
 	var redisCache *cache.RedisCache
 	if cfg.Redis.Host != "" {
@@ -80,22 +127,90 @@ func main() {
 		}
 	}
 
-	// Инициализируем Kafka producer (опционально)
-	var kafkaProducer *events.KafkaProducer
-	if len(cfg.Kafka.Brokers) > 0 {
-		kafkaProducer, err = events.NewKafkaProducer(cfg.Kafka)
+	// Фоновые задачи останавливаются при graceful shutdown
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
+
+	go database.NewStatsCollector(db, cfg.Database.Type).Run(bgCtx, cfg.Database.StatsInterval)
+
+	// Инициализируем шину событий (опционально): kafka, nats, memory или none
+	publisher, err := events.NewPublisher(cfg.Events, cfg.Kafka)
+	if err != nil {
+		logger.Warn("Failed to initialize event publisher, continuing without events",
+			zap.String("driver", cfg.Events.Driver), zap.Error(err))
+	}
+	if publisher != nil {
+		defer publisher.Close()
+	}
+
+	// In-memory шина живёт внутри процесса, поэтому проекции запускаются здесь же
+	if bus, ok := publisher.(*events.MemoryBus); ok {
+		if err := database.EnsureProjectionTables(db); err != nil {
+			logger.Fatal("Failed to ensure projection tables", zap.Error(err))
+		}
+		dispatcher := projections.NewDispatcher()
+		projections.RegisterBuiltin(dispatcher, db)
+		// Подписка регистрируется до старта сервера, чтобы ранние события не терялись
+		sub, err := bus.Register()
 		if err != nil {
-			logger.Warn("Failed to initialize Kafka producer, continuing without events", zap.Error(err))
-		} else {
-			defer kafkaProducer.Close()
+			logger.Fatal("Failed to subscribe projections", zap.Error(err))
 		}
+		go sub.Run(bgCtx, dispatcher.Dispatch)
+	}
+
+	// Без outbox события публикуются из запроса: асинхронный publisher убирает задержку брокера
+	// из HTTP-ответа. Relay outbox отмечает сообщения отправленными, поэтому ему нужна синхронная публикация.
+	servicePublisher := publisher
+	var asyncPublisher *events.AsyncPublisher
+	if publisher != nil && cfg.Events.Async.Enabled {
+		asyncPublisher = events.NewAsyncPublisher(publisher, cfg.Events.Async)
+		servicePublisher = asyncPublisher
 	}
 
 	// Инициализируем сервис
-	todoService := services.NewTodoService(repo, redisCache, kafkaProducer)
+	var todoService *services.TodoService
+	if esRepo != nil {
+		// Сервис передаёт свои события в журнал; в outbox они попадают, только если он включён
+		todoService = services.NewTodoService(esRepo, redisCache, nil).WithOutbox(esRepo).WithHistory(esRepo)
+		if !cfg.Outbox.Enabled && publisher != nil {
+			logger.Warn("Event sourcing without outbox: events are stored but not published")
+		}
+	} else {
+		todoService = services.NewTodoService(repo, redisCache, servicePublisher)
+	}
+
+	// Outbox: события пишутся в одной транзакции с todo, relay публикует их в шину событий
+	if cfg.Outbox.Enabled {
+		if esRepo == nil {
+			todoService.WithOutbox(repo)
+		}
+		if publisher != nil {
+			relay := events.NewOutboxRelay(models.NewSQLiteOutboxStore(db), publisher, cfg.Outbox)
+			go relay.Run(bgCtx)
+		} else {
+			logger.Warn("Outbox is enabled but event publisher is unavailable, events will be kept in outbox")
+		}
+	}
+
+	// Gauge открытых и выполненных todo: начальное значение из базы, дальше сервис
+	// обновляет их сам, периодическая сверка учитывает изменения других реплик
+	go todoService.RunTodoGaugeSync(bgCtx, cfg.Metrics.TodoGaugesInterval)
+
+	// Проверки зависимостей для /ready: без БД сервис не работает,
+	// без кэша и шины событий — работает в degraded режиме
+	healthRegistry := health.NewRegistry(cfg.Health.Timeout)
+	healthRegistry.Register(health.Checker{Name: "database", Check: health.DBCheck(db), Critical: true})
+	if redisCache != nil {
+		healthRegistry.Register(health.Checker{Name: "cache", Check: redisCache.Ping})
+	}
+	if pinger, ok := publisher.(events.Pinger); ok {
+		healthRegistry.Register(health.Checker{Name: "events", Check: pinger.Ping})
+	}
 
 	// Инициализируем хендлеры
-	todoHandler := handlers.NewTodoHandler(todoService)
+	todoHandler := handlers.NewTodoHandler(todoService).WithHealth(healthRegistry)
+	shareService := services.NewShareService(todoService, models.NewSQLiteShareRepository(db), models.NewSQLiteCommentRepository(db))
+	shareHandler := handlers.NewShareHandler(shareService)
 
 	// Настраиваем Gin
 	if cfg.Log.Level == "debug" {
@@ -104,40 +219,166 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	actor, err := middleware.Actor(cfg.Server.TrustedProxies)
+	if err != nil {
+		logger.Fatal("Invalid server.trusted_proxies", zap.Error(err))
+	}
+
 	router := gin.New()
 
 	// Middleware
 	router.Use(gin.Recovery())
+	router.Use(middleware.Metrics())
 	router.Use(middleware.CORSGin())
 	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(actor)
 	router.Use(middleware.Logger())
 	router.Use(middleware.Timeout(cfg.Server.ReadTimeout))
 
-	// Метрики
-	if cfg.Metrics.Enabled {
-		router.GET(cfg.Metrics.Path, gin.WrapH(promhttp.Handler()))
+	// API routes
+	api := router.Group("/api/v1")
+	// requireScope проверяет область доступа учётных данных; без аутентификации не действует
+	requireScope := func(string) gin.HandlerFunc { return func(c *gin.Context) { c.Next() } }
+	var apiKeyHandler *handlers.APIKeyHandler
+	var workspaceHandler *handlers.WorkspaceHandler
+	if cfg.Auth.Enabled {
+		validator, err := auth.NewValidator(cfg.Auth.JWT)
+		if err != nil {
+			logger.Fatal("Failed to initialize JWT validator", zap.Error(err))
+		}
+		if err := database.EnsureAPIKeysTable(db); err != nil {
+			logger.Fatal("Failed to ensure API keys table", zap.Error(err))
+		}
+		if err := database.EnsureWorkspaceTables(db); err != nil {
+			logger.Fatal("Failed to ensure workspace tables", zap.Error(err))
+		}
+		apiKeyService := services.NewAPIKeyService(models.NewSQLiteAPIKeyRepository(db))
+		apiKeyHandler = handlers.NewAPIKeyHandler(apiKeyService)
+
+		workspaceRepo := models.NewSQLiteWorkspaceRepository(db)
+		accessPolicy := policy.New()
+		todoService.WithWorkspaces(workspaceRepo, accessPolicy)
+		workspaceHandler = handlers.NewWorkspaceHandler(services.NewWorkspaceService(workspaceRepo, accessPolicy))
+
+		api.Use(middleware.Auth(validator, apiKeyService))
+		requireScope = middleware.RequireScope
+	} else {
+		logger.Warn("Authentication is disabled, API is open to everyone")
 	}
+	// Маршруты todo одинаковы для личного списка и для списка пространства
+	registerTodoRoutes := func(group *gin.RouterGroup) {
+		todosRead := group.Group("/todos", requireScope(auth.ScopeTodosRead))
+		{
+			todosRead.GET("", todoHandler.GetAllTodos)
+			todosRead.GET("/:id", todoHandler.GetTodo)
+			todosRead.GET("/:id/comments", shareHandler.ListComments)
+		}
+		todosWrite := group.Group("/todos", requireScope(auth.ScopeTodosWrite))
+		{
+			todosWrite.POST("", todoHandler.CreateTodo)
+			todosWrite.PUT("/:id", todoHandler.UpdateTodo)
+			todosWrite.DELETE("/:id", todoHandler.DeleteTodo)
+			todosWrite.POST("/:id/comments", shareHandler.AddComment)
+			todosWrite.POST("/:id/share", shareHandler.ShareTodo)
+		}
+		group.GET("/shares", requireScope(auth.ScopeTodosRead), shareHandler.ListShares)
+		group.POST("/shares", requireScope(auth.ScopeTodosWrite), shareHandler.ShareList)
+		group.DELETE("/shares/:share_id", requireScope(auth.ScopeTodosWrite), shareHandler.RevokeShare)
+	}
+	{
+		registerTodoRoutes(api)
+		// API-ключи и пространства принадлежат пользователям, поэтому доступны только с аутентификацией
+		if apiKeyHandler != nil {
+			apiKeys := api.Group("/apikeys", requireScope(auth.ScopeAdmin))
+			{
+				apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+				apiKeys.GET("", apiKeyHandler.ListAPIKeys)
+				apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+			}
+		}
+		if workspaceHandler != nil {
+			api.GET("/workspaces", requireScope(auth.ScopeTodosRead), workspaceHandler.ListWorkspaces)
+			api.POST("/workspaces", requireScope(auth.ScopeAdmin), workspaceHandler.CreateWorkspace)
+			api.POST("/invites/accept", requireScope(auth.ScopeAdmin), workspaceHandler.AcceptInvite)
 
-	// Health checks
-	router.GET("/health", todoHandler.HealthCheck)
-	router.GET("/ready", todoHandler.ReadyCheck)
+			workspace := api.Group("/workspaces/:workspace_id", middleware.Workspace())
+			{
+				registerTodoRoutes(workspace)
+				workspace.GET("/members", requireScope(auth.ScopeTodosRead), workspaceHandler.ListMembers)
+				workspace.POST("/invites", requireScope(auth.ScopeAdmin), workspaceHandler.CreateInvite)
+				workspace.PUT("/members/:user_id", requireScope(auth.ScopeAdmin), workspaceHandler.UpdateMember)
+				workspace.DELETE("/members/:user_id", requireScope(auth.ScopeAdmin), workspaceHandler.RemoveMember)
+			}
+		}
+	}
 
-	// API routes
-	api := router.Group("/api/v1")
+	// Публичные ссылки: токен ссылки — единственные учётные данные, middleware.Auth не применяется
+	shared := router.Group("/api/v1/shared")
 	{
-		todos := api.Group("/todos")
+		shared.GET("/:token", shareHandler.GetShared)
+		shared.POST("/:token/comments", shareHandler.AddSharedComment)
+	}
+
+	// Собственные учётные записи: выдают токены, которые проверяет middleware.Auth
+	if cfg.Auth.Local.Enabled {
+		if err := database.EnsureUserTables(db); err != nil {
+			logger.Fatal("Failed to ensure user tables", zap.Error(err))
+		}
+		issuer, err := auth.NewTokenIssuer(cfg.Auth.JWT, cfg.Auth.Local.AccessTokenTTL)
+		if err != nil {
+			logger.Fatal("Failed to initialize token issuer", zap.Error(err))
+		}
+		notifier, err := notify.New(cfg.Auth.Local)
+		if err != nil {
+			logger.Fatal("Failed to initialize notifier", zap.Error(err))
+		}
+		users := models.NewSQLiteUserRepository(db)
+		authService := services.NewAuthService(users, issuer, cfg.Auth.Local).WithNotifier(notifier)
+		authHandler := handlers.NewAuthHandler(authService)
+
+		authRoutes := router.Group("/auth")
 		{
-			todos.GET("", todoHandler.GetAllTodos)
-			todos.POST("", todoHandler.CreateTodo)
-			todos.GET("/:id", todoHandler.GetTodo)
-			todos.PUT("/:id", todoHandler.UpdateTodo)
-			todos.DELETE("/:id", todoHandler.DeleteTodo)
+			authRoutes.POST("/register", authHandler.Register)
+			authRoutes.POST("/login", authHandler.Login)
+			authRoutes.POST("/refresh", authHandler.Refresh)
+			authRoutes.POST("/logout", authHandler.Logout)
+			authRoutes.POST("/password/forgot", authHandler.ForgotPassword)
+			authRoutes.POST("/password/reset", authHandler.ResetPassword)
+		}
+
+		// Вход через SSO выпускает те же токены, что и вход по паролю
+		if cfg.Auth.OIDC.Enabled {
+			provider, err := auth.NewOIDCProvider(cfg.Auth.OIDC)
+			if err != nil {
+				logger.Fatal("Failed to initialize OIDC provider", zap.Error(err))
+			}
+			oidcService := services.NewOIDCService(authService, users, provider, cfg.Auth.OIDC)
+			oidcHandler := handlers.NewOIDCHandler(oidcService, cfg.Auth.OIDC.StateTTL)
+			authRoutes.GET("/oidc/login", oidcHandler.Login)
+			authRoutes.GET("/oidc/callback", oidcHandler.Callback)
 		}
+		if !cfg.Auth.Enabled {
+			logger.Warn("Local accounts are enabled but auth.enabled is false, issued tokens are not required by the API")
+		}
+	} else if cfg.Auth.OIDC.Enabled {
+		logger.Fatal("auth.oidc requires auth.local to issue tokens")
 	}
 
 	// Swagger UI
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Внутренний сервер: метрики, health checks и pprof не видны на публичном порту
+	healthRouter := gin.New()
+	healthRouter.Use(gin.Recovery())
+	healthRouter.GET("/health", todoHandler.HealthCheck)
+	healthRouter.GET("/ready", todoHandler.ReadyCheck)
+
+	adminServer := admin.NewServer(admin.Addr(cfg.Metrics), cfg.Metrics)
+	adminServer.Handle("/health", healthRouter)
+	adminServer.Handle("/ready", healthRouter)
+	adminServer.Start()
+
 	// Создаем HTTP сервер
 	srv := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
@@ -162,6 +403,10 @@ func main() {
 
 	logger.Info("Shutting down server...")
 
+	// Сначала /ready начинает отвечать 503, чтобы балансировщик перестал присылать новые запросы
+	healthRegistry.SetShuttingDown()
+	time.Sleep(cfg.Health.ShutdownDelay)
+
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
@@ -170,5 +415,23 @@ func main() {
 		logger.Error("Server forced to shutdown", zap.Error(err))
 	}
 
+	// Отправляем накопленные события, пока не истёк таймаут shutdown
+	if asyncPublisher != nil {
+		if err := asyncPublisher.Shutdown(ctx); err != nil {
+			logger.Error("Failed to flush pending events", zap.Error(err))
+		}
+	}
+	bgCancel()
+
+	// Admin-сервер останавливается последним, чтобы метрики и пробы были доступны во время остановки
+	if err := adminServer.Shutdown(ctx); err != nil {
+		logger.Error("Admin server forced to shutdown", zap.Error(err))
+	}
+
+	// Отправляем оставшиеся span'ы
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Failed to shutdown tracing", zap.Error(err))
+	}
+
 	logger.Info("Server exited")
 }
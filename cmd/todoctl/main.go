@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/events"
+	"todo_app_go/internal/eventsourcing"
+	"todo_app_go/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+const usage = `Usage: todoctl <command> [flags]
+
+Commands:
+  dlq-replay            Переотправить сообщения из DLQ в исходный топик
+  rebuild-projection    Пересобрать таблицу todos из журнала events (режим event sourcing)
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	// Загружаем конфигурацию
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Инициализируем логгер
+	if err := logger.Init(cfg.Log); err != nil {
+		fmt.Printf("Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Get().Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch os.Args[1] {
+	case "dlq-replay":
+		err = dlqReplay(ctx, cfg, os.Args[2:])
+	case "rebuild-projection":
+		err = rebuildProjection(ctx, cfg, os.Args[2:])
+	default:
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		logger.Error("Command failed", zap.String("command", os.Args[1]), zap.Error(err))
+		os.Exit(1)
+	}
+}
+
+func dlqReplay(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("dlq-replay", flag.ExitOnError)
+	limit := fs.Int("limit", 0, "максимум сообщений (0 — все)")
+	idle := fs.Duration("idle-timeout", 10*time.Second, "остановиться, если новых сообщений нет дольше")
+	dryRun := fs.Bool("dry-run", false, "только показать сообщения, не переотправляя их")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	replayed, err := events.ReplayDLQ(ctx, cfg.Kafka, events.ReplayOptions{
+		Limit:       *limit,
+		IdleTimeout: *idle,
+		DryRun:      *dryRun,
+	})
+	logger.Info("DLQ replay finished", zap.Int("replayed", replayed), zap.Bool("dry_run", *dryRun))
+	return err
+}
+
+func rebuildProjection(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("rebuild-projection", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !cfg.Database.EventSourcing {
+		return fmt.Errorf("database.event_sourcing is disabled, the todos table is not a projection")
+	}
+
+	db, err := database.NewSQLiteDB(database.Config{
+		Path:            cfg.Database.Path,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+	})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := database.EnsureTodosTableAndColumn(db); err != nil {
+		return err
+	}
+	if err := database.EnsureEventsTable(db); err != nil {
+		return err
+	}
+
+	// Изменения, сделанные без event sourcing, сначала попадают в журнал, иначе пересборка потеряла бы их
+	if _, err := eventsourcing.NewRepository(db, false).Bootstrap(ctx); err != nil {
+		return err
+	}
+
+	result, err := eventsourcing.Rebuild(ctx, db)
+	if err != nil {
+		return err
+	}
+	logger.Info("Projection rebuilt", zap.Int("events", result.Events), zap.Int("todos", result.Todos))
+	return nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"todo_app_go/internal/admin"
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/dedup"
+	"todo_app_go/internal/events"
+	"todo_app_go/internal/health"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/projections"
+	"todo_app_go/internal/tracing"
+
+	"go.uber.org/zap"
+)
+
+// Worker читает события из шины (events.driver) и обновляет read-модели через зарегистрированные проекции
+func main() {
+	// Загружаем конфигурацию
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Инициализируем логгер
+	if err := logger.Init(cfg.Log); err != nil {
+		fmt.Printf("Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Get().Sync()
+
+	logger.Info("Starting Todo Worker")
+
+	// Инициализируем трассировку
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+
+	// Инициализируем базу данных read-моделей
+	db, err := database.NewSQLiteDB(database.Config{
+		Path:            cfg.Database.Path,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize database", zap.Error(err))
+	}
+	defer db.Close()
+
+	if err := database.EnsureProjectionTables(db); err != nil {
+		logger.Fatal("Failed to ensure projection tables", zap.Error(err))
+	}
+
+	// Регистрируем проекции
+	dispatcher := projections.NewDispatcher()
+	stats := projections.RegisterBuiltin(dispatcher, db)
+
+	// Журнал обработанных событий: повторно доставленное событие не применяется дважды
+	var sqliteDedup *dedup.SQLiteStore
+	if cfg.Worker.Dedup.Enabled {
+		switch cfg.Worker.Dedup.Backend {
+		case dedup.BackendRedis:
+			store, err := dedup.NewRedisStore(cfg.Redis, cfg.Worker.Dedup.TTL)
+			if err != nil {
+				logger.Fatal("Failed to initialize dedup store", zap.Error(err))
+			}
+			defer store.Close()
+			dispatcher.WithDedup(store)
+		case dedup.BackendSQLite:
+			if err := database.EnsureProcessedEventsTable(db); err != nil {
+				logger.Fatal("Failed to ensure processed events table", zap.Error(err))
+			}
+			sqliteDedup = dedup.NewSQLiteStore(db, cfg.Worker.Dedup.TTL)
+			dispatcher.WithDedup(sqliteDedup)
+		default:
+			logger.Fatal("Unknown dedup backend", zap.String("backend", cfg.Worker.Dedup.Backend))
+		}
+		logger.Info("Event deduplication enabled",
+			zap.String("backend", cfg.Worker.Dedup.Backend),
+			zap.Duration("ttl", cfg.Worker.Dedup.TTL))
+	}
+
+	subscriber, err := events.NewSubscriber(cfg.Events, cfg.Kafka)
+	if err != nil {
+		logger.Fatal("Failed to initialize event subscriber", zap.String("driver", cfg.Events.Driver), zap.Error(err))
+	}
+
+	var ready atomic.Bool
+
+	// Worker готов, когда подписка запущена и доступны БД и брокер
+	healthRegistry := health.NewRegistry(cfg.Health.Timeout)
+	healthRegistry.Register(health.Checker{Name: "database", Check: health.DBCheck(db), Critical: true})
+	healthRegistry.Register(health.Checker{Name: "subscriber", Critical: true, Check: func(ctx context.Context) error {
+		if !ready.Load() {
+			return errors.New("subscriber is not running")
+		}
+		return nil
+	}})
+	if pinger, ok := subscriber.(events.Pinger); ok {
+		healthRegistry.Register(health.Checker{Name: "events", Check: pinger.Ping, Critical: true})
+	}
+
+	// Health, readiness, метрики и pprof worker; публичного API у worker нет,
+	// поэтому admin-сервер слушает worker.port
+	srv := admin.NewServer(fmt.Sprintf("%s:%d", cfg.Worker.Host, cfg.Worker.Port), cfg.Metrics)
+	srv.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if !isVerbose(r) {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+			return
+		}
+		writeJSON(w, http.StatusOK, healthRegistry.Check(r.Context()))
+	})
+	srv.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		report, ok := healthRegistry.Ready(r.Context())
+		response := health.Report{Status: health.ReadyStatus(report, ok)}
+		if isVerbose(r) {
+			response.Components = report.Components
+		}
+		if !ok {
+			writeJSON(w, http.StatusServiceUnavailable, response)
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+	})
+	srv.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		snapshot, err := stats.Snapshot(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to get stats"})
+			return
+		}
+		writeJSON(w, http.StatusOK, snapshot)
+	})
+
+	srv.Start()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go database.NewStatsCollector(db, cfg.Database.Type).Run(ctx, cfg.Database.StatsInterval)
+
+	// Redis удаляет ключи сам, из SQLite просроченные ключи удаляются периодически
+	if sqliteDedup != nil {
+		go purgeProcessedEvents(ctx, sqliteDedup, cfg.Worker.Dedup.CleanupInterval)
+	}
+
+	// Worker готов только после подключения к брокеру и снова не готов, когда подписка завершилась
+	if notifier, ok := subscriber.(events.SubscribeNotifier); ok {
+		notifier.OnSubscribed(func() {
+			logger.Info("Subscribed to events", zap.String("driver", cfg.Events.Driver))
+			ready.Store(true)
+		})
+	}
+
+	// Offset подтверждается только после того, как все проекции обработали событие
+	done := make(chan error, 1)
+	go func() {
+		if _, ok := subscriber.(events.SubscribeNotifier); !ok {
+			ready.Store(true)
+		}
+		err := subscriber.Subscribe(ctx, dispatcher.Dispatch)
+		ready.Store(false)
+		done <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutting down worker...")
+		healthRegistry.SetShuttingDown()
+		<-done
+	case err := <-done:
+		if err != nil && err != context.Canceled {
+			logger.Error("Subscriber stopped unexpectedly", zap.Error(err))
+		}
+	}
+
+	if err := subscriber.Close(); err != nil {
+		logger.Error("Failed to close event subscriber", zap.Error(err))
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Worker.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Worker HTTP server forced to shutdown", zap.Error(err))
+	}
+
+	// Отправляем оставшиеся span'ы
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("Failed to shutdown tracing", zap.Error(err))
+	}
+
+	logger.Info("Worker exited")
+}
+
+func purgeProcessedEvents(ctx context.Context, store *dedup.SQLiteStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := store.Purge(ctx)
+			if err != nil {
+				logger.Warn("Failed to purge processed events", zap.Error(err))
+				continue
+			}
+			if deleted > 0 {
+				logger.Debug("Expired processed events purged", zap.Int64("deleted", deleted))
+			}
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func isVerbose(r *http.Request) bool {
+	verbose := r.URL.Query().Get("verbose")
+	return verbose == "1" || verbose == "true"
+}
@@ -0,0 +1,85 @@
+// Package admin serves internal endpoints (metrics, health checks, pprof, runtime
+// administration) on a separate address that is not exposed with the public API.
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/logger"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// Server is the internal HTTP server of a process
+type Server struct {
+	mux *http.ServeMux
+	srv *http.Server
+}
+
+// NewServer creates the admin server on addr with Prometheus metrics on cfg.Path,
+// the runtime log level on /log/level and, if enabled, pprof under /debug/pprof/
+func NewServer(addr string, cfg config.MetricsConfig) *Server {
+	mux := http.NewServeMux()
+	if cfg.Enabled {
+		mux.Handle(cfg.Path, promhttp.Handler())
+	}
+	// Уровень логирования меняется без перезапуска: PUT {"level":"debug"}
+	mux.Handle("/log/level", logger.LevelHandler())
+	if cfg.Pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	return &Server{
+		mux: mux,
+		srv: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// Addr returns the address for the metrics section of the config
+func Addr(cfg config.MetricsConfig) string {
+	return fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+}
+
+// Handle registers a handler on the admin server
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// HandleFunc registers a handler function on the admin server
+func (s *Server) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// Handler returns the admin routes; used in tests
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Start serves in the background; failing to bind the address is fatal
+func (s *Server) Start() {
+	go func() {
+		logger.Info("Starting admin HTTP server", zap.String("address", s.srv.Addr))
+		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Failed to start admin HTTP server", zap.Error(err))
+		}
+	}()
+}
+
+// Shutdown stops the server gracefully
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo_app_go/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_Routes(t *testing.T) {
+	srv := NewServer("127.0.0.1:0", config.MetricsConfig{Enabled: true, Path: "/metrics", Pprof: true})
+	srv.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, path := range []string{"/metrics", "/debug/pprof/", "/log/level", "/ready"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+}
+
+func TestServer_Disabled(t *testing.T) {
+	srv := NewServer("127.0.0.1:0", config.MetricsConfig{Path: "/metrics"})
+
+	for _, path := range []string{"/metrics", "/debug/pprof/"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
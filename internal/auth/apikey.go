@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// Области доступа API-ключей. Пользовательские JWT получают все области
+const (
+	ScopeTodosRead  = "todos:read"
+	ScopeTodosWrite = "todos:write"
+	// ScopeAdmin включает все остальные области и управление API-ключами
+	ScopeAdmin = "admin"
+)
+
+// AllScopes lists every known scope
+var AllScopes = []string{ScopeTodosRead, ScopeTodosWrite, ScopeAdmin}
+
+// APIKeyPrefix starts every API key, so that keys are recognizable in the Authorization
+// header and by secret scanners
+const APIKeyPrefix = "todo_"
+
+// HasScope reports whether the granted scopes allow the required one
+func HasScope(granted []string, required string) bool {
+	return slices.Contains(granted, required) || slices.Contains(granted, ScopeAdmin)
+}
+
+// ValidScope reports whether the scope is known
+func ValidScope(scope string) bool {
+	return slices.Contains(AllScopes, scope)
+}
+
+// GenerateAPIKey returns a new key of the form todo_<id>_<secret> and its public prefix
+// todo_<id>. The prefix is stored in clear to let users tell their keys apart.
+func GenerateAPIKey() (key, prefix string, err error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	secret, err := GenerateToken()
+	if err != nil {
+		return "", "", err
+	}
+	prefix = APIKeyPrefix + hex.EncodeToString(id)
+	return prefix + "_" + secret, prefix, nil
+}
+
+// IsAPIKey reports whether the credential looks like an API key rather than a JWT
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
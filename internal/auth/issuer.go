@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"todo_app_go/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TokenIssuer signs HS256 access tokens for local accounts. The tokens carry the
+// configured issuer and audience, so the Validator built from the same config accepts them.
+type TokenIssuer struct {
+	secret   []byte
+	issuer   string
+	audience string
+	ttl      time.Duration
+}
+
+// NewTokenIssuer creates an issuer; it requires auth.jwt.secret
+func NewTokenIssuer(cfg config.JWTConfig, ttl time.Duration) (*TokenIssuer, error) {
+	if cfg.Secret == "" {
+		return nil, errors.New("local accounts require auth.jwt.secret to sign access tokens")
+	}
+	return &TokenIssuer{
+		secret:   []byte(cfg.Secret),
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      ttl,
+	}, nil
+}
+
+// Issue returns a signed access token for the subject and its expiry time
+func (i *TokenIssuer) Issue(subject string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(i.ttl)
+	claims := Claims{
+		ID:        uuid.New().String(),
+		Subject:   subject,
+		Issuer:    i.issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+	if i.audience != "" {
+		claims.Audience = jwt.ClaimStrings{i.audience}
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(i.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
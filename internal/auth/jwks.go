@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"todo_app_go/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// minRefreshInterval ограничивает перечитывание JWKS из-за токенов с неизвестным kid
+const minRefreshInterval = 10 * time.Second
+
+// ErrKeyNotFound is returned when the key set has no key with the token's kid
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// JWK is a public key in JSON Web Key format (RFC 7517); only RSA and EC keys are supported
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served by a JWKS endpoint
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS caches the public keys of a JWKS file or URL. Keys are reloaded when the cache is
+// older than the refresh interval or a token refers to an unknown kid, so that signing keys
+// can be rotated without a restart.
+type JWKS struct {
+	fetch   func(ctx context.Context) ([]byte, error)
+	refresh time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWKSFile creates a key set read from a file
+func NewJWKSFile(path string, refresh time.Duration) *JWKS {
+	return &JWKS{
+		fetch:   func(context.Context) ([]byte, error) { return os.ReadFile(path) },
+		refresh: refresh,
+	}
+}
+
+// NewJWKSURL creates a key set fetched over HTTP
+func NewJWKSURL(url string, refresh time.Duration) *JWKS {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &JWKS{
+		fetch: func(ctx context.Context) ([]byte, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("unexpected JWKS response status %d", resp.StatusCode)
+			}
+			return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		},
+		refresh: refresh,
+	}
+}
+
+// Key returns the public key with the given kid. An empty kid matches the only key of the set.
+func (s *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	key, found := lookup(s.keys, kid)
+	stale := time.Since(s.fetchedAt) > s.refresh
+	recent := time.Since(s.fetchedAt) < minRefreshInterval
+	s.mu.RUnlock()
+
+	if found && !stale {
+		return key, nil
+	}
+	// Неизвестный kid перечитывает набор не чаще minRefreshInterval, чтобы
+	// поддельные токены не превращались в поток запросов к JWKS
+	if !found && !stale && recent {
+		return nil, ErrKeyNotFound
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		// Устаревший, но загруженный набор лучше отказа всем запросам
+		if found {
+			logger.FromContext(ctx).Warn("Failed to refresh JWKS, using cached keys", zap.Error(err))
+			return key, nil
+		}
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key, ok := lookup(s.keys, kid); ok {
+		return key, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// Refresh reloads the key set
+func (s *JWKS) Refresh(ctx context.Context) error {
+	data, err := s.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS: %w", err)
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func lookup(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+// ParseJWKS decodes a JWKS document into public keys by kid. Keys of unsupported
+// types and keys not meant for signatures are skipped.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set JWKSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWK %q: %w", jwk.Kid, err)
+		}
+		if key != nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// PublicKey decodes the key; it returns nil for unsupported key types
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,93 @@
+// Package auth authenticates API requests.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"todo_app_go/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Ошибки проверки токена; текст возвращается клиенту в ответе 401
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrMissingSub   = errors.New("token has no subject")
+)
+
+// Claims are the registered claims checked by the validator
+type Claims = jwt.RegisteredClaims
+
+// Validator verifies the signature and the exp, nbf, iss and aud claims of JWTs
+type Validator struct {
+	secret []byte
+	jwks   *JWKS
+	parser *jwt.Parser
+}
+
+// NewValidator creates a validator for the configured keys: a shared secret enables HS256,
+// a JWKS file or URL enables RS256 and ES256
+func NewValidator(cfg config.JWTConfig) (*Validator, error) {
+	v := &Validator{}
+
+	var methods []string
+	if cfg.Secret != "" {
+		v.secret = []byte(cfg.Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	switch {
+	case cfg.JWKSFile != "" && cfg.JWKSURL != "":
+		return nil, errors.New("jwks_file and jwks_url are mutually exclusive")
+	case cfg.JWKSFile != "":
+		v.jwks = NewJWKSFile(cfg.JWKSFile, cfg.JWKSRefreshInterval)
+	case cfg.JWKSURL != "":
+		v.jwks = NewJWKSURL(cfg.JWKSURL, cfg.JWKSRefreshInterval)
+	}
+	if v.jwks != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("no JWT keys configured: set auth.jwt.secret, jwks_file or jwks_url")
+	}
+
+	// Алгоритм фиксирован конфигурацией: токен не может выбрать его сам (alg=none, HS256 с публичным ключом)
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+	return v, nil
+}
+
+// Validate parses the token and returns its claims if the signature and claims are valid
+func (v *Validator) Validate(ctx context.Context, token string) (*Claims, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	claims := &Claims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+			return v.secret, nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		return v.jwks.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return nil, ErrMissingSub
+	}
+	return claims, nil
+}
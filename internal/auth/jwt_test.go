@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "test-secret"
+
+func claims(sub string, ttl time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Subject:   sub,
+		Issuer:    "https://issuer.test",
+		Audience:  jwt.ClaimStrings{"todo-api"},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, c jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, c)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256",
+		N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) JWK {
+	return JWK{
+		Kty: "EC", Kid: kid, Use: "sig", Alg: "ES256", Crv: "P-256",
+		X: base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y: base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func hsConfig() config.JWTConfig {
+	return config.JWTConfig{Secret: testSecret, Issuer: "https://issuer.test", Audience: "todo-api"}
+}
+
+func TestValidator_HS256(t *testing.T) {
+	v, err := NewValidator(hsConfig())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	got, err := v.Validate(ctx, sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), claims("alice", time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", got.Subject)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"wrong secret", sign(t, jwt.SigningMethodHS256, "", []byte("other"), claims("alice", time.Hour))},
+		{"expired", sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), claims("alice", -time.Hour))},
+		{"not yet valid", func() string {
+			c := claims("alice", 2*time.Hour)
+			c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+			return sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), c)
+		}()},
+		{"no expiry", func() string {
+			c := claims("alice", time.Hour)
+			c.ExpiresAt = nil
+			return sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), c)
+		}()},
+		{"wrong issuer", func() string {
+			c := claims("alice", time.Hour)
+			c.Issuer = "https://evil.test"
+			return sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), c)
+		}()},
+		{"wrong audience", func() string {
+			c := claims("alice", time.Hour)
+			c.Audience = jwt.ClaimStrings{"other-api"}
+			return sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), c)
+		}()},
+		{"alg none", sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, claims("alice", time.Hour))},
+		{"garbage", "not-a-jwt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Validate(ctx, tt.token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+
+	_, err = v.Validate(ctx, "")
+	assert.ErrorIs(t, err, ErrMissingToken)
+	_, err = v.Validate(ctx, sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), claims("", time.Hour)))
+	assert.ErrorIs(t, err, ErrMissingSub)
+}
+
+func TestValidator_RS256_JWKSFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	data, _ := json.Marshal(JWKSet{Keys: []JWK{rsaJWK("rsa-1", &key.PublicKey)}})
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	v, err := NewValidator(config.JWTConfig{JWKSFile: path, JWKSRefreshInterval: time.Hour, Audience: "todo-api"})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	got, err := v.Validate(ctx, sign(t, jwt.SigningMethodRS256, "rsa-1", key, claims("bob", time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, "bob", got.Subject)
+
+	// HS256 не настроен: токен, подписанный публичным ключом как секретом, отклоняется
+	_, err = v.Validate(ctx, sign(t, jwt.SigningMethodHS256, "rsa-1", key.PublicKey.N.Bytes(), claims("bob", time.Hour)))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, err = v.Validate(ctx, sign(t, jwt.SigningMethodRS256, "rsa-2", other, claims("bob", time.Hour)))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestValidator_ES256_JWKSURL_Rotation(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	set := JWKSet{Keys: []JWK{ecJWK("ec-1", &oldKey.PublicKey)}}
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		mu.Lock()
+		defer mu.Unlock()
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+
+	v, err := NewValidator(config.JWTConfig{JWKSURL: srv.URL, JWKSRefreshInterval: time.Hour})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err = v.Validate(ctx, sign(t, jwt.SigningMethodES256, "ec-1", oldKey, claims("carol", time.Hour)))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "keys are cached")
+
+	// Ротация: новый kid появляется в JWKS, кэш ещё свежий
+	mu.Lock()
+	set.Keys = append(set.Keys, ecJWK("ec-2", &newKey.PublicKey))
+	mu.Unlock()
+
+	// Неизвестный kid сразу после загрузки не вызывает повторный запрос
+	_, err = v.Validate(ctx, sign(t, jwt.SigningMethodES256, "ec-2", newKey, claims("carol", time.Hour)))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// После minRefreshInterval неизвестный kid перечитывает набор
+	v.jwks.mu.Lock()
+	v.jwks.fetchedAt = time.Now().Add(-minRefreshInterval)
+	v.jwks.mu.Unlock()
+	got, err := v.Validate(ctx, sign(t, jwt.SigningMethodES256, "ec-2", newKey, claims("carol", time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, "carol", got.Subject)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestNewValidator_NoKeys(t *testing.T) {
+	_, err := NewValidator(config.JWTConfig{})
+	assert.Error(t, err)
+	_, err = NewValidator(config.JWTConfig{JWKSFile: "a.json", JWKSURL: "http://b"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"todo_app_go/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrOIDCExchange is returned when the provider rejects the authorization code
+	ErrOIDCExchange = errors.New("OIDC code exchange failed")
+	// ErrInvalidIDToken is returned for an ID token with a bad signature, claims or nonce
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+// IDToken holds the verified claims of an OIDC ID token
+type IDToken struct {
+	Issuer  string
+	Subject string
+	Email   string
+	// EmailVerified is nil if the provider does not send email_verified
+	EmailVerified *bool
+	Claims        jwt.MapClaims
+}
+
+// oidcDiscovery is the part of the provider metadata the client uses
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider is the client side of the OIDC authorization code flow with PKCE.
+// Provider metadata is discovered on first use, so the service starts even if the
+// provider is temporarily unavailable.
+type OIDCProvider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	jwks      *JWKS
+}
+
+// NewOIDCProvider creates a provider client; it requires issuer, client_id and redirect_url
+func NewOIDCProvider(cfg config.OIDCConfig) (*OIDCProvider, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("auth.oidc requires issuer, client_id and redirect_url")
+	}
+	if cfg.EmailClaim == "" {
+		cfg.EmailClaim = "email"
+	}
+	return &OIDCProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// GeneratePKCE returns a PKCE code verifier and its S256 challenge (RFC 7636)
+func GeneratePKCE() (verifier, challenge string, err error) {
+	verifier, err = GenerateToken()
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// AuthCodeURL returns the provider URL the user is redirected to for signing in
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns the verified ID token.
+// The nonce must match the one sent in the authorization request.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*IDToken, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	if p.cfg.ClientSecret == "" {
+		// Публичный клиент: аутентификацию заменяет PKCE
+		form.Set("client_id", p.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOIDCExchange, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOIDCExchange, err)
+	}
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("%w: status %d", ErrOIDCExchange, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("%w: status %d: %s %s", ErrOIDCExchange, resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in response", ErrOIDCExchange)
+	}
+
+	return p.verify(ctx, d, token.IDToken, nonce)
+}
+
+// verify checks the ID token signature against the provider JWKS and its iss, aud, exp and nonce
+func (p *OIDCProvider) verify(ctx context.Context, d *oidcDiscovery, raw, nonce string) (*IDToken, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithLeeway(time.Minute),
+	)
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.jwks.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	token := &IDToken{Issuer: d.Issuer, Claims: claims}
+	token.Subject, _ = claims["sub"].(string)
+	if token.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+	token.Email, _ = claims[p.cfg.EmailClaim].(string)
+	if verified, ok := claims["email_verified"].(bool); ok {
+		token.EmailVerified = &verified
+	}
+	return token, nil
+}
+
+// discover loads the provider metadata once; a failed attempt is retried on the next call
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(p.cfg.Issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC discovery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OIDC discovery: unexpected status %d", resp.StatusCode)
+	}
+
+	var d oidcDiscovery
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&d); err != nil {
+		return nil, fmt.Errorf("OIDC discovery: %w", err)
+	}
+	// Метаданные должны принадлежать настроенному провайдеру (OIDC Discovery, раздел 4.3)
+	if strings.TrimSuffix(d.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery: issuer %q does not match %q", d.Issuer, p.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery: incomplete provider metadata")
+	}
+
+	p.discovery = &d
+	p.jwks = NewJWKSURL(d.JWKSURI, time.Hour)
+	return p.discovery, nil
+}
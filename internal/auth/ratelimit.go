@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginLimiter counts failed login attempts per key (email, client IP) in a fixed window.
+// A key is blocked once it reaches the limit until its window expires. State is kept in
+// memory, so with several replicas the effective limit is multiplied by their number.
+type LoginLimiter struct {
+	maxAttempts int
+	window      time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]*loginFailures
+	prunedAt time.Time
+}
+
+type loginFailures struct {
+	count int
+	start time.Time
+}
+
+// NewLoginLimiter creates a limiter; maxAttempts <= 0 disables it
+func NewLoginLimiter(maxAttempts int, window time.Duration) *LoginLimiter {
+	return &LoginLimiter{
+		maxAttempts: maxAttempts,
+		window:      window,
+		now:         time.Now,
+		failures:    make(map[string]*loginFailures),
+	}
+}
+
+// RetryAfter returns how long the key stays blocked; zero means an attempt is allowed
+func (l *LoginLimiter) RetryAfter(key string) time.Duration {
+	if l.maxAttempts <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[key]
+	if !ok {
+		return 0
+	}
+	left := f.start.Add(l.window).Sub(l.now())
+	if left <= 0 {
+		delete(l.failures, key)
+		return 0
+	}
+	if f.count < l.maxAttempts {
+		return 0
+	}
+	return left
+}
+
+// Fail records a failed attempt for the key
+func (l *LoginLimiter) Fail(key string) {
+	if l.maxAttempts <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+	f, ok := l.failures[key]
+	if !ok || now.Sub(f.start) >= l.window {
+		l.failures[key] = &loginFailures{count: 1, start: now}
+		return
+	}
+	f.count++
+}
+
+// Reset forgets the failures of the key after a successful login
+func (l *LoginLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, key)
+}
+
+// prune раз в окно удаляет истёкшие записи, чтобы перебор адресов не раздувал map
+func (l *LoginLimiter) prune(now time.Time) {
+	if now.Sub(l.prunedAt) < l.window {
+		return
+	}
+	l.prunedAt = now
+	for key, f := range l.failures {
+		if now.Sub(f.start) >= l.window {
+			delete(l.failures, key)
+		}
+	}
+}
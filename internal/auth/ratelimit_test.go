@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	now := time.Now()
+	l := NewLoginLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Fail("ip:1")
+	assert.Zero(t, l.RetryAfter("ip:1"))
+	l.Fail("ip:1")
+	assert.Equal(t, time.Minute, l.RetryAfter("ip:1"))
+	assert.Zero(t, l.RetryAfter("ip:2"), "keys are independent")
+
+	// Блокировка снимается по окончании окна
+	now = now.Add(time.Minute)
+	assert.Zero(t, l.RetryAfter("ip:1"))
+
+	l.Fail("ip:1")
+	l.Fail("ip:1")
+	l.Reset("ip:1")
+	assert.Zero(t, l.RetryAfter("ip:1"))
+
+	disabled := NewLoginLimiter(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		disabled.Fail("ip:1")
+	}
+	assert.Zero(t, disabled.RetryAfter("ip:1"))
+}
@@ -16,7 +16,6 @@ import (
 
 type RedisCache struct {
 	client *redis.Client
-	ctx    context.Context
 }
 
 func NewRedisCache(cfg config.RedisConfig) (*RedisCache, error) {
@@ -26,10 +25,10 @@ func NewRedisCache(cfg config.RedisConfig) (*RedisCache, error) {
 		DB:       cfg.DB,
 	})
 
-	ctx := context.Background()
+	client.AddHook(tracingHook{})
 
 	// Проверяем подключение
-	_, err := client.Ping(ctx).Result()
+	_, err := client.Ping(context.Background()).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
@@ -37,14 +36,23 @@ func NewRedisCache(cfg config.RedisConfig) (*RedisCache, error) {
 	logger.Info("Redis cache initialized successfully")
 	return &RedisCache{
 		client: client,
-		ctx:    ctx,
 	}, nil
 }
 
-func (c *RedisCache) GetTodo(id int64) (*models.Todo, error) {
-	key := fmt.Sprintf("todo:%d", id)
+// Ключи разделены по владельцам: закэшированная todo одного пользователя
+// не может быть отдана другому
+func todoKey(ownerID string, id int64) string {
+	return fmt.Sprintf("todo:%s:%d", ownerID, id)
+}
+
+func todosKey(ownerID string) string {
+	return "todos:" + ownerID
+}
 
-	data, err := c.client.Get(c.ctx, key).Result()
+func (c *RedisCache) GetTodo(ctx context.Context, ownerID string, id int64) (*models.Todo, error) {
+	key := todoKey(ownerID, id)
+
+	data, err := c.client.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
 			metrics.CacheMissesTotal.Inc()
@@ -63,26 +71,26 @@ func (c *RedisCache) GetTodo(id int64) (*models.Todo, error) {
 	return &todo, nil
 }
 
-func (c *RedisCache) SetTodo(todo *models.Todo, expiration time.Duration) error {
-	key := fmt.Sprintf("todo:%d", todo.ID)
+func (c *RedisCache) SetTodo(ctx context.Context, todo *models.Todo, expiration time.Duration) error {
+	key := todoKey(todo.OwnerID, todo.ID)
 
 	data, err := json.Marshal(todo)
 	if err != nil {
 		return err
 	}
 
-	return c.client.Set(c.ctx, key, data, expiration).Err()
+	return c.client.Set(ctx, key, data, expiration).Err()
 }
 
-func (c *RedisCache) DeleteTodo(id int64) error {
-	key := fmt.Sprintf("todo:%d", id)
-	return c.client.Del(c.ctx, key).Err()
+func (c *RedisCache) DeleteTodo(ctx context.Context, ownerID string, id int64) error {
+	key := todoKey(ownerID, id)
+	return c.client.Del(ctx, key).Err()
 }
 
-func (c *RedisCache) GetTodos() ([]models.Todo, error) {
-	key := "todos:all"
+func (c *RedisCache) GetTodos(ctx context.Context, ownerID string) ([]models.Todo, error) {
+	key := todosKey(ownerID)
 
-	data, err := c.client.Get(c.ctx, key).Result()
+	data, err := c.client.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
 			metrics.CacheMissesTotal.Inc()
@@ -101,20 +109,25 @@ func (c *RedisCache) GetTodos() ([]models.Todo, error) {
 	return todos, nil
 }
 
-func (c *RedisCache) SetTodos(todos []models.Todo, expiration time.Duration) error {
-	key := "todos:all"
+func (c *RedisCache) SetTodos(ctx context.Context, ownerID string, todos []models.Todo, expiration time.Duration) error {
+	key := todosKey(ownerID)
 
 	data, err := json.Marshal(todos)
 	if err != nil {
 		return err
 	}
 
-	return c.client.Set(c.ctx, key, data, expiration).Err()
+	return c.client.Set(ctx, key, data, expiration).Err()
+}
+
+func (c *RedisCache) InvalidateTodos(ctx context.Context, ownerID string) error {
+	key := todosKey(ownerID)
+	return c.client.Del(ctx, key).Err()
 }
 
-func (c *RedisCache) InvalidateTodos() error {
-	key := "todos:all"
-	return c.client.Del(c.ctx, key).Err()
+// Ping checks the connection to Redis
+func (c *RedisCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
 }
 
 func (c *RedisCache) Close() error {
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"strings"
+
+	"todo_app_go/internal/tracing"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingHook starts a client span for every Redis command and pipeline
+type tracingHook struct{}
+
+func (tracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, _ = startSpan(ctx, cmd.FullName(), attribute.String("db.operation", cmd.Name()))
+	return ctx, nil
+}
+
+func (tracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endSpan(ctx, cmd.Err())
+	return nil
+}
+
+func (tracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	names := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name())
+	}
+	ctx, _ = startSpan(ctx, "pipeline", attribute.String("db.operation", strings.Join(names, " ")))
+	return ctx, nil
+}
+
+func (tracingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			err = cmd.Err()
+			break
+		}
+	}
+	endSpan(ctx, err)
+	return nil
+}
+
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("db.system", "redis"))
+	return tracing.Tracer().Start(ctx, "redis "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+}
+
+// endSpan ends the span started in BeforeProcess; a cache miss is not an error
+func endSpan(ctx context.Context, err error) {
+	if err == redis.Nil {
+		err = nil
+	}
+	tracing.End(trace.SpanFromContext(ctx), err)
+}
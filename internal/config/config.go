@@ -1,6 +1,9 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -10,8 +13,14 @@ type Config struct {
 	Server   ServerConfig   `mapstructure:"server"`
 	Database DatabaseConfig `mapstructure:"database"`
 	Redis    RedisConfig    `mapstructure:"redis"`
+	Events   EventsConfig   `mapstructure:"events"`
 	Kafka    KafkaConfig    `mapstructure:"kafka"`
+	Outbox   OutboxConfig   `mapstructure:"outbox"`
+	Worker   WorkerConfig   `mapstructure:"worker"`
 	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	Tracing  TracingConfig  `mapstructure:"tracing"`
+	Health   HealthConfig   `mapstructure:"health"`
+	Auth     AuthConfig     `mapstructure:"auth"`
 	Log      LogConfig      `mapstructure:"log"`
 }
 
@@ -22,6 +31,8 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
 	IdleTimeout     time.Duration `mapstructure:"idle_timeout"`
+	// Адреса или CIDR шлюзов, которым разрешено передавать X-Actor; без них заголовок игнорируется
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type DatabaseConfig struct {
@@ -32,6 +43,16 @@ type DatabaseConfig struct {
 	Name string `mapstructure:"name"`
 	User string `mapstructure:"user"`
 	Pass string `mapstructure:"pass"`
+
+	// EventSourcing делает таблицу events источником истины, а todos — её проекцией
+	EventSourcing bool `mapstructure:"event_sourcing"`
+
+	// Пул соединений; 0 оставляет значение database/sql по умолчанию
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	// StatsInterval — период экспорта sql.DBStats в метрики
+	StatsInterval time.Duration `mapstructure:"stats_interval"`
 }
 
 type RedisConfig struct {
@@ -41,21 +62,204 @@ type RedisConfig struct {
 	DB       int    `mapstructure:"db"`
 }
 
+// EventsConfig выбирает шину событий
+type EventsConfig struct {
+	Driver        string               `mapstructure:"driver"`         // kafka, nats, memory, none
+	Source        string               `mapstructure:"source"`         // CloudEvents source
+	SchemaVersion int                  `mapstructure:"schema_version"` // версия схемы публикуемых событий
+	NATS          NATSConfig           `mapstructure:"nats"`
+	Memory        MemoryBusConfig      `mapstructure:"memory"`
+	Async         AsyncPublisherConfig `mapstructure:"async"`
+}
+
+type NATSConfig struct {
+	URL        string        `mapstructure:"url"`
+	Stream     string        `mapstructure:"stream"`
+	Subject    string        `mapstructure:"subject"` // события публикуются в <subject>.<type>
+	Durable    string        `mapstructure:"durable"` // имя durable consumer
+	MaxDeliver int           `mapstructure:"max_deliver"`
+	AckWait    time.Duration `mapstructure:"ack_wait"`
+	DLQSubject string        `mapstructure:"dlq_subject"`
+}
+
+type MemoryBusConfig struct {
+	BufferSize int `mapstructure:"buffer_size"`
+}
+
+// AsyncPublisherConfig настраивает фоновую публикацию из запроса; несовместима с outbox
+type AsyncPublisherConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	BufferSize     int           `mapstructure:"buffer_size"`     // размер очереди событий
+	BatchSize      int           `mapstructure:"batch_size"`      // пакет отправляется при достижении размера...
+	FlushInterval  time.Duration `mapstructure:"flush_interval"`  // ...или по истечении интервала
+	EnqueueTimeout time.Duration `mapstructure:"enqueue_timeout"` // сколько ждать места в очереди, прежде чем отбросить событие
+}
+
 type KafkaConfig struct {
-	Brokers []string `mapstructure:"brokers"`
-	Topic   string   `mapstructure:"topic"`
-	GroupID string   `mapstructure:"group_id"`
+	Brokers     []string `mapstructure:"brokers"`
+	Topic       string   `mapstructure:"topic"`
+	GroupID     string   `mapstructure:"group_id"`
+	ContentMode string   `mapstructure:"content_mode"` // structured, binary
+
+	Consumer KafkaConsumerConfig `mapstructure:"consumer"`
+}
+
+type KafkaConsumerConfig struct {
+	MaxRetries     int           `mapstructure:"max_retries"` // повторы обработчика до отправки в DLQ
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+	DLQTopic       string        `mapstructure:"dlq_topic"` // обязателен для драйвера kafka
+}
+
+type OutboxConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	PollInterval   time.Duration `mapstructure:"poll_interval"`
+	BatchSize      int           `mapstructure:"batch_size"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+	Retention      time.Duration `mapstructure:"retention"` // сколько хранить отправленные сообщения
+}
+
+// WorkerConfig настраивает cmd/worker
+type WorkerConfig struct {
+	Host            string        `mapstructure:"host"`
+	Port            int           `mapstructure:"port"` // health и metrics endpoints
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	Dedup           DedupConfig   `mapstructure:"dedup"`
+}
+
+// DedupConfig настраивает журнал обработанных событий для идемпотентных проекций
+type DedupConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Backend         string        `mapstructure:"backend"` // sqlite, redis
+	TTL             time.Duration `mapstructure:"ttl"`     // сколько помнить обработанное событие
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+}
+
+// TracingConfig настраивает OpenTelemetry
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter"`     // otlp, stdout, none
+	Endpoint    string  `mapstructure:"endpoint"`     // OTLP/HTTP collector, host:port
+	Insecure    bool    `mapstructure:"insecure"`     // без TLS
+	ServiceName string  `mapstructure:"service_name"` // service.name ресурса
+	SampleRatio float64 `mapstructure:"sample_ratio"` // доля новых trace, 0..1
+}
+
+type HealthConfig struct {
+	// Timeout — таймаут одной проверки зависимости
+	Timeout time.Duration `mapstructure:"timeout"`
+	// ShutdownDelay — пауза между переходом в not ready и остановкой сервера,
+	// чтобы балансировщик успел убрать pod из ротации
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay"`
+}
+
+// AuthConfig настраивает аутентификацию запросов к /api/v1
+type AuthConfig struct {
+	Enabled bool            `mapstructure:"enabled"`
+	JWT     JWTConfig       `mapstructure:"jwt"`
+	Local   LocalAuthConfig `mapstructure:"local"`
+	OIDC    OIDCConfig      `mapstructure:"oidc"`
+}
+
+// JWTConfig задаёт ключи и проверяемые claims bearer-токенов. Secret включает HS256,
+// JWKSFile или JWKSURL — RS256 и ES256
+type JWTConfig struct {
+	Secret   string `mapstructure:"secret"`
+	JWKSFile string `mapstructure:"jwks_file"`
+	JWKSURL  string `mapstructure:"jwks_url"`
+	// JWKSRefreshInterval — как долго набор ключей считается актуальным
+	JWKSRefreshInterval time.Duration `mapstructure:"jwks_refresh_interval"`
+	Issuer              string        `mapstructure:"issuer"`
+	Audience            string        `mapstructure:"audience"`
+	// Leeway — допустимое расхождение часов при проверке exp и nbf
+	Leeway time.Duration `mapstructure:"leeway"`
+}
+
+// LocalAuthConfig включает собственные учётные записи: регистрацию, вход по паролю
+// и выпуск токенов. Access-токены подписываются HS256 секретом auth.jwt.secret
+type LocalAuthConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	AccessTokenTTL  time.Duration `mapstructure:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl"`
+	ResetTokenTTL   time.Duration `mapstructure:"reset_token_ttl"`
+	// BcryptCost — стоимость хэширования паролей (4..31)
+	BcryptCost int `mapstructure:"bcrypt_cost"`
+	// Notifier доставляет токены сброса пароля: log или webhook
+	Notifier   string               `mapstructure:"notifier"`
+	WebhookURL string               `mapstructure:"webhook_url"`
+	RateLimit  LoginRateLimitConfig `mapstructure:"rate_limit"`
+}
+
+// LoginRateLimitConfig ограничивает неудачные попытки входа для одного email и одного IP
+type LoginRateLimitConfig struct {
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	Window      time.Duration `mapstructure:"window"`
+}
+
+// OIDCConfig включает вход через внешний OIDC-провайдер (authorization code с PKCE).
+// Пользователь провайдера сопоставляется с локальной учётной записью, токены выпускаются
+// так же, как при входе по паролю, поэтому требуется auth.local
+type OIDCConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Issuer — URL провайдера; эндпоинты берутся из {issuer}/.well-known/openid-configuration
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+	// EmailClaim — claim ID-токена с email пользователя
+	EmailClaim string `mapstructure:"email_claim"`
+	// AllowedDomains ограничивает вход email-доменами; пустой список разрешает все
+	AllowedDomains []string `mapstructure:"allowed_domains"`
+	// StateTTL — сколько живёт начатый вход до возврата с провайдера
+	StateTTL time.Duration `mapstructure:"state_ttl"`
 }
 
+// MetricsConfig настраивает внутренний admin-сервер: метрики, health checks, pprof
 type MetricsConfig struct {
 	Enabled bool   `mapstructure:"enabled"`
 	Path    string `mapstructure:"path"`
+	Host    string `mapstructure:"host"`
 	Port    int    `mapstructure:"port"`
+	Pprof   bool   `mapstructure:"pprof"`
+	// TodoGaugesInterval — период сверки todos_open/todos_completed с базой
+	TodoGaugesInterval time.Duration `mapstructure:"todo_gauges_interval"`
 }
 
 type LogConfig struct {
-	Level  string `mapstructure:"level"`
+	Level  string `mapstructure:"level"`  // debug, info, warn, error
 	Format string `mapstructure:"format"` // json, text
+	// Outputs — приёмники логов: stdout, file, syslog
+	Outputs  []string          `mapstructure:"outputs"`
+	File     LogFileConfig     `mapstructure:"file"`
+	Syslog   LogSyslogConfig   `mapstructure:"syslog"`
+	Sampling LogSamplingConfig `mapstructure:"sampling"`
+	// Redact — ключи полей, значения которых заменяются на [REDACTED] (без учёта регистра)
+	Redact []string `mapstructure:"redact"`
+}
+
+// LogFileConfig настраивает файл с ротацией по размеру и возрасту
+type LogFileConfig struct {
+	Path       string `mapstructure:"path"`
+	MaxSizeMB  int    `mapstructure:"max_size_mb"`
+	MaxAgeDays int    `mapstructure:"max_age_days"`
+	MaxBackups int    `mapstructure:"max_backups"`
+	Compress   bool   `mapstructure:"compress"`
+}
+
+// LogSyslogConfig настраивает отправку в syslog; пустой network — локальный демон
+type LogSyslogConfig struct {
+	Network string `mapstructure:"network"`
+	Address string `mapstructure:"address"`
+	Tag     string `mapstructure:"tag"`
+}
+
+// LogSamplingConfig ограничивает поток одинаковых debug/info сообщений: в секунду пишутся
+// первые Initial, затем каждое Thereafter-е. Warn и выше не сэмплируются
+type LogSamplingConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	Initial    int  `mapstructure:"initial"`
+	Thereafter int  `mapstructure:"thereafter"`
 }
 
 func Load() (*Config, error) {
@@ -68,7 +272,8 @@ func Load() (*Config, error) {
 	// Set defaults
 	setDefaults()
 
-	// Read environment variables
+	// Read environment variables: events.driver -> EVENTS_DRIVER
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err != nil {
@@ -81,10 +286,61 @@ func Load() (*Config, error) {
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, err
 	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
 
+// validate отклоняет несовместимые сочетания настроек
+func (c *Config) validate() error {
+	// Relay outbox отмечает сообщения отправленными только после подтверждения брокера,
+	// поэтому асинхронная публикация возможна лишь без outbox
+	if c.Events.Async.Enabled && c.Outbox.Enabled {
+		return errors.New("events.async.enabled requires outbox.enabled=false: the outbox relay publishes synchronously")
+	}
+	// Нулевая задержка превращает повторы в холостой цикл
+	if err := validateBackoff("kafka.consumer", c.Kafka.Consumer.InitialBackoff, c.Kafka.Consumer.MaxBackoff); err != nil {
+		return err
+	}
+	if c.Kafka.Consumer.MaxRetries < 0 {
+		return errors.New("kafka.consumer.max_retries must not be negative")
+	}
+	// Без DLQ сообщение, исчерпавшее повторы, некуда деть: consumer остановился бы на нём
+	if c.Events.Driver == "kafka" && c.Kafka.Consumer.DLQTopic == "" {
+		return errors.New("kafka.consumer.dlq_topic must be set when events.driver is kafka")
+	}
+	if err := validateBackoff("outbox", c.Outbox.InitialBackoff, c.Outbox.MaxBackoff); err != nil {
+		return err
+	}
+	// Нулевой интервал роняет time.NewTicker, нулевой пакет — LIMIT 0, и relay ничего не отправляет
+	if c.Outbox.PollInterval <= 0 {
+		return errors.New("outbox.poll_interval must be positive")
+	}
+	if c.Outbox.BatchSize <= 0 {
+		return errors.New("outbox.batch_size must be positive")
+	}
+	if c.Events.NATS.AckWait <= 0 {
+		return errors.New("events.nats.ack_wait must be positive")
+	}
+	// Просроченные ключи SQLite удаляются по тикеру
+	if c.Worker.Dedup.Enabled && c.Worker.Dedup.Backend == "sqlite" && c.Worker.Dedup.CleanupInterval <= 0 {
+		return errors.New("worker.dedup.cleanup_interval must be positive")
+	}
+	return nil
+}
+
+func validateBackoff(section string, initial, max time.Duration) error {
+	if initial <= 0 {
+		return fmt.Errorf("%s.initial_backoff must be positive", section)
+	}
+	if max < initial {
+		return fmt.Errorf("%s.max_backoff must not be less than initial_backoff", section)
+	}
+	return nil
+}
+
 func setDefaults() {
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.host", "0.0.0.0")
@@ -95,19 +351,101 @@ func setDefaults() {
 
 	viper.SetDefault("database.type", "sqlite")
 	viper.SetDefault("database.path", "todos.db")
+	viper.SetDefault("database.event_sourcing", false)
+	viper.SetDefault("database.max_open_conns", 10)
+	viper.SetDefault("database.max_idle_conns", 5)
+	viper.SetDefault("database.conn_max_lifetime", "1h")
+	viper.SetDefault("database.stats_interval", "15s")
 
 	viper.SetDefault("redis.host", "localhost")
 	viper.SetDefault("redis.port", 6379)
 	viper.SetDefault("redis.db", 0)
 
+	viper.SetDefault("events.driver", "kafka")
+	viper.SetDefault("events.source", "/todo-app")
+	viper.SetDefault("events.schema_version", 1)
+	viper.SetDefault("events.nats.url", "nats://localhost:4222")
+	viper.SetDefault("events.nats.stream", "TODO_EVENTS")
+	viper.SetDefault("events.nats.subject", "todo.events")
+	viper.SetDefault("events.nats.durable", "todo-worker")
+	viper.SetDefault("events.nats.max_deliver", 4)
+	viper.SetDefault("events.nats.ack_wait", "30s")
+	viper.SetDefault("events.nats.dlq_subject", "todo.dlq")
+	viper.SetDefault("events.memory.buffer_size", 1000)
+	viper.SetDefault("events.async.enabled", false)
+	viper.SetDefault("events.async.buffer_size", 10000)
+	viper.SetDefault("events.async.batch_size", 100)
+	viper.SetDefault("events.async.flush_interval", "100ms")
+	viper.SetDefault("events.async.enqueue_timeout", "50ms")
+
 	viper.SetDefault("kafka.brokers", []string{"localhost:9092"})
 	viper.SetDefault("kafka.topic", "todo-events")
 	viper.SetDefault("kafka.group_id", "todo-app")
+	viper.SetDefault("kafka.content_mode", "structured")
+	viper.SetDefault("kafka.consumer.max_retries", 3)
+	viper.SetDefault("kafka.consumer.initial_backoff", "500ms")
+	viper.SetDefault("kafka.consumer.max_backoff", "30s")
+	viper.SetDefault("kafka.consumer.dlq_topic", "todo-events.dlq")
+
+	viper.SetDefault("outbox.enabled", true)
+	viper.SetDefault("outbox.poll_interval", "1s")
+	viper.SetDefault("outbox.batch_size", 100)
+	viper.SetDefault("outbox.initial_backoff", "1s")
+	viper.SetDefault("outbox.max_backoff", "5m")
+	viper.SetDefault("outbox.retention", "24h")
+
+	// Admin-серверы без аутентификации: по умолчанию доступны только с localhost
+	viper.SetDefault("worker.host", "127.0.0.1")
+	viper.SetDefault("worker.port", 8081)
+	viper.SetDefault("worker.shutdown_timeout", "30s")
+	viper.SetDefault("worker.dedup.enabled", true)
+	viper.SetDefault("worker.dedup.backend", "sqlite")
+	viper.SetDefault("worker.dedup.ttl", "168h")
+	viper.SetDefault("worker.dedup.cleanup_interval", "1h")
 
 	viper.SetDefault("metrics.enabled", true)
 	viper.SetDefault("metrics.path", "/metrics")
+	viper.SetDefault("metrics.host", "127.0.0.1")
 	viper.SetDefault("metrics.port", 9090)
+	viper.SetDefault("metrics.pprof", false)
+	viper.SetDefault("metrics.todo_gauges_interval", "1m")
+
+	viper.SetDefault("health.timeout", "2s")
+	viper.SetDefault("health.shutdown_delay", "5s")
+
+	viper.SetDefault("tracing.exporter", "none")
+	viper.SetDefault("tracing.endpoint", "localhost:4318")
+	viper.SetDefault("tracing.insecure", true)
+	viper.SetDefault("tracing.service_name", "todo-app")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+
+	viper.SetDefault("auth.enabled", false)
+	viper.SetDefault("auth.jwt.jwks_refresh_interval", "15m")
+	viper.SetDefault("auth.jwt.leeway", "30s")
+	viper.SetDefault("auth.local.enabled", false)
+	viper.SetDefault("auth.local.access_token_ttl", "15m")
+	viper.SetDefault("auth.local.refresh_token_ttl", "720h")
+	viper.SetDefault("auth.local.reset_token_ttl", "1h")
+	viper.SetDefault("auth.local.bcrypt_cost", 12)
+	viper.SetDefault("auth.local.notifier", "log")
+	viper.SetDefault("auth.local.rate_limit.max_attempts", 5)
+	viper.SetDefault("auth.local.rate_limit.window", "15m")
+	viper.SetDefault("auth.oidc.enabled", false)
+	viper.SetDefault("auth.oidc.scopes", []string{"openid", "email", "profile"})
+	viper.SetDefault("auth.oidc.email_claim", "email")
+	viper.SetDefault("auth.oidc.state_ttl", "10m")
 
 	viper.SetDefault("log.level", "info")
 	viper.SetDefault("log.format", "json")
+	viper.SetDefault("log.outputs", []string{"stdout"})
+	viper.SetDefault("log.file.path", "logs/todo-app.log")
+	viper.SetDefault("log.file.max_size_mb", 100)
+	viper.SetDefault("log.file.max_age_days", 7)
+	viper.SetDefault("log.file.max_backups", 5)
+	viper.SetDefault("log.file.compress", true)
+	viper.SetDefault("log.syslog.tag", "todo-app")
+	viper.SetDefault("log.sampling.enabled", true)
+	viper.SetDefault("log.sampling.initial", 100)
+	viper.SetDefault("log.sampling.thereafter", 100)
+	viper.SetDefault("log.redact", []string{"authorization", "cookie", "password", "token", "refresh_token", "api_key"})
 }
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func validConfig() Config {
+	var cfg Config
+	cfg.Outbox.Enabled = true
+	cfg.Outbox.InitialBackoff, cfg.Outbox.MaxBackoff = time.Second, 5*time.Minute
+	cfg.Outbox.PollInterval, cfg.Outbox.BatchSize = time.Second, 100
+	cfg.Kafka.Consumer.InitialBackoff, cfg.Kafka.Consumer.MaxBackoff = 500*time.Millisecond, 30*time.Second
+	cfg.Events.NATS.AckWait = 30 * time.Second
+	return cfg
+}
+
+func TestDefaults_EventSchemaVersion(t *testing.T) {
+	// Consumer'ы v1 не должны получить v2 после обновления без явной настройки
+	setDefaults()
+	assert.Equal(t, 1, viper.GetInt("events.schema_version"))
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := validConfig()
+	assert.NoError(t, cfg.validate())
+
+	// Асинхронная публикация несовместима с outbox
+	cfg.Events.Async.Enabled = true
+	assert.ErrorContains(t, cfg.validate(), "events.async.enabled")
+
+	cfg.Outbox.Enabled = false
+	assert.NoError(t, cfg.validate())
+}
+
+func TestConfigValidate_Backoff(t *testing.T) {
+	cfg := validConfig()
+	cfg.Kafka.Consumer.InitialBackoff = 0
+	assert.ErrorContains(t, cfg.validate(), "kafka.consumer.initial_backoff")
+
+	cfg = validConfig()
+	cfg.Outbox.MaxBackoff = time.Millisecond
+	assert.ErrorContains(t, cfg.validate(), "outbox.max_backoff")
+
+	cfg = validConfig()
+	cfg.Events.NATS.AckWait = 0
+	assert.ErrorContains(t, cfg.validate(), "events.nats.ack_wait")
+}
+
+func TestConfigValidate_KafkaDLQ(t *testing.T) {
+	cfg := validConfig()
+	cfg.Events.Driver = "kafka"
+	assert.ErrorContains(t, cfg.validate(), "kafka.consumer.dlq_topic")
+
+	cfg.Kafka.Consumer.DLQTopic = "todo-events.dlq"
+	assert.NoError(t, cfg.validate())
+}
+
+func TestConfigValidate_Outbox(t *testing.T) {
+	cfg := validConfig()
+	cfg.Outbox.PollInterval = 0
+	assert.ErrorContains(t, cfg.validate(), "outbox.poll_interval")
+
+	cfg = validConfig()
+	cfg.Outbox.BatchSize = 0
+	assert.ErrorContains(t, cfg.validate(), "outbox.batch_size")
+}
+
+func TestConfigValidate_DedupCleanup(t *testing.T) {
+	cfg := validConfig()
+	cfg.Worker.Dedup.Enabled, cfg.Worker.Dedup.Backend = true, "sqlite"
+	assert.ErrorContains(t, cfg.validate(), "worker.dedup.cleanup_interval")
+
+	cfg.Worker.Dedup.CleanupInterval = time.Hour
+	assert.NoError(t, cfg.validate())
+}
@@ -18,9 +18,23 @@ func EnsureTodosTableAndColumn(db *sql.DB) error {
 		return err
 	}
 
-	// Проверяем, есть ли столбец updated_at
+	// Столбцы, добавленные после первой версии схемы
+	if err := ensureColumn(db, "todos", "updated_at", "DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP"); err != nil {
+		return err
+	}
+	// Существующие todo без владельца остаются в общем пространстве (аутентификация выключена)
+	if err := ensureColumn(db, "todos", "owner_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_todos_owner ON todos (owner_id, created_at);`)
+	return err
+}
+
+// ensureColumn добавляет столбец в таблицу, если его ещё нет
+func ensureColumn(db *sql.DB, table, column, definition string) error {
 	found := false
-	rows, err := db.Query("PRAGMA table_info(todos);")
+	rows, err := db.Query("PRAGMA table_info(" + table + ");")
 	if err != nil {
 		return err
 	}
@@ -33,16 +47,298 @@ func EnsureTodosTableAndColumn(db *sql.DB) error {
 		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dfltValue, &pk); err != nil {
 			return err
 		}
-		if name == "updated_at" {
+		if name == column {
 			found = true
 			break
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
 	if !found {
-		_, err := db.Exec(`ALTER TABLE todos ADD COLUMN updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP;`)
+		_, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition + ";")
 		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// EnsureOutboxTable создаёт таблицу outbox для транзакционной публикации событий
+func EnsureOutboxTable(db *sql.DB) error {
+	createTable := `
+	CREATE TABLE IF NOT EXISTS outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		aggregate_id INTEGER NOT NULL,
+		event_type TEXT NOT NULL,
+		payload BLOB NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		created_at DATETIME NOT NULL,
+		next_attempt_at DATETIME NOT NULL,
+		sent_at DATETIME
+	);
+	`
+	if _, err := db.Exec(createTable); err != nil {
+		return err
+	}
+	// Сообщения, которые нельзя опубликовать (например, повреждённый payload), не повторяются
+	if err := ensureColumn(db, "outbox", "parked_at", "DATETIME"); err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (sent_at, next_attempt_at);`,
+		// Поиск более раннего неотправленного события того же todo
+		`CREATE INDEX IF NOT EXISTS idx_outbox_aggregate ON outbox (aggregate_id, id);`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureProjectionTables создаёт таблицы read-моделей, которые ведёт worker
+func EnsureProjectionTables(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS todo_stats (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			created_total INTEGER NOT NULL DEFAULT 0,
+			completed_total INTEGER NOT NULL DEFAULT 0,
+			reopened_total INTEGER NOT NULL DEFAULT 0,
+			deleted_total INTEGER NOT NULL DEFAULT 0,
+			open_count INTEGER NOT NULL DEFAULT 0,
+			completed_count INTEGER NOT NULL DEFAULT 0,
+			updated_at DATETIME
+		);`,
+		`INSERT OR IGNORE INTO todo_stats (id) VALUES (1);`,
+		// Текущий статус каждого todo нужен, чтобы корректно учесть удаление
+		`CREATE TABLE IF NOT EXISTS todo_stats_items (
+			todo_id INTEGER PRIMARY KEY,
+			completed BOOLEAN NOT NULL DEFAULT 0
+		);`,
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			todo_id INTEGER NOT NULL,
+			changes TEXT,
+			occurred_at DATETIME NOT NULL,
+			recorded_at DATETIME NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_todo ON audit_log (todo_id, occurred_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_event ON audit_log (event_id);`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureEventsTable создаёт append-only журнал событий для режима event sourcing
+func EnsureEventsTable(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS events (
+			seq INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id TEXT NOT NULL UNIQUE,
+			aggregate_id INTEGER NOT NULL,
+			version INTEGER NOT NULL,
+			event_type TEXT NOT NULL,
+			payload BLOB NOT NULL,
+			occurred_at DATETIME NOT NULL,
+			UNIQUE (aggregate_id, version)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_events_aggregate ON events (aggregate_id, occurred_at);`,
+		// Журнал только дополняется: изменять и удалять события запрещено
+		`CREATE TRIGGER IF NOT EXISTS events_no_update BEFORE UPDATE ON events
+		BEGIN SELECT RAISE(ABORT, 'events table is append-only'); END;`,
+		`CREATE TRIGGER IF NOT EXISTS events_no_delete BEFORE DELETE ON events
+		BEGIN SELECT RAISE(ABORT, 'events table is append-only'); END;`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureProcessedEventsTable создаёт журнал обработанных событий для дедупликации в worker
+func EnsureProcessedEventsTable(db *sql.DB) error {
+	createTable := `
+	CREATE TABLE IF NOT EXISTS processed_events (
+		key TEXT PRIMARY KEY,
+		processed_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL
+	);
+	`
+	if _, err := db.Exec(createTable); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_processed_events_expires ON processed_events (expires_at);`)
+	return err
+}
+
+// EnsureUserTables создаёт таблицы собственных учётных записей: пользователи,
+// refresh-токены, токены сброса пароля и привязки OIDC. Токены хранятся только в виде хэшей
+func EnsureUserTables(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS users (
+			id TEXT PRIMARY KEY,
+			email TEXT NOT NULL UNIQUE,
+			password_hash TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL REFERENCES users (id),
+			token_hash TEXT NOT NULL UNIQUE,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			revoked_at DATETIME
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens (user_id);`,
+		`CREATE TABLE IF NOT EXISTS password_resets (
+			token_hash TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users (id),
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			used_at DATETIME
+		);`,
+		// Вход через OIDC: аккаунты провайдеров и начатые входы
+		`CREATE TABLE IF NOT EXISTS user_identities (
+			issuer TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			user_id TEXT NOT NULL REFERENCES users (id),
+			email TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (issuer, subject)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities (user_id);`,
+		`CREATE TABLE IF NOT EXISTS oidc_logins (
+			state_hash TEXT PRIMARY KEY,
+			nonce TEXT NOT NULL,
+			code_verifier TEXT NOT NULL,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL
+		);`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureAPIKeysTable creates the table of API keys for machine clients
+func EnsureAPIKeysTable(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			key_hash TEXT NOT NULL UNIQUE,
+			scopes TEXT NOT NULL,
+			expires_at DATETIME,
+			last_used_at DATETIME,
+			created_at DATETIME NOT NULL,
+			revoked_at DATETIME
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys (user_id, created_at);`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureWorkspaceTables создаёт таблицы общих списков: пространства, участники с ролями
+// и приглашения. Todo пространства хранятся в todos с owner_id = "workspace:<id>"
+func EnsureWorkspaceTables(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS workspaces (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			created_by TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS workspace_members (
+			workspace_id TEXT NOT NULL REFERENCES workspaces (id),
+			user_id TEXT NOT NULL,
+			role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (workspace_id, user_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_workspace_members_user ON workspace_members (user_id);`,
+		`CREATE TABLE IF NOT EXISTS workspace_invites (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL REFERENCES workspaces (id),
+			role TEXT NOT NULL,
+			invited_by TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			accepted_at DATETIME,
+			accepted_by TEXT
+		);`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureShareTables создаёт таблицы публичных ссылок на todo и списки и комментариев к todo.
+// owner_id совпадает с owner_id в todos: пользователь или "workspace:<id>"
+func EnsureShareTables(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS shares (
+			id TEXT PRIMARY KEY,
+			owner_id TEXT NOT NULL,
+			todo_id INTEGER,
+			scope TEXT NOT NULL CHECK (scope IN ('read', 'comment')),
+			token_hash TEXT NOT NULL UNIQUE,
+			created_by TEXT NOT NULL,
+			expires_at DATETIME,
+			created_at DATETIME NOT NULL,
+			revoked_at DATETIME
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_shares_owner ON shares (owner_id, created_at);`,
+		`CREATE TABLE IF NOT EXISTS todo_comments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			todo_id INTEGER NOT NULL,
+			owner_id TEXT NOT NULL,
+			author TEXT NOT NULL,
+			share_id TEXT NOT NULL DEFAULT '',
+			body TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_todo_comments_owner ON todo_comments (owner_id, todo_id);`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -10,6 +11,10 @@ import (
 // Config holds the database configuration
 type Config struct {
 	Path string
+	// Настройки пула соединений; нулевые значения не меняют умолчания database/sql
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
 }
 
 // NewSQLiteDB creates a new SQLite database connection
@@ -19,6 +24,8 @@ func NewSQLiteDB(config Config) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
 
+	configurePool(db, config)
+
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
@@ -30,6 +37,19 @@ func NewSQLiteDB(config Config) (*sql.DB, error) {
 	return db, nil
 }
 
+// configurePool applies the connection pool settings
+func configurePool(db *sql.DB, config Config) {
+	if config.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+}
+
 // initSchema creates the necessary database tables
 func initSchema(db *sql.DB) error {
 	query := `
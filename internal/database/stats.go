@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+// StatsCollector exports sql.DBStats of a connection pool as Prometheus metrics
+type StatsCollector struct {
+	db     *sql.DB
+	driver string
+	// last — предыдущий снимок: счётчики ожидания в DBStats накопительные
+	last sql.DBStats
+}
+
+// NewStatsCollector creates a collector; driver is used as the metrics label (database.type)
+func NewStatsCollector(db *sql.DB, driver string) *StatsCollector {
+	return &StatsCollector{db: db, driver: driver}
+}
+
+// Collect records the current pool statistics
+func (c *StatsCollector) Collect() {
+	stats := c.db.Stats()
+
+	metrics.DatabaseConnections.WithLabelValues(c.driver, "in_use").Set(float64(stats.InUse))
+	metrics.DatabaseConnections.WithLabelValues(c.driver, "idle").Set(float64(stats.Idle))
+	metrics.DatabaseMaxOpenConnections.WithLabelValues(c.driver).Set(float64(stats.MaxOpenConnections))
+
+	if delta := stats.WaitCount - c.last.WaitCount; delta > 0 {
+		metrics.DatabaseWaitCount.WithLabelValues(c.driver).Add(float64(delta))
+	}
+	if delta := stats.WaitDuration - c.last.WaitDuration; delta > 0 {
+		metrics.DatabaseWaitDuration.WithLabelValues(c.driver).Add(delta.Seconds())
+	}
+	c.last = stats
+}
+
+// Run collects statistics every interval until the context is cancelled.
+// A non-positive interval collects them once.
+func (c *StatsCollector) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		c.Collect()
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logger.Debug("Database stats collector started", zap.Duration("interval", interval))
+	for {
+		c.Collect()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
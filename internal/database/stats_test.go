@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/metrics"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsCollector(t *testing.T) {
+	db, err := NewSQLiteDB(Config{Path: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1, ConnMaxLifetime: time.Hour})
+	require.NoError(t, err)
+	defer db.Close()
+
+	collector := NewStatsCollector(db, "sqlite_test")
+	waits := testutil.ToFloat64(metrics.DatabaseWaitCount.WithLabelValues("sqlite_test"))
+
+	// Держим единственное соединение, чтобы второй запрос ждал освобождения пула
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	collector.Collect()
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.DatabaseConnections.WithLabelValues("sqlite_test", "in_use")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.DatabaseMaxOpenConnections.WithLabelValues("sqlite_test")))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var one int
+		_ = db.QueryRow("SELECT 1").Scan(&one)
+	}()
+	require.Eventually(t, func() bool { return db.Stats().WaitCount > 0 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, conn.Close())
+	<-done
+
+	collector.Collect()
+	assert.Equal(t, waits+1, testutil.ToFloat64(metrics.DatabaseWaitCount.WithLabelValues("sqlite_test")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.DatabaseConnections.WithLabelValues("sqlite_test", "in_use")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.DatabaseConnections.WithLabelValues("sqlite_test", "idle")))
+
+	// Повторный сбор без новых ожиданий не увеличивает счётчик
+	collector.Collect()
+	assert.Equal(t, waits+1, testutil.ToFloat64(metrics.DatabaseWaitCount.WithLabelValues("sqlite_test")))
+
+	// Нулевой интервал: один сбор без тикера вместо паники
+	collector.Run(context.Background(), 0)
+	assert.Equal(t, waits+1, testutil.ToFloat64(metrics.DatabaseWaitCount.WithLabelValues("sqlite_test")))
+}
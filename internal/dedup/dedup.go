@@ -0,0 +1,129 @@
+// Package dedup remembers processed event IDs so that redelivered events are skipped.
+package dedup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo_app_go/internal/config"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Бэкенды журнала обработанных событий (worker.dedup.backend)
+const (
+	BackendSQLite = "sqlite"
+	BackendRedis  = "redis"
+)
+
+// Store records processed keys for a limited time
+type Store interface {
+	// Seen reports whether the key was marked as processed and has not expired
+	Seen(ctx context.Context, key string) (bool, error)
+	// Mark records the key as processed after the event was handled; marking a known key is not an error
+	Mark(ctx context.Context, key string) error
+}
+
+// TxStore is a Store that can claim keys inside a transaction of the same database as the
+// read models: the key is committed together with the projection update or not at all
+type TxStore interface {
+	Store
+	BeginTx(ctx context.Context) (*sql.Tx, error)
+	ClaimTx(ctx context.Context, tx *sql.Tx, key string) (bool, error)
+}
+
+// SQLiteStore keeps processed keys in the processed_events table
+type SQLiteStore struct {
+	db  *sql.DB
+	ttl time.Duration
+	now func() time.Time
+}
+
+func NewSQLiteStore(db *sql.DB, ttl time.Duration) *SQLiteStore {
+	return &SQLiteStore{db: db, ttl: ttl, now: time.Now}
+}
+
+func (s *SQLiteStore) Seen(ctx context.Context, key string) (bool, error) {
+	var seen bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM processed_events WHERE key = ? AND expires_at > ?)",
+		key, s.now().UTC()).Scan(&seen)
+	return seen, err
+}
+
+func (s *SQLiteStore) Mark(ctx context.Context, key string) error {
+	_, err := s.claim(ctx, s.db, key)
+	return err
+}
+
+func (s *SQLiteStore) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return s.db.BeginTx(ctx, nil)
+}
+
+func (s *SQLiteStore) ClaimTx(ctx context.Context, tx *sql.Tx, key string) (bool, error) {
+	return s.claim(ctx, tx, key)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *SQLiteStore) claim(ctx context.Context, db execer, key string) (bool, error) {
+	// Просроченный ключ перезаписывается, действующий оставляет вставку без изменений
+	now := s.now().UTC()
+	result, err := db.ExecContext(ctx, `INSERT INTO processed_events (key, processed_at, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET processed_at = excluded.processed_at, expires_at = excluded.expires_at
+		WHERE processed_events.expires_at <= excluded.processed_at`,
+		key, now, now.Add(s.ttl))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Purge deletes expired keys and returns how many were removed
+func (s *SQLiteStore) Purge(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM processed_events WHERE expires_at <= ?", s.now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RedisStore keeps processed keys as Redis keys that expire after the TTL
+type RedisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+	prefix string
+}
+
+func NewRedisStore(cfg config.RedisConfig, ttl time.Duration) (*RedisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	// Проверяем подключение
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &RedisStore{client: client, ttl: ttl, prefix: "processed:"}, nil
+}
+
+func (s *RedisStore) Seen(ctx context.Context, key string) (bool, error) {
+	n, err := s.client.Exists(ctx, s.prefix+key).Result()
+	return n > 0, err
+}
+
+func (s *RedisStore) Mark(ctx context.Context, key string) error {
+	return s.client.Set(ctx, s.prefix+key, 1, s.ttl).Err()
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
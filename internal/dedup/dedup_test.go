@@ -0,0 +1,67 @@
+package dedup
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/database"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	require.NoError(t, database.EnsureProcessedEventsTable(db))
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewSQLiteStore(db, time.Hour)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	seen, err := store.Seen(ctx, "stats:e1")
+	require.NoError(t, err)
+	assert.False(t, seen)
+
+	// Повторная отметка известного ключа не ошибка
+	require.NoError(t, store.Mark(ctx, "stats:e1"))
+	require.NoError(t, store.Mark(ctx, "stats:e1"))
+	seen, err = store.Seen(ctx, "stats:e1")
+	require.NoError(t, err)
+	assert.True(t, seen)
+
+	// Ключ, занятый в откаченной транзакции, не сохраняется, а уже отмеченный занять нельзя
+	tx, err := store.BeginTx(ctx)
+	require.NoError(t, err)
+	claimed, err := store.ClaimTx(ctx, tx, "audit_log:e1")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = store.ClaimTx(ctx, tx, "stats:e1")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	require.NoError(t, tx.Rollback())
+	seen, err = store.Seen(ctx, "audit_log:e1")
+	require.NoError(t, err)
+	assert.False(t, seen)
+
+	// После TTL ключ считается новым и отмечается снова, а просроченные ключи удаляются при очистке
+	now = now.Add(2 * time.Hour)
+	seen, err = store.Seen(ctx, "stats:e1")
+	require.NoError(t, err)
+	assert.False(t, seen)
+	require.NoError(t, store.Mark(ctx, "stats:e1"))
+	seen, err = store.Seen(ctx, "stats:e1")
+	require.NoError(t, err)
+	assert.True(t, seen)
+
+	now = now.Add(2 * time.Hour)
+	deleted, err := store.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
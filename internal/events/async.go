@@ -0,0 +1,213 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+// ErrPublishQueueFull is returned when the async publisher could not enqueue an event in time
+var ErrPublishQueueFull = errors.New("event publish queue is full")
+
+// Причины отброшенных событий (events_dropped_total)
+const (
+	dropReasonQueueFull = "queue_full"
+	dropReasonPublish   = "publish_error"
+	dropReasonShutdown  = "shutdown"
+)
+
+// BatchPublisher is implemented by publishers that can send several events in one call
+type BatchPublisher interface {
+	PublishBatch(ctx context.Context, events []TodoEvent) error
+}
+
+// AsyncPublisher queues events in a bounded buffer and publishes them in the background,
+// in batches of batch_size or every flush_interval, whichever comes first.
+// When the buffer is full Publish waits up to enqueue_timeout and then drops the event.
+type AsyncPublisher struct {
+	next  Publisher
+	cfg   config.AsyncPublisherConfig
+	queue chan TodoEvent
+
+	mu     sync.RWMutex
+	closed bool
+
+	// ctx прерывает отправку, если graceful shutdown не уложился в таймаут
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewAsyncPublisher(next Publisher, cfg config.AsyncPublisherConfig) *AsyncPublisher {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 100 * time.Millisecond
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &AsyncPublisher{
+		next:   next,
+		cfg:    cfg,
+		queue:  make(chan TodoEvent, cfg.BufferSize),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go p.run()
+
+	logger.Info("Async event publisher started",
+		zap.Int("buffer_size", cfg.BufferSize),
+		zap.Int("batch_size", cfg.BatchSize),
+		zap.Duration("flush_interval", cfg.FlushInterval))
+	return p
+}
+
+// Publish enqueues the event without waiting for the broker
+func (p *AsyncPublisher) Publish(ctx context.Context, event TodoEvent) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrBusClosed
+	}
+
+	select {
+	case p.queue <- event:
+		metrics.EventsQueueDepth.Set(float64(len(p.queue)))
+		return nil
+	default:
+	}
+
+	// Очередь заполнена: ждём освобождения места, но не дольше enqueue_timeout
+	timer := time.NewTimer(p.cfg.EnqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case p.queue <- event:
+		metrics.EventsQueueDepth.Set(float64(len(p.queue)))
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	metrics.EventsDroppedTotal.WithLabelValues(dropReasonQueueFull).Inc()
+	eventLogger(ctx, event).Warn("Event publish queue is full, dropping event",
+		zap.Int64("todo_id", event.TodoID))
+	return ErrPublishQueueFull
+}
+
+func (p *AsyncPublisher) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]TodoEvent, 0, p.cfg.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		p.publishBatch(batch)
+		batch = batch[:0]
+		metrics.EventsQueueDepth.Set(float64(len(p.queue)))
+	}
+
+	for {
+		select {
+		case event, ok := <-p.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= p.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (p *AsyncPublisher) publishBatch(batch []TodoEvent) {
+	if p.ctx.Err() != nil {
+		p.drop(batch, dropReasonShutdown, p.ctx.Err())
+		return
+	}
+
+	metrics.EventsBatchSize.Observe(float64(len(batch)))
+
+	if bp, ok := p.next.(BatchPublisher); ok {
+		if err := bp.PublishBatch(p.ctx, batch); err != nil {
+			p.drop(batch, dropReasonPublish, err)
+		}
+		return
+	}
+
+	for _, event := range batch {
+		if err := p.next.Publish(p.ctx, event); err != nil {
+			p.drop([]TodoEvent{event}, dropReasonPublish, err)
+		}
+	}
+}
+
+func (p *AsyncPublisher) drop(batch []TodoEvent, reason string, err error) {
+	metrics.EventsDroppedTotal.WithLabelValues(reason).Add(float64(len(batch)))
+	logger.Error("Failed to publish events",
+		zap.Error(err),
+		zap.String("reason", reason),
+		zap.Int("count", len(batch)))
+}
+
+// Shutdown stops accepting events and flushes the queue. If ctx expires first,
+// the in-flight batch is aborted and the remaining events are dropped.
+// The wrapped publisher is left open.
+func (p *AsyncPublisher) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	defer p.cancel()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-p.done
+		return ctx.Err()
+	}
+}
+
+// Ping fails after shutdown and otherwise checks the wrapped publisher
+func (p *AsyncPublisher) Ping(ctx context.Context) error {
+	p.mu.RLock()
+	closed := p.closed
+	p.mu.RUnlock()
+	if closed {
+		return ErrBusClosed
+	}
+	if pinger, ok := p.next.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// Close flushes the queue and closes the wrapped publisher
+func (p *AsyncPublisher) Close() error {
+	if err := p.Shutdown(context.Background()); err != nil {
+		return err
+	}
+	return p.next.Close()
+}
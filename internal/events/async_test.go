@@ -0,0 +1,122 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingBatchPublisher struct {
+	mu      sync.Mutex
+	batches [][]TodoEvent
+	block   chan struct{}
+}
+
+func (p *recordingBatchPublisher) Publish(ctx context.Context, event TodoEvent) error {
+	return p.PublishBatch(ctx, []TodoEvent{event})
+}
+
+func (p *recordingBatchPublisher) PublishBatch(ctx context.Context, batch []TodoEvent) error {
+	if p.block != nil {
+		select {
+		case <-p.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batches = append(p.batches, append([]TodoEvent(nil), batch...))
+	return nil
+}
+
+func (p *recordingBatchPublisher) Close() error { return nil }
+
+func (p *recordingBatchPublisher) sizes() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sizes := make([]int, 0, len(p.batches))
+	for _, b := range p.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func TestAsyncPublisher_BatchesBySize(t *testing.T) {
+	next := &recordingBatchPublisher{}
+	p := NewAsyncPublisher(next, config.AsyncPublisherConfig{
+		BufferSize:    10,
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+	})
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, p.Publish(context.Background(), TodoEvent{ID: fmt.Sprint(i)}))
+	}
+
+	require.Eventually(t, func() bool { return len(next.sizes()) == 2 }, time.Second, 5*time.Millisecond)
+
+	// Остаток отправляется при shutdown
+	require.NoError(t, p.Shutdown(context.Background()))
+	assert.Equal(t, []int{3, 3, 1}, next.sizes())
+	assert.ErrorIs(t, p.Publish(context.Background(), TodoEvent{ID: "late"}), ErrBusClosed)
+}
+
+func TestAsyncPublisher_FlushesByInterval(t *testing.T) {
+	next := &recordingBatchPublisher{}
+	p := NewAsyncPublisher(next, config.AsyncPublisherConfig{
+		BufferSize:    10,
+		BatchSize:     100,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer p.Close()
+
+	require.NoError(t, p.Publish(context.Background(), TodoEvent{ID: "1"}))
+	require.Eventually(t, func() bool { return len(next.sizes()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestAsyncPublisher_DropsWhenQueueIsFull(t *testing.T) {
+	next := &recordingBatchPublisher{block: make(chan struct{})}
+	p := NewAsyncPublisher(next, config.AsyncPublisherConfig{
+		BufferSize:     1,
+		BatchSize:      1,
+		FlushInterval:  time.Hour,
+		EnqueueTimeout: 10 * time.Millisecond,
+	})
+
+	// Первое событие забирает фоновая горутина и блокируется в publisher, второе занимает буфер
+	require.NoError(t, p.Publish(context.Background(), TodoEvent{ID: "1"}))
+	require.Eventually(t, func() bool { return len(p.queue) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, p.Publish(context.Background(), TodoEvent{ID: "2"}))
+
+	assert.ErrorIs(t, p.Publish(context.Background(), TodoEvent{ID: "3"}), ErrPublishQueueFull)
+
+	close(next.block)
+	require.NoError(t, p.Shutdown(context.Background()))
+	assert.Equal(t, []int{1, 1}, next.sizes())
+}
+
+func TestAsyncPublisher_ShutdownTimeout(t *testing.T) {
+	next := &recordingBatchPublisher{block: make(chan struct{})}
+	p := NewAsyncPublisher(next, config.AsyncPublisherConfig{
+		BufferSize:    10,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+	})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, p.Publish(context.Background(), TodoEvent{ID: fmt.Sprint(i)}))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.Shutdown(ctx), context.DeadlineExceeded)
+	assert.Empty(t, next.sizes())
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"todo_app_go/internal/config"
+)
+
+// Драйверы шины событий (events.driver)
+const (
+	DriverKafka  = "kafka"
+	DriverNATS   = "nats"
+	DriverMemory = "memory"
+	DriverNone   = "none"
+)
+
+// Publisher publishes todo events to the event bus
+type Publisher interface {
+	Publish(ctx context.Context, event TodoEvent) error
+	Close() error
+}
+
+// Subscriber delivers todo events from the event bus to a handler until the context is cancelled
+type Subscriber interface {
+	Subscribe(ctx context.Context, handler EventHandler) error
+	Close() error
+}
+
+// SubscribeNotifier is implemented by subscribers that report when Subscribe has connected
+// to the broker and started receiving events; until then the subscriber is not ready
+type SubscribeNotifier interface {
+	// OnSubscribed sets the function called once the subscription is established
+	OnSubscribed(fn func())
+}
+
+// Pinger is implemented by publishers and subscribers that can check their connection
+// to the broker; it is used by readiness checks
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// NewPublisher creates a publisher for the configured driver.
+// It returns nil without an error when events are disabled.
+func NewPublisher(eventsCfg config.EventsConfig, kafkaCfg config.KafkaConfig) (Publisher, error) {
+	switch eventsCfg.Driver {
+	case DriverKafka:
+		if len(kafkaCfg.Brokers) == 0 {
+			return nil, nil
+		}
+		producer, err := NewKafkaProducer(kafkaCfg, eventsCfg)
+		if err != nil {
+			return nil, err
+		}
+		return producer, nil
+	case DriverNATS:
+		publisher, err := NewNATSPublisher(eventsCfg)
+		if err != nil {
+			return nil, err
+		}
+		return publisher, nil
+	case DriverMemory:
+		return NewMemoryBus(eventsCfg.Memory), nil
+	case DriverNone, "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown events driver %q", eventsCfg.Driver)
+	}
+}
+
+// NewSubscriber creates a subscriber for the configured driver. The memory driver only works
+// inside one process, so its subscriber is obtained from the MemoryBus returned by NewPublisher.
+func NewSubscriber(eventsCfg config.EventsConfig, kafkaCfg config.KafkaConfig) (Subscriber, error) {
+	switch eventsCfg.Driver {
+	case DriverKafka:
+		consumer, err := NewKafkaConsumer(kafkaCfg)
+		if err != nil {
+			return nil, err
+		}
+		return consumer, nil
+	case DriverNATS:
+		subscriber, err := NewNATSSubscriber(eventsCfg.NATS)
+		if err != nil {
+			return nil, err
+		}
+		return subscriber, nil
+	case DriverMemory:
+		return nil, fmt.Errorf("memory events driver does not support standalone subscribers")
+	default:
+		return nil, fmt.Errorf("events driver %q does not support subscribers", eventsCfg.Driver)
+	}
+}
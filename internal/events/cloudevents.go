@@ -0,0 +1,212 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo_app_go/internal/models"
+)
+
+// Версионирование схемы событий:
+//   - версия входит в тип события (com.todoapp.todo.<kind>.v<N>) и в dataschema;
+//   - внутри версии допускаются только обратно совместимые изменения (новые необязательные поля);
+//   - удаление, переименование или смена типа поля требует новой версии;
+//   - producer публикует одну версию (events.schema_version), а consumer обязан
+//     понимать текущую и предыдущую версии, поэтому FromCloudEvent принимает v1 и v2;
+//   - новые типы событий появляются только в новой версии: completed и reopened
+//     публикуются в v1 как updated.
+const (
+	CloudEventsSpecVersion = "1.0"
+	EventTypePrefix        = "com.todoapp.todo."
+	DataSchemaBaseURL      = "https://schemas.todoapp.com/todo"
+
+	SchemaVersion1 = 1
+	SchemaVersion2 = 2
+
+	ContentModeStructured = "structured"
+	ContentModeBinary     = "binary"
+
+	ContentTypeCloudEventsJSON = "application/cloudevents+json; charset=UTF-8"
+	ContentTypeJSON            = "application/json"
+)
+
+// CloudEvent is a CloudEvents 1.0 envelope in JSON format
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            time.Time       `json:"time"`
+	Subject         string          `json:"subject,omitempty"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+
+	// Атрибуты-расширения: distributed tracing (traceparent), корреляция с HTTP-запросом
+	// и владелец todo, по которому consumer может фильтровать события без разбора data
+	TraceParent string `json:"traceparent,omitempty"`
+	RequestID   string `json:"requestid,omitempty"`
+	Actor       string `json:"actor,omitempty"`
+	OwnerID     string `json:"ownerid,omitempty"`
+}
+
+// TodoDataV1 is the v1 payload: a flat todo snapshot. Update events also carry the
+// changed fields; the field is optional, so consumers that predate it are not affected.
+type TodoDataV1 struct {
+	models.Todo
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// TodoDataV2 is the v2 payload: deleted events carry no snapshot,
+// update events carry the changed fields with old and new values
+type TodoDataV2 struct {
+	TodoID  int64         `json:"todo_id"`
+	OwnerID string        `json:"owner_id,omitempty"`
+	Todo    *models.Todo  `json:"todo,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// CloudEventType builds the versioned CloudEvents type, e.g. com.todoapp.todo.updated.v1
+func CloudEventType(kind string, version int) string {
+	return fmt.Sprintf("%s%s.v%d", EventTypePrefix, kind, version)
+}
+
+// ParseCloudEventType extracts the event kind and schema version from a CloudEvents type
+func ParseCloudEventType(ceType string) (string, int, error) {
+	if !strings.HasPrefix(ceType, EventTypePrefix) {
+		return "", 0, fmt.Errorf("unknown event type %q", ceType)
+	}
+
+	rest := strings.TrimPrefix(ceType, EventTypePrefix)
+	idx := strings.LastIndex(rest, ".v")
+	if idx <= 0 {
+		return "", 0, fmt.Errorf("event type %q has no version", ceType)
+	}
+
+	version, err := strconv.Atoi(rest[idx+2:])
+	if err != nil {
+		return "", 0, fmt.Errorf("event type %q has invalid version: %w", ceType, err)
+	}
+	return rest[:idx], version, nil
+}
+
+func validateSchemaVersion(version int) error {
+	if version != SchemaVersion1 && version != SchemaVersion2 {
+		return fmt.Errorf("unsupported event schema version %d", version)
+	}
+	return nil
+}
+
+// cloudEventKind returns the event kind published in the given schema version.
+// Consumers of v1 only know the types that existed in v1, so status changes stay updated there.
+func cloudEventKind(eventType string, version int) string {
+	if version == SchemaVersion1 && (eventType == TypeCompleted || eventType == TypeReopened) {
+		return TypeUpdated
+	}
+	return eventType
+}
+
+// DataSchemaURL returns the schema URI for the given event kind and version
+func DataSchemaURL(kind string, version int) string {
+	return fmt.Sprintf("%s/%s/v%d.json", DataSchemaBaseURL, kind, version)
+}
+
+// ToCloudEvent converts a todo event into a CloudEvent with the given schema version
+func ToCloudEvent(event TodoEvent, source string, version int) (CloudEvent, error) {
+	var data interface{}
+	switch version {
+	case SchemaVersion1:
+		data = TodoDataV1{Todo: event.Payload, Changes: event.Changes}
+	case SchemaVersion2:
+		payload := TodoDataV2{TodoID: event.TodoID, OwnerID: event.OwnerID, Changes: event.Changes}
+		if event.Type != TypeDeleted {
+			todo := event.Payload
+			payload.Todo = &todo
+		}
+		data = payload
+	default:
+		return CloudEvent{}, fmt.Errorf("unsupported schema version %d", version)
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return CloudEvent{}, fmt.Errorf("failed to marshal event data: %w", err)
+	}
+
+	kind := cloudEventKind(event.Type, version)
+	return CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              event.ID,
+		Source:          source,
+		Type:            CloudEventType(kind, version),
+		Time:            event.Timestamp.UTC(),
+		Subject:         fmt.Sprintf("todos/%d", event.TodoID),
+		DataSchema:      DataSchemaURL(kind, version),
+		DataContentType: ContentTypeJSON,
+		Data:            raw,
+		TraceParent:     event.Metadata.TraceParent,
+		RequestID:       event.Metadata.RequestID,
+		Actor:           event.Metadata.Actor,
+		OwnerID:         event.OwnerID,
+	}, nil
+}
+
+// FromCloudEvent converts a CloudEvent of any supported schema version into a todo event
+func FromCloudEvent(ce CloudEvent) (TodoEvent, error) {
+	if ce.SpecVersion != CloudEventsSpecVersion {
+		return TodoEvent{}, fmt.Errorf("unsupported specversion %q", ce.SpecVersion)
+	}
+
+	kind, version, err := ParseCloudEventType(ce.Type)
+	if err != nil {
+		return TodoEvent{}, err
+	}
+
+	event := TodoEvent{
+		ID:        ce.ID,
+		Type:      kind,
+		OwnerID:   ce.OwnerID,
+		Timestamp: ce.Time,
+		Metadata: EventMetadata{
+			RequestID:   ce.RequestID,
+			TraceParent: ce.TraceParent,
+			Actor:       ce.Actor,
+		},
+	}
+
+	switch version {
+	case SchemaVersion1:
+		var data TodoDataV1
+		if err := json.Unmarshal(ce.Data, &data); err != nil {
+			return TodoEvent{}, fmt.Errorf("failed to unmarshal v1 data: %w", err)
+		}
+		event.TodoID = data.ID
+		event.Payload = data.Todo
+		event.Changes = data.Changes
+		if event.OwnerID == "" {
+			event.OwnerID = data.OwnerID
+		}
+	case SchemaVersion2:
+		var data TodoDataV2
+		if err := json.Unmarshal(ce.Data, &data); err != nil {
+			return TodoEvent{}, fmt.Errorf("failed to unmarshal v2 data: %w", err)
+		}
+		event.TodoID = data.TodoID
+		event.Changes = data.Changes
+		if data.Todo != nil {
+			event.Payload = *data.Todo
+		} else {
+			event.Payload = models.Todo{ID: data.TodoID, OwnerID: data.OwnerID}
+		}
+		if event.OwnerID == "" {
+			event.OwnerID = data.OwnerID
+		}
+	default:
+		return TodoEvent{}, fmt.Errorf("unsupported schema version %d", version)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,147 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/models"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudEventType(t *testing.T) {
+	assert.Equal(t, "com.todoapp.todo.updated.v1", CloudEventType(TypeUpdated, SchemaVersion1))
+
+	kind, version, err := ParseCloudEventType("com.todoapp.todo.deleted.v2")
+	assert.NoError(t, err)
+	assert.Equal(t, TypeDeleted, kind)
+	assert.Equal(t, SchemaVersion2, version)
+
+	_, _, err = ParseCloudEventType("org.example.deleted.v1")
+	assert.Error(t, err)
+	_, _, err = ParseCloudEventType("com.todoapp.todo.deleted")
+	assert.Error(t, err)
+}
+
+func TestKafkaMessage_RoundTrip(t *testing.T) {
+	todo := models.Todo{ID: 7, OwnerID: "alice", Task: "Write tests", Completed: true, CreatedAt: time.Now().UTC()}
+
+	cases := []struct {
+		name    string
+		mode    string
+		version int
+		event   TodoEvent
+	}{
+		{"structured v1", ContentModeStructured, SchemaVersion1, CreateTodoUpdatedEvent(models.Todo{ID: 7, Task: "Draft"}, todo)},
+		{"binary v1", ContentModeBinary, SchemaVersion1, CreateTodoCreatedEvent(todo)},
+		{"structured v2", ContentModeStructured, SchemaVersion2, CreateTodoUpdatedEvent(models.Todo{ID: 7, Task: "Draft"}, todo)},
+		{"binary v2 deleted", ContentModeBinary, SchemaVersion2, CreateTodoDeletedEvent(7, "alice")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ce, err := ToCloudEvent(tc.event, "/todo-app", tc.version)
+			assert.NoError(t, err)
+			assert.Equal(t, "1.0", ce.SpecVersion)
+			assert.Equal(t, "todos/7", ce.Subject)
+			kind := cloudEventKind(tc.event.Type, tc.version)
+			assert.Equal(t, DataSchemaURL(kind, tc.version), ce.DataSchema)
+
+			msg, err := EncodeKafkaMessage(ce, tc.mode)
+			assert.NoError(t, err)
+
+			decoded, err := DecodeKafkaMessage(msg)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.event.ID, decoded.ID)
+			assert.Equal(t, kind, decoded.Type)
+			assert.Equal(t, tc.event.TodoID, decoded.TodoID)
+			assert.Equal(t, "alice", decoded.OwnerID)
+			assert.Equal(t, tc.event.Payload.Task, decoded.Payload.Task)
+			assert.True(t, tc.event.Timestamp.Equal(decoded.Timestamp))
+		})
+	}
+}
+
+func TestDecodeKafkaMessage_Legacy(t *testing.T) {
+	msg := kafka.Message{Value: []byte(`{"type":"created","todo_id":3,"payload":{"id":3,"task":"old"}}`)}
+
+	event, err := DecodeKafkaMessage(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, TypeCreated, event.Type)
+	assert.Equal(t, "old", event.Payload.Task)
+}
+
+func TestFromCloudEvent_UnsupportedVersion(t *testing.T) {
+	_, err := FromCloudEvent(CloudEvent{SpecVersion: "1.0", Type: "com.todoapp.todo.created.v9"})
+	assert.Error(t, err)
+}
+
+func TestCreateTodoUpdatedEvent_Changes(t *testing.T) {
+	before := models.Todo{ID: 1, Task: "Buy milk"}
+
+	renamed := before
+	renamed.Task = "Buy oat milk"
+	event := CreateTodoUpdatedEvent(before, renamed)
+	assert.Equal(t, TypeUpdated, event.Type)
+	assert.Equal(t, []FieldChange{{Field: "task", Old: "Buy milk", New: "Buy oat milk"}}, event.Changes)
+
+	completed := before
+	completed.Completed = true
+	event = CreateTodoUpdatedEvent(before, completed)
+	assert.Equal(t, TypeCompleted, event.Type)
+	assert.Equal(t, []FieldChange{{Field: "completed", Old: false, New: true}}, event.Changes)
+
+	event = CreateTodoUpdatedEvent(completed, before)
+	assert.Equal(t, TypeReopened, event.Type)
+
+	// Consumer v1 не знает completed и reopened: смена статуса публикуется в v1 как updated
+	ce, err := ToCloudEvent(event, "/todo-app", SchemaVersion1)
+	assert.NoError(t, err)
+	assert.Equal(t, "com.todoapp.todo.updated.v1", ce.Type)
+	assert.Equal(t, DataSchemaURL(TypeUpdated, SchemaVersion1), ce.DataSchema)
+	ce, err = ToCloudEvent(event, "/todo-app", SchemaVersion2)
+	assert.NoError(t, err)
+	assert.Equal(t, "com.todoapp.todo.reopened.v2", ce.Type)
+
+	// Изменения доходят до consumer в обеих версиях схемы
+	for _, version := range []int{SchemaVersion1, SchemaVersion2} {
+		ce, err := ToCloudEvent(CreateTodoUpdatedEvent(before, renamed), "/todo-app", version)
+		assert.NoError(t, err)
+		decoded, err := FromCloudEvent(ce)
+		assert.NoError(t, err)
+		assert.Equal(t, []FieldChange{{Field: "task", Old: "Buy milk", New: "Buy oat milk"}}, decoded.Changes)
+	}
+}
+
+func TestKafkaProducer_SchemaV1CarriesChanges(t *testing.T) {
+	// v1 — версия по умолчанию (events.schema_version, проверяется в пакете config)
+	producer, err := NewKafkaProducer(
+		config.KafkaConfig{Brokers: []string{"localhost:9092"}, Topic: "todo-events", ContentMode: ContentModeStructured},
+		config.EventsConfig{Source: "/todo-app", SchemaVersion: SchemaVersion1},
+	)
+	require.NoError(t, err)
+	writer := &fakeWriter{}
+	producer.writer = writer
+
+	before := models.Todo{ID: 1, Task: "Buy milk"}
+	after := models.Todo{ID: 1, Task: "Buy oat milk"}
+	require.NoError(t, producer.Publish(context.Background(), CreateTodoUpdatedEvent(before, after)))
+
+	require.Len(t, writer.written, 1)
+	var ce CloudEvent
+	require.NoError(t, json.Unmarshal(writer.written[0].Value, &ce))
+	assert.Equal(t, "com.todoapp.todo.updated.v1", ce.Type)
+	var data struct {
+		Task    string            `json:"task"`
+		Changes []json.RawMessage `json:"changes"`
+	}
+	require.NoError(t, json.Unmarshal(ce.Data, &data))
+	assert.Equal(t, "Buy oat milk", data.Task)
+	require.Len(t, data.Changes, 1)
+	assert.JSONEq(t, `{"field": "task", "old": "Buy milk", "new": "Buy oat milk"}`, string(data.Changes[0]))
+}
@@ -0,0 +1,206 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Заголовки, которые добавляются к сообщению при отправке в DLQ
+const (
+	HeaderDLQReason            = "x-dlq-reason"
+	HeaderDLQError             = "x-dlq-error"
+	HeaderDLQAttempts          = "x-dlq-attempts"
+	HeaderDLQOriginalTopic     = "x-dlq-original-topic"
+	HeaderDLQOriginalPartition = "x-dlq-original-partition"
+	HeaderDLQOriginalOffset    = "x-dlq-original-offset"
+	HeaderDLQFailedAt          = "x-dlq-failed-at"
+
+	dlqHeaderPrefix = "x-dlq-"
+
+	dlqReasonDecode  = "decode"
+	dlqReasonHandler = "handler"
+)
+
+// sendToDLQ writes the original message with error headers to the DLQ topic.
+// The write is retried until it succeeds so that the offset is never committed for a lost message.
+// Without a DLQ it returns an error: the consumer stops and the message is redelivered after restart.
+func (c *KafkaConsumer) sendToDLQ(ctx context.Context, m kafka.Message, reason string, cause error, attempts int) error {
+	if c.dlq == nil {
+		return fmt.Errorf("DLQ is not configured, message at partition %d offset %d is not committed (%s): %w",
+			m.Partition, m.Offset, reason, cause)
+	}
+
+	msg := NewDLQMessage(m, reason, cause, attempts, time.Now())
+
+	for i := 0; ; i++ {
+		err := c.dlq.WriteMessages(ctx, msg)
+		if err == nil {
+			metrics.KafkaMessagesDeadLettered.WithLabelValues(reason).Inc()
+			logger.Warn("Message sent to DLQ",
+				zap.String("reason", reason),
+				zap.String("topic", m.Topic),
+				zap.Int("partition", m.Partition),
+				zap.Int64("offset", m.Offset))
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		delay := backoffDelay(c.cfg.InitialBackoff, c.cfg.MaxBackoff, i)
+		logger.Error("Failed to write message to DLQ", zap.Error(err), zap.Duration("retry_in", delay))
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// NewDLQMessage copies the original message and appends headers describing the failure
+func NewDLQMessage(m kafka.Message, reason string, cause error, attempts int, failedAt time.Time) kafka.Message {
+	headers := make([]kafka.Header, 0, len(m.Headers)+7)
+	headers = append(headers, m.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderDLQReason, Value: []byte(reason)},
+		kafka.Header{Key: HeaderDLQError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderDLQAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderDLQOriginalTopic, Value: []byte(m.Topic)},
+		kafka.Header{Key: HeaderDLQOriginalPartition, Value: []byte(strconv.Itoa(m.Partition))},
+		kafka.Header{Key: HeaderDLQOriginalOffset, Value: []byte(strconv.FormatInt(m.Offset, 10))},
+		kafka.Header{Key: HeaderDLQFailedAt, Value: []byte(failedAt.UTC().Format(time.RFC3339))},
+	)
+
+	return kafka.Message{
+		Key:     m.Key,
+		Value:   m.Value,
+		Headers: headers,
+	}
+}
+
+// ReplayOptions controls DLQ replay
+type ReplayOptions struct {
+	Limit       int           // максимум сообщений, 0 — без ограничений
+	IdleTimeout time.Duration // остановиться, если новых сообщений нет
+	DryRun      bool          // только вывести сообщения, не переотправляя их
+}
+
+// ReplayDLQ reads messages from the DLQ topic and publishes them back to their original topic
+// with the DLQ headers removed. It returns the number of replayed messages.
+func ReplayDLQ(ctx context.Context, cfg config.KafkaConfig, opts ReplayOptions) (int, error) {
+	if cfg.Consumer.DLQTopic == "" {
+		return 0, errors.New("kafka.consumer.dlq_topic is not configured")
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: cfg.Brokers,
+		Topic:   cfg.Consumer.DLQTopic,
+		GroupID: cfg.GroupID + "-dlq-replay",
+	})
+	defer reader.Close()
+
+	writer := &kafka.Writer{
+		Addr:     kafka.TCP(cfg.Brokers...),
+		Balancer: &kafka.Hash{},
+	}
+	defer writer.Close()
+
+	return replayMessages(ctx, reader, writer, cfg.Topic, opts)
+}
+
+func replayMessages(ctx context.Context, reader messageReader, writer messageWriter, defaultTopic string, opts ReplayOptions) (int, error) {
+	replayed := 0
+	for opts.Limit == 0 || replayed < opts.Limit {
+		fetchCtx, cancel := context.WithTimeout(ctx, opts.IdleTimeout)
+		m, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				// Очередь прочитана до конца
+				return replayed, nil
+			}
+			return replayed, fmt.Errorf("failed to read DLQ message: %w", err)
+		}
+
+		msg := StripDLQHeaders(m)
+		msg.Topic = dlqHeader(m, HeaderDLQOriginalTopic)
+		if msg.Topic == "" {
+			msg.Topic = defaultTopic
+		}
+
+		logger.Info("Replaying DLQ message",
+			zap.String("topic", msg.Topic),
+			zap.String("reason", dlqHeader(m, HeaderDLQReason)),
+			zap.String("error", dlqHeader(m, HeaderDLQError)),
+			zap.Int64("dlq_offset", m.Offset),
+			zap.Bool("dry_run", opts.DryRun))
+
+		if opts.DryRun {
+			replayed++
+			continue
+		}
+
+		if err := writer.WriteMessages(ctx, msg); err != nil {
+			return replayed, fmt.Errorf("failed to republish message: %w", err)
+		}
+		if err := reader.CommitMessages(ctx, m); err != nil {
+			return replayed, fmt.Errorf("failed to commit DLQ message: %w", err)
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+// StripDLQHeaders returns a copy of the message without x-dlq-* headers
+func StripDLQHeaders(m kafka.Message) kafka.Message {
+	headers := make([]kafka.Header, 0, len(m.Headers))
+	for _, h := range m.Headers {
+		if !strings.HasPrefix(strings.ToLower(h.Key), dlqHeaderPrefix) {
+			headers = append(headers, h)
+		}
+	}
+	return kafka.Message{Key: m.Key, Value: m.Value, Headers: headers}
+}
+
+func dlqHeader(m kafka.Message, key string) string {
+	for _, h := range m.Headers {
+		if strings.EqualFold(h.Key, key) {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// backoffDelay returns initial * 2^attempt capped at max
+func backoffDelay(initial, max time.Duration, attempt int) time.Duration {
+	delay := initial
+	for i := 0; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// sleepContext waits for the given duration or until the context is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,192 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/models"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReader struct {
+	messages  []kafka.Message
+	committed []int64
+	cancel    context.CancelFunc
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if len(r.messages) == 0 {
+		if r.cancel != nil {
+			r.cancel()
+		}
+		<-ctx.Done()
+		return kafka.Message{}, ctx.Err()
+	}
+	m := r.messages[0]
+	r.messages = r.messages[1:]
+	return m, nil
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	for _, m := range msgs {
+		r.committed = append(r.committed, m.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+type fakeWriter struct {
+	written []kafka.Message
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.written = append(w.written, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+func encodedEvent(t *testing.T, offset int64, event TodoEvent) kafka.Message {
+	ce, err := ToCloudEvent(event, "/todo-app", SchemaVersion1)
+	assert.NoError(t, err)
+	m, err := EncodeKafkaMessage(ce, ContentModeStructured)
+	assert.NoError(t, err)
+	m.Topic = "todo-events"
+	m.Offset = offset
+	return m
+}
+
+func newTestConsumer(reader *fakeReader, dlq *fakeWriter) *KafkaConsumer {
+	return &KafkaConsumer{
+		reader: reader,
+		dlq:    dlq,
+		topic:  "todo-events",
+		cfg: config.KafkaConsumerConfig{
+			MaxRetries:     2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     5 * time.Millisecond,
+			DLQTopic:       "todo-events.dlq",
+		},
+	}
+}
+
+func TestConsumeMessages_RetryAndDLQ(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reader := &fakeReader{
+		cancel: cancel,
+		messages: []kafka.Message{
+			encodedEvent(t, 1, CreateTodoCreatedEvent(models.Todo{ID: 1})),
+			encodedEvent(t, 2, CreateTodoCreatedEvent(models.Todo{ID: 2})),
+			{Topic: "todo-events", Offset: 3, Value: []byte("not json")},
+		},
+	}
+	dlq := &fakeWriter{}
+	consumer := newTestConsumer(reader, dlq)
+
+	calls := map[int64]int{}
+	err := consumer.ConsumeMessages(ctx, func(ctx context.Context, event TodoEvent) error {
+		calls[event.TodoID]++
+		// todo 1 обрабатывается со второй попытки, todo 2 не обрабатывается никогда
+		if event.TodoID == 1 && calls[1] == 1 || event.TodoID == 2 {
+			return errors.New("projection unavailable")
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.Equal(t, 2, calls[1])
+	assert.Equal(t, 3, calls[2]) // первая попытка + 2 повтора
+	assert.Equal(t, []int64{1, 2, 3}, reader.committed)
+
+	assert.Len(t, dlq.written, 2)
+	assert.Equal(t, "handler", dlqHeader(dlq.written[0], HeaderDLQReason))
+	assert.Equal(t, "projection unavailable", dlqHeader(dlq.written[0], HeaderDLQError))
+	assert.Equal(t, "3", dlqHeader(dlq.written[0], HeaderDLQAttempts))
+	assert.Equal(t, "2", dlqHeader(dlq.written[0], HeaderDLQOriginalOffset))
+	assert.Equal(t, "decode", dlqHeader(dlq.written[1], HeaderDLQReason))
+	assert.Equal(t, []byte("not json"), dlq.written[1].Value)
+}
+
+func TestConsumeMessages_WithoutDLQKeepsOffset(t *testing.T) {
+	reader := &fakeReader{
+		messages: []kafka.Message{
+			encodedEvent(t, 1, CreateTodoCreatedEvent(models.Todo{ID: 1})),
+			{Topic: "todo-events", Offset: 2, Value: []byte("not json")},
+		},
+	}
+	consumer := newTestConsumer(reader, nil)
+	consumer.dlq = nil
+
+	// Сообщение не теряется: consumer останавливается, offset не подтверждается
+	err := consumer.ConsumeMessages(context.Background(), func(ctx context.Context, event TodoEvent) error {
+		return nil
+	})
+	assert.ErrorContains(t, err, "DLQ is not configured")
+	assert.Equal(t, []int64{1}, reader.committed)
+}
+
+func TestReplayMessages(t *testing.T) {
+	original := encodedEvent(t, 5, CreateTodoCreatedEvent(models.Todo{ID: 5}))
+	dlqMessage := NewDLQMessage(original, dlqReasonHandler, errors.New("boom"), 4, time.Now())
+	dlqMessage.Offset = 10
+
+	reader := &fakeReader{messages: []kafka.Message{dlqMessage}}
+	writer := &fakeWriter{}
+
+	replayed, err := replayMessages(context.Background(), reader, writer, "fallback", ReplayOptions{
+		IdleTimeout: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	assert.Equal(t, []int64{10}, reader.committed)
+
+	assert.Len(t, writer.written, 1)
+	assert.Equal(t, "todo-events", writer.written[0].Topic)
+	assert.Equal(t, original.Headers, writer.written[0].Headers)
+
+	event, err := DecodeKafkaMessage(writer.written[0])
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), event.TodoID)
+}
+
+func TestKafkaConsumer_SubscribedAfterBrokerIsReachable(t *testing.T) {
+	consumer := newTestConsumer(&fakeReader{}, &fakeWriter{})
+	subscribed := false
+	consumer.OnSubscribed(func() { subscribed = true })
+
+	// Брокер недоступен: подписка не считается установленной
+	consumer.brokers = []string{"127.0.0.1:1"}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, consumer.Subscribe(ctx, func(context.Context, TodoEvent) error { return nil }), context.DeadlineExceeded)
+	assert.False(t, subscribed)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	consumer.brokers = []string{listener.Addr().String()}
+	ctx, cancel = context.WithCancel(context.Background())
+	consumer.reader = &fakeReader{cancel: cancel}
+	assert.ErrorIs(t, consumer.Subscribe(ctx, func(context.Context, TodoEvent) error { return nil }), context.Canceled)
+	assert.True(t, subscribed)
+}
@@ -2,7 +2,7 @@ package events
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,61 +10,145 @@ import (
 	"todo_app_go/internal/logger"
 	"todo_app_go/internal/metrics"
 	"todo_app_go/internal/models"
+	"todo_app_go/internal/tracing"
 
+	"github.com/google/uuid"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// Типы событий todo
+const (
+	TypeCreated = "created"
+	TypeUpdated = "updated"
+	TypeDeleted = "deleted"
+	// completed и reopened публикуются вместо updated, когда меняется статус выполнения
+	TypeCompleted = "completed"
+	TypeReopened  = "reopened"
+)
+
 type TodoEvent struct {
-	Type      string      `json:"type"` // "created", "updated", "deleted"
-	TodoID    int64       `json:"todo_id"`
-	Timestamp time.Time   `json:"timestamp"`
-	Payload   models.Todo `json:"payload"`
+	ID        string        `json:"id"`
+	Type      string        `json:"type"` // "created", "updated", "completed", "reopened", "deleted"
+	TodoID    int64         `json:"todo_id"`
+	OwnerID   string        `json:"owner_id,omitempty"` // владелец todo; пустой без аутентификации
+	Timestamp time.Time     `json:"timestamp"`
+	Payload   models.Todo   `json:"payload"`
+	Changes   []FieldChange `json:"changes,omitempty"` // только для updated/completed/reopened
+	Metadata  EventMetadata `json:"metadata"`
+}
+
+// FieldChange describes a single changed todo field
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
 }
 
 type KafkaProducer struct {
-	writer *kafka.Writer
-	topic  string
+	writer        messageWriter
+	brokers       []string
+	topic         string
+	source        string
+	contentMode   string
+	schemaVersion int
 }
 
 type KafkaConsumer struct {
-	reader *kafka.Reader
-	topic  string
+	reader       messageReader
+	brokers      []string
+	dlq          messageWriter
+	topic        string
+	cfg          config.KafkaConsumerConfig
+	onSubscribed func()
+}
+
+// EventHandler processes a single consumed event
+type EventHandler func(ctx context.Context, event TodoEvent) error
+
+// messageReader и messageWriter позволяют подменить kafka.Reader/Writer в тестах
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
 }
 
-func NewKafkaProducer(cfg config.KafkaConfig) (*KafkaProducer, error) {
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+func NewKafkaProducer(cfg config.KafkaConfig, eventsCfg config.EventsConfig) (*KafkaProducer, error) {
 	writer := &kafka.Writer{
 		Addr:     kafka.TCP(cfg.Brokers...),
 		Topic:    cfg.Topic,
 		Balancer: &kafka.LeastBytes{},
+		// По умолчанию writer ждёт заполнения пакета до 1s; пакеты собирает AsyncPublisher
+		BatchTimeout: 10 * time.Millisecond,
 	}
 
-	logger.Info("Kafka producer initialized successfully")
+	switch cfg.ContentMode {
+	case ContentModeStructured, ContentModeBinary:
+	default:
+		return nil, fmt.Errorf("unsupported kafka content mode %q", cfg.ContentMode)
+	}
+	if err := validateSchemaVersion(eventsCfg.SchemaVersion); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Kafka producer initialized successfully",
+		zap.String("content_mode", cfg.ContentMode),
+		zap.Int("schema_version", eventsCfg.SchemaVersion))
 	return &KafkaProducer{
-		writer: writer,
-		topic:  cfg.Topic,
+		writer:        writer,
+		brokers:       cfg.Brokers,
+		topic:         cfg.Topic,
+		source:        eventsCfg.Source,
+		contentMode:   cfg.ContentMode,
+		schemaVersion: eventsCfg.SchemaVersion,
 	}, nil
 }
 
-func (p *KafkaProducer) PublishTodoEvent(event TodoEvent) error {
-	data, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
-	}
+func (p *KafkaProducer) Publish(ctx context.Context, event TodoEvent) error {
+	return p.PublishBatch(ctx, []TodoEvent{event})
+}
 
-	err = p.writer.WriteMessages(context.Background(), kafka.Message{
-		Key:   []byte(fmt.Sprintf("todo-%d", event.TodoID)),
-		Value: data,
-	})
+// PublishBatch writes all events with a single WriteMessages call
+func (p *KafkaProducer) PublishBatch(ctx context.Context, batch []TodoEvent) (err error) {
+	spans := make([]trace.Span, 0, len(batch))
+	defer func() {
+		for _, span := range spans {
+			tracing.End(span, err)
+		}
+	}()
 
-	if err != nil {
+	msgs := make([]kafka.Message, 0, len(batch))
+	for _, event := range batch {
+		event, span := startPublishSpan(ctx, event, DriverKafka, p.topic)
+		spans = append(spans, span)
+
+		ce, err := ToCloudEvent(event, p.source, p.schemaVersion)
+		if err != nil {
+			return err
+		}
+
+		msg, err := EncodeKafkaMessage(ce, p.contentMode)
+		if err != nil {
+			return err
+		}
+		msg.Key = []byte(fmt.Sprintf("todo-%d", event.TodoID))
+		msgs = append(msgs, msg)
+	}
+
+	if err := p.writer.WriteMessages(ctx, msgs...); err != nil {
 		return fmt.Errorf("failed to publish event: %w", err)
 	}
 
-	metrics.KafkaMessagesPublished.Inc()
-	logger.Info("Todo event published",
-		zap.String("type", event.Type),
-		zap.Int64("todo_id", event.TodoID))
+	metrics.KafkaMessagesPublished.Add(float64(len(msgs)))
+	for _, event := range batch {
+		eventLogger(ctx, event).Info("Todo event published", zap.Int64("todo_id", event.TodoID))
+	}
 
 	return nil
 }
@@ -73,6 +157,11 @@ func (p *KafkaProducer) Close() error {
 	return p.writer.Close()
 }
 
+// Ping checks that at least one broker accepts connections
+func (p *KafkaProducer) Ping(ctx context.Context) error {
+	return pingBrokers(ctx, p.brokers)
+}
+
 func NewKafkaConsumer(cfg config.KafkaConfig) (*KafkaConsumer, error) {
 	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:  cfg.Brokers,
@@ -82,71 +171,219 @@ func NewKafkaConsumer(cfg config.KafkaConfig) (*KafkaConsumer, error) {
 		MaxBytes: 10e6, // 10MB
 	})
 
-	logger.Info("Kafka consumer initialized successfully")
+	var dlq messageWriter
+	if cfg.Consumer.DLQTopic != "" {
+		dlq = &kafka.Writer{
+			Addr:     kafka.TCP(cfg.Brokers...),
+			Topic:    cfg.Consumer.DLQTopic,
+			Balancer: &kafka.Hash{},
+		}
+	}
+
+	logger.Info("Kafka consumer initialized successfully",
+		zap.Int("max_retries", cfg.Consumer.MaxRetries),
+		zap.String("dlq_topic", cfg.Consumer.DLQTopic))
 	return &KafkaConsumer{
-		reader: reader,
-		topic:  cfg.Topic,
+		reader:  reader,
+		brokers: cfg.Brokers,
+		dlq:     dlq,
+		topic:   cfg.Topic,
+		cfg:     cfg.Consumer,
 	}, nil
 }
 
-func (c *KafkaConsumer) ConsumeMessages(ctx context.Context, handler func(TodoEvent) error) error {
+// ConsumeMessages reads events and passes them to the handler. A failed handler is retried
+// with exponential backoff; messages that cannot be decoded or still fail after all retries
+// are sent to the DLQ topic. The offset is committed only after the message is processed.
+func (c *KafkaConsumer) ConsumeMessages(ctx context.Context, handler EventHandler) error {
+	readAttempt := 0
 	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			m, err := c.reader.ReadMessage(ctx)
-			if err != nil {
-				logger.Error("Failed to read message", zap.Error(err))
-				continue
+		m, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
-
-			var event TodoEvent
-			if err := json.Unmarshal(m.Value, &event); err != nil {
-				logger.Error("Failed to unmarshal event", zap.Error(err))
-				continue
+			// Не крутим цикл вхолостую, пока брокер недоступен
+			delay := backoffDelay(c.cfg.InitialBackoff, c.cfg.MaxBackoff, readAttempt)
+			readAttempt++
+			logger.Error("Failed to read message", zap.Error(err), zap.Duration("retry_in", delay))
+			if err := sleepContext(ctx, delay); err != nil {
+				return err
 			}
+			continue
+		}
+		readAttempt = 0
 
-			if err := handler(event); err != nil {
-				logger.Error("Failed to handle event",
-					zap.Error(err),
-					zap.String("event_type", event.Type))
-				continue
+		if err := c.processMessage(ctx, m, handler); err != nil {
+			return err
+		}
+
+		if err := c.reader.CommitMessages(ctx, m); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
+			logger.Error("Failed to commit message",
+				zap.Error(err),
+				zap.Int("partition", m.Partition),
+				zap.Int64("offset", m.Offset))
+		}
+	}
+}
 
+// Subscribe implements Subscriber. The subscription is established once a broker is reachable.
+func (c *KafkaConsumer) Subscribe(ctx context.Context, handler EventHandler) error {
+	// Reader подключается лениво, поэтому доступность брокера проверяется заранее
+	for attempt := 0; ; attempt++ {
+		err := c.Ping(ctx)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		delay := backoffDelay(c.cfg.InitialBackoff, c.cfg.MaxBackoff, attempt)
+		logger.Error("Kafka brokers are unreachable", zap.Error(err), zap.Duration("retry_in", delay))
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+	if c.onSubscribed != nil {
+		c.onSubscribed()
+	}
+	return c.ConsumeMessages(ctx, handler)
+}
+
+// OnSubscribed implements SubscribeNotifier
+func (c *KafkaConsumer) OnSubscribed(fn func()) {
+	c.onSubscribed = fn
+}
+
+// processMessage handles a single message; it returns an error only when the context is cancelled
+func (c *KafkaConsumer) processMessage(ctx context.Context, m kafka.Message, handler EventHandler) error {
+	event, err := DecodeKafkaMessage(m)
+	if err != nil {
+		// Повторять бессмысленно: сообщение не станет корректным
+		logger.Error("Failed to unmarshal event", zap.Error(err), zap.Int64("offset", m.Offset))
+		metrics.KafkaPoisonMessagesTotal.WithLabelValues(dlqReasonDecode).Inc()
+		return c.sendToDLQ(ctx, m, dlqReasonDecode, err, 0)
+	}
+
+	// Обработчик получает request ID, traceparent и actor исходного HTTP-запроса
+	handlerCtx, span := startProcessSpan(ctx, event, DriverKafka, c.topic)
+	defer span.End()
+	log := eventLogger(handlerCtx, event)
+
+	attempts := 0
+	for {
+		err = handler(handlerCtx, event)
+		attempts++
+		if err == nil {
 			metrics.KafkaMessagesConsumed.Inc()
+			return nil
+		}
+
+		if attempts > c.cfg.MaxRetries {
+			break
+		}
+
+		delay := backoffDelay(c.cfg.InitialBackoff, c.cfg.MaxBackoff, attempts-1)
+		log.Warn("Failed to handle event, retrying",
+			zap.Error(err),
+			zap.Int("attempt", attempts),
+			zap.Duration("retry_in", delay))
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
 		}
 	}
+
+	log.Error("Failed to handle event, giving up",
+		zap.Error(err),
+		zap.Int("attempts", attempts))
+	tracing.RecordError(span, err)
+	metrics.KafkaPoisonMessagesTotal.WithLabelValues(dlqReasonHandler).Inc()
+	return c.sendToDLQ(ctx, m, dlqReasonHandler, err, attempts)
+}
+
+// Ping checks that at least one broker accepts connections
+func (c *KafkaConsumer) Ping(ctx context.Context) error {
+	return pingBrokers(ctx, c.brokers)
+}
+
+func pingBrokers(ctx context.Context, brokers []string) error {
+	err := errors.New("no kafka brokers configured")
+	for _, broker := range brokers {
+		var conn *kafka.Conn
+		conn, err = kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+	}
+	return err
 }
 
 func (c *KafkaConsumer) Close() error {
+	if c.dlq != nil {
+		if err := c.dlq.Close(); err != nil {
+			logger.Warn("Failed to close DLQ writer", zap.Error(err))
+		}
+	}
 	return c.reader.Close()
 }
 
 // Вспомогательные функции для создания событий
 func CreateTodoCreatedEvent(todo models.Todo) TodoEvent {
 	return TodoEvent{
-		Type:      "created",
+		ID:        uuid.New().String(),
+		Type:      TypeCreated,
 		TodoID:    todo.ID,
+		OwnerID:   todo.OwnerID,
 		Timestamp: time.Now(),
 		Payload:   todo,
 	}
 }
 
-func CreateTodoUpdatedEvent(todo models.Todo) TodoEvent {
+// CreateTodoUpdatedEvent builds an update event with the fields changed between before and after.
+// A change of the completed flag produces a completed or reopened event instead of updated.
+func CreateTodoUpdatedEvent(before, after models.Todo) TodoEvent {
+	eventType := TypeUpdated
+	if before.Completed != after.Completed {
+		if after.Completed {
+			eventType = TypeCompleted
+		} else {
+			eventType = TypeReopened
+		}
+	}
+
 	return TodoEvent{
-		Type:      "updated",
-		TodoID:    todo.ID,
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		TodoID:    after.ID,
+		OwnerID:   after.OwnerID,
 		Timestamp: time.Now(),
-		Payload:   todo,
+		Payload:   after,
+		Changes:   DiffTodos(before, after),
+	}
+}
+
+// DiffTodos returns the user-visible fields that differ between two todo versions
+func DiffTodos(before, after models.Todo) []FieldChange {
+	var changes []FieldChange
+	if before.Task != after.Task {
+		changes = append(changes, FieldChange{Field: "task", Old: before.Task, New: after.Task})
+	}
+	if before.Completed != after.Completed {
+		changes = append(changes, FieldChange{Field: "completed", Old: before.Completed, New: after.Completed})
 	}
+	return changes
 }
 
-func CreateTodoDeletedEvent(todoID int64) TodoEvent {
+func CreateTodoDeletedEvent(todoID int64, ownerID string) TodoEvent {
 	return TodoEvent{
-		Type:      "deleted",
+		ID:        uuid.New().String(),
+		Type:      TypeDeleted,
 		TodoID:    todoID,
+		OwnerID:   ownerID,
 		Timestamp: time.Now(),
-		Payload:   models.Todo{ID: todoID},
+		Payload:   models.Todo{ID: todoID, OwnerID: ownerID},
 	}
 }
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Заголовки CloudEvents Kafka protocol binding для binary content mode
+const (
+	headerContentType   = "content-type"
+	headerCESpecVersion = "ce_specversion"
+	headerCEID          = "ce_id"
+	headerCESource      = "ce_source"
+	headerCEType        = "ce_type"
+	headerCETime        = "ce_time"
+	headerCESubject     = "ce_subject"
+	headerCEDataSchema  = "ce_dataschema"
+	headerCEOwnerID     = "ce_ownerid"
+)
+
+// EncodeKafkaMessage serializes a CloudEvent in structured or binary content mode.
+// Request correlation attributes are also written as plain X-Request-ID, traceparent
+// and X-Actor headers in both modes, so consumers can read them without decoding the event.
+func EncodeKafkaMessage(ce CloudEvent, mode string) (kafka.Message, error) {
+	msg, err := encodeKafkaMessage(ce, mode)
+	if err != nil {
+		return msg, err
+	}
+	metadata := EventMetadata{RequestID: ce.RequestID, TraceParent: ce.TraceParent, Actor: ce.Actor}
+	msg.Headers = append(msg.Headers, metadata.kafkaHeaders()...)
+	return msg, nil
+}
+
+func encodeKafkaMessage(ce CloudEvent, mode string) (kafka.Message, error) {
+	switch mode {
+	case ContentModeStructured:
+		data, err := json.Marshal(ce)
+		if err != nil {
+			return kafka.Message{}, fmt.Errorf("failed to marshal cloud event: %w", err)
+		}
+		return kafka.Message{
+			Value:   data,
+			Headers: []kafka.Header{{Key: headerContentType, Value: []byte(ContentTypeCloudEventsJSON)}},
+		}, nil
+	case ContentModeBinary:
+		headers := []kafka.Header{
+			{Key: headerContentType, Value: []byte(ce.DataContentType)},
+			{Key: headerCESpecVersion, Value: []byte(ce.SpecVersion)},
+			{Key: headerCEID, Value: []byte(ce.ID)},
+			{Key: headerCESource, Value: []byte(ce.Source)},
+			{Key: headerCEType, Value: []byte(ce.Type)},
+			{Key: headerCETime, Value: []byte(ce.Time.Format(time.RFC3339Nano))},
+		}
+		if ce.Subject != "" {
+			headers = append(headers, kafka.Header{Key: headerCESubject, Value: []byte(ce.Subject)})
+		}
+		if ce.DataSchema != "" {
+			headers = append(headers, kafka.Header{Key: headerCEDataSchema, Value: []byte(ce.DataSchema)})
+		}
+		if ce.OwnerID != "" {
+			headers = append(headers, kafka.Header{Key: headerCEOwnerID, Value: []byte(ce.OwnerID)})
+		}
+		return kafka.Message{Value: ce.Data, Headers: headers}, nil
+	default:
+		return kafka.Message{}, fmt.Errorf("unsupported content mode %q", mode)
+	}
+}
+
+// DecodeKafkaMessage reads a todo event from a Kafka message in either content mode.
+// Messages without CloudEvents attributes are treated as the legacy TodoEvent JSON.
+func DecodeKafkaMessage(m kafka.Message) (TodoEvent, error) {
+	headers := make(map[string]string, len(m.Headers))
+	for _, h := range m.Headers {
+		headers[strings.ToLower(h.Key)] = string(h.Value)
+	}
+
+	event, err := decodeKafkaMessage(m, headers)
+	if err != nil {
+		return event, err
+	}
+	event.Metadata.mergeHeaders(func(name string) string { return headers[name] })
+	return event, nil
+}
+
+func decodeKafkaMessage(m kafka.Message, headers map[string]string) (TodoEvent, error) {
+	if specVersion, ok := headers[headerCESpecVersion]; ok {
+		ce := CloudEvent{
+			SpecVersion:     specVersion,
+			ID:              headers[headerCEID],
+			Source:          headers[headerCESource],
+			Type:            headers[headerCEType],
+			Subject:         headers[headerCESubject],
+			DataSchema:      headers[headerCEDataSchema],
+			DataContentType: headers[headerContentType],
+			Data:            m.Value,
+			OwnerID:         headers[headerCEOwnerID],
+		}
+		if t, ok := headers[headerCETime]; ok {
+			parsed, err := time.Parse(time.RFC3339Nano, t)
+			if err != nil {
+				return TodoEvent{}, fmt.Errorf("invalid ce_time header: %w", err)
+			}
+			ce.Time = parsed
+		}
+		return FromCloudEvent(ce)
+	}
+
+	if strings.HasPrefix(headers[headerContentType], "application/cloudevents+json") {
+		var ce CloudEvent
+		if err := json.Unmarshal(m.Value, &ce); err != nil {
+			return TodoEvent{}, fmt.Errorf("failed to unmarshal cloud event: %w", err)
+		}
+		return FromCloudEvent(ce)
+	}
+
+	var event TodoEvent
+	if err := json.Unmarshal(m.Value, &event); err != nil {
+		return TodoEvent{}, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+	return event, nil
+}
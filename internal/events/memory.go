@@ -0,0 +1,167 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/tracing"
+
+	"go.uber.org/zap"
+)
+
+// ErrBusClosed is returned when publishing to a closed in-memory bus
+var ErrBusClosed = errors.New("event bus is closed")
+
+// MemoryBus is a channel-based event bus for tests and single-node deployments.
+// Every subscriber gets its own buffered channel and receives all published events.
+type MemoryBus struct {
+	mu          sync.RWMutex
+	subscribers []*MemorySubscription
+	bufferSize  int
+	closed      bool
+	done        chan struct{}
+}
+
+// MemorySubscription is a subscriber registered on a MemoryBus. Events published after
+// registration are buffered until Run handles them, so nothing is lost while it starts.
+type MemorySubscription struct {
+	bus     *MemoryBus
+	events  chan TodoEvent
+	stopped chan struct{}
+}
+
+func NewMemoryBus(cfg config.MemoryBusConfig) *MemoryBus {
+	logger.Info("In-memory event bus initialized", zap.Int("buffer_size", cfg.BufferSize))
+	return &MemoryBus{bufferSize: cfg.BufferSize, done: make(chan struct{})}
+}
+
+// Publish delivers the event to every subscriber, blocking while a subscriber's buffer is full.
+// Subscribers that stop while it waits are skipped.
+func (b *MemoryBus) Publish(ctx context.Context, event TodoEvent) (err error) {
+	event, span := startPublishSpan(ctx, event, DriverMemory, DriverMemory)
+	defer func() { tracing.End(span, err) }()
+
+	// Отправка идёт без блокировки: ожидание медленного подписчика не должно мешать
+	// отписке и Close
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrBusClosed
+	}
+	subscribers := slices.Clone(b.subscribers)
+	b.mu.RUnlock()
+
+	for _, sub := range subscribers {
+		select {
+		case sub.events <- event:
+		case <-sub.stopped:
+		case <-b.done:
+			return ErrBusClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	metrics.EventsPublishedTotal.WithLabelValues(DriverMemory).Inc()
+	return nil
+}
+
+// Subscribe handles events until the context is cancelled or the bus is closed
+func (b *MemoryBus) Subscribe(ctx context.Context, handler EventHandler) error {
+	sub, err := b.Register()
+	if err != nil {
+		return err
+	}
+	return sub.Run(ctx, handler)
+}
+
+// Register adds a subscriber that receives every event published from now on.
+// Run must be called to handle them, usually in its own goroutine.
+func (b *MemoryBus) Register() (*MemorySubscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+	sub := &MemorySubscription{
+		bus:     b,
+		events:  make(chan TodoEvent, b.bufferSize),
+		stopped: make(chan struct{}),
+	}
+	b.subscribers = append(b.subscribers, sub)
+	return sub, nil
+}
+
+// Run handles events until the context is cancelled or the bus is closed; after Close it
+// handles the events already buffered. The subscription is removed when Run returns.
+func (s *MemorySubscription) Run(ctx context.Context, handler EventHandler) error {
+	defer s.bus.unsubscribe(s)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-s.events:
+			s.handle(ctx, event, handler)
+		case <-s.bus.done:
+			for {
+				select {
+				case event := <-s.events:
+					s.handle(ctx, event, handler)
+				default:
+					return nil
+				}
+			}
+		}
+	}
+}
+
+func (s *MemorySubscription) handle(ctx context.Context, event TodoEvent, handler EventHandler) {
+	handlerCtx, span := startProcessSpan(ctx, event, DriverMemory, DriverMemory)
+	err := handler(handlerCtx, event)
+	tracing.End(span, err)
+	if err != nil {
+		metrics.EventsConsumedTotal.WithLabelValues(DriverMemory, "error").Inc()
+		eventLogger(handlerCtx, event).Error("Failed to handle event", zap.Error(err))
+		return
+	}
+	metrics.EventsConsumedTotal.WithLabelValues(DriverMemory, "success").Inc()
+}
+
+func (b *MemoryBus) unsubscribe(sub *MemorySubscription) {
+	close(sub.stopped)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = slices.DeleteFunc(b.subscribers, func(s *MemorySubscription) bool { return s == sub })
+}
+
+// Ping fails after the bus is closed
+func (b *MemoryBus) Ping(ctx context.Context) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrBusClosed
+	}
+	return nil
+}
+
+// Close stops all subscribers after they drain their buffers
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	b.subscribers = nil
+	return nil
+}
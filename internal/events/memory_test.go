@@ -0,0 +1,91 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBus_FanOut(t *testing.T) {
+	bus := NewMemoryBus(config.MemoryBusConfig{BufferSize: 4})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 4)
+	for i := 0; i < 2; i++ {
+		go bus.Subscribe(ctx, func(ctx context.Context, event TodoEvent) error {
+			received <- event.ID
+			return nil
+		})
+	}
+
+	// Ждём регистрации обоих подписчиков
+	require.Eventually(t, func() bool {
+		bus.mu.RLock()
+		defer bus.mu.RUnlock()
+		return len(bus.subscribers) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, bus.Publish(ctx, TodoEvent{ID: "e1", Type: TypeCreated}))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case id := <-received:
+			assert.Equal(t, "e1", id)
+		case <-time.After(time.Second):
+			t.Fatal("event was not delivered to every subscriber")
+		}
+	}
+}
+
+func TestMemoryBus_Close(t *testing.T) {
+	bus := NewMemoryBus(config.MemoryBusConfig{BufferSize: 1})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.Subscribe(context.Background(), func(ctx context.Context, event TodoEvent) error { return nil })
+	}()
+	require.Eventually(t, func() bool {
+		bus.mu.RLock()
+		defer bus.mu.RUnlock()
+		return len(bus.subscribers) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, bus.Close())
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("subscriber did not stop after Close")
+	}
+
+	assert.ErrorIs(t, bus.Publish(context.Background(), TodoEvent{ID: "e2"}), ErrBusClosed)
+}
+
+func TestMemoryBus_StoppedSubscriberDoesNotBlock(t *testing.T) {
+	bus := NewMemoryBus(config.MemoryBusConfig{BufferSize: 1})
+	sub, err := bus.Register()
+	require.NoError(t, err)
+
+	// Подписчик остановлен при полном буфере: Publish ждёт, но не держит блокировку
+	require.NoError(t, bus.Publish(context.Background(), TodoEvent{ID: "e1"}))
+	published := make(chan error, 1)
+	go func() { published <- bus.Publish(context.Background(), TodoEvent{ID: "e2"}) }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sub.Run(ctx, func(ctx context.Context, event TodoEvent) error { return nil }), context.Canceled)
+
+	select {
+	case err := <-published:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a stopped subscriber")
+	}
+	require.NoError(t, bus.Close())
+}
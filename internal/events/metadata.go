@@ -0,0 +1,103 @@
+package events
+
+import (
+	"context"
+	"strings"
+
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/requestctx"
+	"todo_app_go/internal/tracing"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// EventMetadata correlates an event with the request that caused it
+type EventMetadata struct {
+	RequestID   string `json:"request_id,omitempty"`
+	TraceParent string `json:"traceparent,omitempty"`
+	Actor       string `json:"actor,omitempty"`
+}
+
+// MetadataFromContext collects the request ID, traceparent and actor stored in ctx.
+// The traceparent of the current span takes precedence over the one of the request;
+// the actor is the authenticated user, or the identity passed by a trusted gateway.
+func MetadataFromContext(ctx context.Context) EventMetadata {
+	traceParent := tracing.TraceParent(ctx)
+	if traceParent == "" {
+		traceParent = requestctx.TraceParent(ctx)
+	}
+	actor := requestctx.UserID(ctx)
+	if actor == "" {
+		actor = requestctx.Actor(ctx)
+	}
+	return EventMetadata{
+		RequestID:   requestctx.RequestID(ctx),
+		TraceParent: traceParent,
+		Actor:       actor,
+	}
+}
+
+// WithRequestMetadata attaches the correlation data from ctx to the event
+func WithRequestMetadata(ctx context.Context, event TodoEvent) TodoEvent {
+	event.Metadata = MetadataFromContext(ctx)
+	return event
+}
+
+// Context stores the metadata in ctx for event handlers
+func (m EventMetadata) Context(ctx context.Context) context.Context {
+	if m.RequestID != "" {
+		ctx = requestctx.WithRequestID(ctx, m.RequestID)
+	}
+	if m.TraceParent != "" {
+		ctx = requestctx.WithTraceParent(ctx, m.TraceParent)
+	}
+	if m.Actor != "" {
+		ctx = requestctx.WithActor(ctx, m.Actor)
+	}
+	return ctx
+}
+
+// eventLogger returns a logger annotated with the event, its originating request
+// and the consumer span stored in the handler context
+func eventLogger(ctx context.Context, event TodoEvent) *zap.Logger {
+	return logger.FromContext(event.Metadata.Context(ctx)).With(
+		zap.String("event_id", event.ID),
+		zap.String("event_type", event.Type))
+}
+
+// kafkaHeaders returns the metadata as plain message headers, independent of the content mode
+func (m EventMetadata) kafkaHeaders() []kafka.Header {
+	var headers []kafka.Header
+	for _, h := range m.headerPairs() {
+		headers = append(headers, kafka.Header{Key: h[0], Value: []byte(h[1])})
+	}
+	return headers
+}
+
+func (m EventMetadata) headerPairs() [][2]string {
+	var pairs [][2]string
+	if m.RequestID != "" {
+		pairs = append(pairs, [2]string{requestctx.HeaderRequestID, m.RequestID})
+	}
+	if m.TraceParent != "" {
+		pairs = append(pairs, [2]string{requestctx.HeaderTraceParent, m.TraceParent})
+	}
+	if m.Actor != "" {
+		pairs = append(pairs, [2]string{requestctx.HeaderActor, m.Actor})
+	}
+	return pairs
+}
+
+// mergeHeaders fills empty fields from message headers; lookup receives a lower-case header name
+func (m *EventMetadata) mergeHeaders(lookup func(name string) string) {
+	if m.RequestID == "" {
+		m.RequestID = lookup(strings.ToLower(requestctx.HeaderRequestID))
+	}
+	if m.TraceParent == "" {
+		m.TraceParent = lookup(strings.ToLower(requestctx.HeaderTraceParent))
+	}
+	if m.Actor == "" {
+		m.Actor = lookup(strings.ToLower(requestctx.HeaderActor))
+	}
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/requestctx"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func requestContext() context.Context {
+	ctx := requestctx.WithRequestID(context.Background(), "req-1")
+	ctx = requestctx.WithTraceParent(ctx, testTraceParent)
+	return requestctx.WithActor(ctx, "alice")
+}
+
+func TestKafkaMessage_MetadataHeaders(t *testing.T) {
+	event := WithRequestMetadata(requestContext(), CreateTodoCreatedEvent(models.Todo{ID: 1, Task: "Trace me"}))
+
+	for _, mode := range []string{ContentModeStructured, ContentModeBinary} {
+		t.Run(mode, func(t *testing.T) {
+			ce, err := ToCloudEvent(event, "/todo-app", SchemaVersion1)
+			assert.NoError(t, err)
+			msg, err := EncodeKafkaMessage(ce, mode)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "req-1", dlqHeader(msg, "X-Request-ID"))
+			assert.Equal(t, testTraceParent, dlqHeader(msg, "traceparent"))
+			assert.Equal(t, "alice", dlqHeader(msg, "X-Actor"))
+
+			decoded, err := DecodeKafkaMessage(msg)
+			assert.NoError(t, err)
+			assert.Equal(t, event.Metadata, decoded.Metadata)
+		})
+	}
+
+	// Legacy-сообщения без CloudEvents берут метаданные только из заголовков
+	legacy := kafka.Message{
+		Value:   []byte(`{"type":"created","todo_id":3,"payload":{"id":3}}`),
+		Headers: []kafka.Header{{Key: "x-request-id", Value: []byte("req-legacy")}},
+	}
+	decoded, err := DecodeKafkaMessage(legacy)
+	assert.NoError(t, err)
+	assert.Equal(t, "req-legacy", decoded.Metadata.RequestID)
+}
+
+func TestConsumeMessages_HandlerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	event := WithRequestMetadata(requestContext(), CreateTodoCreatedEvent(models.Todo{ID: 1}))
+	reader := &fakeReader{cancel: cancel, messages: []kafka.Message{encodedEvent(t, 1, event)}}
+	consumer := newTestConsumer(reader, &fakeWriter{})
+
+	var requestID, traceParent, actor string
+	err := consumer.ConsumeMessages(ctx, func(ctx context.Context, event TodoEvent) error {
+		requestID = requestctx.RequestID(ctx)
+		traceParent = requestctx.TraceParent(ctx)
+		actor = requestctx.Actor(ctx)
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.Equal(t, "req-1", requestID)
+	// Обработчик продолжает trace события
+	assert.True(t, strings.HasPrefix(traceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-"))
+	assert.Equal(t, "alice", actor)
+}
+
+func TestMetadataFromContext_Actor(t *testing.T) {
+	// Аутентифицированный пользователь важнее идентичности, переданной шлюзом
+	ctx := requestctx.WithUserID(requestContext(), "bob")
+	assert.Equal(t, "bob", MetadataFromContext(ctx).Actor)
+	assert.Equal(t, "alice", MetadataFromContext(requestContext()).Actor)
+	assert.Empty(t, MetadataFromContext(context.Background()).Actor)
+}
@@ -0,0 +1,301 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/tracing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// NATSPublisher publishes CloudEvents in structured mode to a JetStream stream
+type NATSPublisher struct {
+	conn          *nats.Conn
+	js            jetstream.JetStream
+	subject       string
+	source        string
+	schemaVersion int
+}
+
+// NATSSubscriber consumes events through a durable JetStream consumer
+type NATSSubscriber struct {
+	conn         *nats.Conn
+	js           msgPublisher
+	consumer     jetstream.Consumer
+	cfg          config.NATSConfig
+	onSubscribed func()
+}
+
+// msgPublisher is the part of JetStream the subscriber needs to write to the DLQ
+type msgPublisher interface {
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// connectJetStream connects to NATS and makes sure the stream for todo events exists
+func connectJetStream(cfg config.NATSConfig) (*nats.Conn, jetstream.JetStream, error) {
+	conn, err := nats.Connect(cfg.URL, nats.Name("todo-app"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	subjects := []string{cfg.Subject + ".>"}
+	if cfg.DLQSubject != "" {
+		subjects = append(subjects, cfg.DLQSubject)
+	}
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.Stream,
+		Subjects: subjects,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to create JetStream stream: %w", err)
+	}
+
+	return conn, js, nil
+}
+
+func NewNATSPublisher(cfg config.EventsConfig) (*NATSPublisher, error) {
+	if err := validateSchemaVersion(cfg.SchemaVersion); err != nil {
+		return nil, err
+	}
+
+	conn, js, err := connectJetStream(cfg.NATS)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("NATS publisher initialized successfully", zap.String("stream", cfg.NATS.Stream))
+	return &NATSPublisher{
+		conn:          conn,
+		js:            js,
+		subject:       cfg.NATS.Subject,
+		source:        cfg.Source,
+		schemaVersion: cfg.SchemaVersion,
+	}, nil
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, event TodoEvent) (err error) {
+	event, span := startPublishSpan(ctx, event, DriverNATS, p.subject)
+	defer func() { tracing.End(span, err) }()
+
+	ce, err := ToCloudEvent(event, p.source, p.schemaVersion)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(ce)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cloud event: %w", err)
+	}
+
+	msg := nats.NewMsg(fmt.Sprintf("%s.%s", p.subject, cloudEventKind(event.Type, p.schemaVersion)))
+	msg.Data = data
+	msg.Header.Set("Content-Type", ContentTypeCloudEventsJSON)
+	for _, h := range event.Metadata.headerPairs() {
+		msg.Header.Set(h[0], h[1])
+	}
+
+	// Msg-Id включает дедупликацию повторных публикаций на стороне JetStream
+	if _, err := p.js.PublishMsg(ctx, msg, jetstream.WithMsgID(ce.ID)); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	metrics.EventsPublishedTotal.WithLabelValues(DriverNATS).Inc()
+	eventLogger(ctx, event).Info("Todo event published", zap.Int64("todo_id", event.TodoID))
+	return nil
+}
+
+// Ping reports whether the NATS connection is established
+func (p *NATSPublisher) Ping(ctx context.Context) error {
+	return pingNATS(p.conn)
+}
+
+func (p *NATSPublisher) Close() error {
+	p.conn.Close()
+	return nil
+}
+
+func NewNATSSubscriber(cfg config.NATSConfig) (*NATSSubscriber, error) {
+	conn, js, err := connectJetStream(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	consumer, err := js.CreateOrUpdateConsumer(ctx, cfg.Stream, jetstream.ConsumerConfig{
+		Durable:       cfg.Durable,
+		FilterSubject: cfg.Subject + ".>",
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       cfg.AckWait,
+		// Лимит max_deliver соблюдает handleMessage: сервер, исчерпав MaxDeliver, перестал бы
+		// доставлять сообщение, и оно потерялось бы, если запись в DLQ не удалась
+		MaxDeliver: -1,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream consumer: %w", err)
+	}
+
+	logger.Info("NATS subscriber initialized successfully",
+		zap.String("stream", cfg.Stream),
+		zap.String("durable", cfg.Durable))
+	return &NATSSubscriber{
+		conn:     conn,
+		js:       js,
+		consumer: consumer,
+		cfg:      cfg,
+	}, nil
+}
+
+// Subscribe acknowledges a message only after the handler succeeds. Failed messages are
+// redelivered with backoff; after max_deliver attempts they go to the DLQ subject.
+func (s *NATSSubscriber) Subscribe(ctx context.Context, handler EventHandler) error {
+	consumeCtx, err := s.consumer.Consume(func(msg jetstream.Msg) {
+		s.handleMessage(ctx, msg, handler)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start consuming: %w", err)
+	}
+	defer consumeCtx.Stop()
+	if s.onSubscribed != nil {
+		s.onSubscribed()
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// OnSubscribed implements SubscribeNotifier
+func (s *NATSSubscriber) OnSubscribed(fn func()) {
+	s.onSubscribed = fn
+}
+
+func (s *NATSSubscriber) handleMessage(ctx context.Context, msg jetstream.Msg, handler EventHandler) {
+	var ce CloudEvent
+	err := json.Unmarshal(msg.Data(), &ce)
+	var event TodoEvent
+	if err == nil {
+		event, err = FromCloudEvent(ce)
+	}
+	if err == nil {
+		event.Metadata.mergeHeaders(func(name string) string { return msg.Headers().Get(name) })
+	}
+	if err != nil {
+		// Повторять бессмысленно: сообщение не станет корректным
+		logger.Error("Failed to unmarshal event", zap.Error(err), zap.String("subject", msg.Subject()))
+		metrics.EventsConsumedTotal.WithLabelValues(DriverNATS, "poison").Inc()
+		s.deadLetter(ctx, msg, dlqReasonDecode, err)
+		return
+	}
+
+	handlerCtx, span := startProcessSpan(ctx, event, DriverNATS, s.cfg.Subject)
+	defer span.End()
+	log := eventLogger(handlerCtx, event)
+	if err := handler(handlerCtx, event); err != nil {
+		tracing.RecordError(span, err)
+		delivered := numDelivered(msg)
+		if s.cfg.MaxDeliver > 0 && int(delivered) >= s.cfg.MaxDeliver {
+			log.Error("Failed to handle event, giving up",
+				zap.Error(err),
+				zap.Uint64("attempts", delivered))
+			metrics.EventsConsumedTotal.WithLabelValues(DriverNATS, "poison").Inc()
+			s.deadLetter(ctx, msg, dlqReasonHandler, err)
+			return
+		}
+
+		delay := backoffDelay(time.Second, s.cfg.AckWait, int(delivered)-1)
+		log.Warn("Failed to handle event, retrying",
+			zap.Error(err),
+			zap.Uint64("attempt", delivered),
+			zap.Duration("retry_in", delay))
+		metrics.EventsConsumedTotal.WithLabelValues(DriverNATS, "error").Inc()
+		if err := msg.NakWithDelay(delay); err != nil {
+			log.Warn("Failed to nak message", zap.Error(err))
+		}
+		return
+	}
+
+	if err := msg.Ack(); err != nil {
+		log.Warn("Failed to ack message", zap.Error(err))
+		return
+	}
+	metrics.EventsConsumedTotal.WithLabelValues(DriverNATS, "success").Inc()
+}
+
+// deadLetter copies the message to the DLQ subject with error headers and terminates redelivery.
+// If the DLQ write fails the message is redelivered after a delay: the consumer has no server-side
+// delivery limit, so the next delivery tries the DLQ again instead of dropping the event.
+func (s *NATSSubscriber) deadLetter(ctx context.Context, msg jetstream.Msg, reason string, cause error) {
+	if s.cfg.DLQSubject != "" {
+		dlq := nats.NewMsg(s.cfg.DLQSubject)
+		dlq.Data = msg.Data()
+		for key, values := range msg.Headers() {
+			for _, v := range values {
+				dlq.Header.Add(key, v)
+			}
+		}
+		dlq.Header.Set(HeaderDLQReason, reason)
+		dlq.Header.Set(HeaderDLQError, cause.Error())
+		dlq.Header.Set(HeaderDLQOriginalTopic, msg.Subject())
+		dlq.Header.Set(HeaderDLQFailedAt, time.Now().UTC().Format(time.RFC3339))
+
+		if _, err := s.js.PublishMsg(ctx, dlq); err != nil {
+			delay := backoffDelay(time.Second, s.cfg.AckWait, int(numDelivered(msg))-1)
+			logger.Error("Failed to write message to DLQ, redelivering",
+				zap.Error(err),
+				zap.Duration("retry_in", delay))
+			if err := msg.NakWithDelay(delay); err != nil {
+				logger.Warn("Failed to nak message", zap.Error(err))
+			}
+			return
+		}
+	}
+
+	if err := msg.Term(); err != nil {
+		logger.Warn("Failed to terminate message", zap.Error(err))
+	}
+}
+
+// numDelivered returns how many times the message has been delivered, counting this delivery
+func numDelivered(msg jetstream.Msg) uint64 {
+	if md, err := msg.Metadata(); err == nil {
+		return md.NumDelivered
+	}
+	return 1
+}
+
+// Ping reports whether the NATS connection is established
+func (s *NATSSubscriber) Ping(ctx context.Context) error {
+	return pingNATS(s.conn)
+}
+
+func pingNATS(conn *nats.Conn) error {
+	if !conn.IsConnected() {
+		return fmt.Errorf("nats connection is %s", conn.Status())
+	}
+	return nil
+}
+
+func (s *NATSSubscriber) Close() error {
+	s.conn.Close()
+	return nil
+}
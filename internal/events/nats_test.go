@@ -0,0 +1,95 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/models"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJSMsg records how the subscriber settled the message
+type fakeJSMsg struct {
+	jetstream.Msg
+	data       []byte
+	delivered  uint64
+	nakDelay   time.Duration
+	naked      bool
+	terminated bool
+}
+
+func (m *fakeJSMsg) Data() []byte         { return m.data }
+func (m *fakeJSMsg) Headers() nats.Header { return nats.Header{} }
+func (m *fakeJSMsg) Subject() string      { return "todos.todo.created" }
+func (m *fakeJSMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{NumDelivered: m.delivered}, nil
+}
+func (m *fakeJSMsg) NakWithDelay(delay time.Duration) error {
+	m.naked, m.nakDelay = true, delay
+	return nil
+}
+func (m *fakeJSMsg) Term() error {
+	m.terminated = true
+	return nil
+}
+
+type fakeMsgPublisher struct {
+	err       error
+	published []*nats.Msg
+}
+
+func (p *fakeMsgPublisher) PublishMsg(_ context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.published = append(p.published, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+func TestNATSSubscriber_DeadLetter(t *testing.T) {
+	cfg := config.NATSConfig{Subject: "todos", DLQSubject: "todos-dlq", MaxDeliver: 3, AckWait: 30 * time.Second}
+	failing := func(context.Context, TodoEvent) error { return errors.New("projection down") }
+
+	// Последняя попытка: сообщение уходит в DLQ и больше не доставляется
+	publisher := &fakeMsgPublisher{}
+	s := &NATSSubscriber{js: publisher, cfg: cfg}
+	msg := &fakeJSMsg{data: validEventData(t), delivered: 3}
+	s.handleMessage(context.Background(), msg, failing)
+	require.Len(t, publisher.published, 1)
+	assert.Equal(t, "todos-dlq", publisher.published[0].Subject)
+	assert.Equal(t, dlqReasonHandler, publisher.published[0].Header.Get(HeaderDLQReason))
+	assert.True(t, msg.terminated)
+	assert.False(t, msg.naked)
+
+	// DLQ недоступна: сообщение не теряется, а доставляется снова с задержкой
+	publisher = &fakeMsgPublisher{err: errors.New("nats: timeout")}
+	s = &NATSSubscriber{js: publisher, cfg: cfg}
+	msg = &fakeJSMsg{data: validEventData(t), delivered: 5}
+	s.handleMessage(context.Background(), msg, failing)
+	assert.False(t, msg.terminated)
+	assert.True(t, msg.naked)
+	assert.Equal(t, 16*time.Second, msg.nakDelay)
+
+	// Некорректное сообщение тоже не теряется при недоступной DLQ
+	msg = &fakeJSMsg{data: []byte("not json"), delivered: 1}
+	s.handleMessage(context.Background(), msg, failing)
+	assert.False(t, msg.terminated)
+	assert.True(t, msg.naked)
+}
+
+func validEventData(t *testing.T) []byte {
+	t.Helper()
+	ce, err := ToCloudEvent(CreateTodoCreatedEvent(models.Todo{ID: 1, Task: "Buy milk"}), "/todo-app", SchemaVersion1)
+	require.NoError(t, err)
+	data, err := json.Marshal(ce)
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// OutboxRelay publishes events stored in the outbox and marks them as sent
+type OutboxRelay struct {
+	store     models.OutboxStore
+	publisher Publisher
+	cfg       config.OutboxConfig
+	now       func() time.Time
+}
+
+// NewOutboxMessage serializes an event for storage in the outbox
+func NewOutboxMessage(event TodoEvent) (*models.OutboxMessage, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return &models.OutboxMessage{
+		AggregateID: event.TodoID,
+		EventType:   event.Type,
+		Payload:     data,
+	}, nil
+}
+
+func NewOutboxRelay(store models.OutboxStore, publisher Publisher, cfg config.OutboxConfig) *OutboxRelay {
+	return &OutboxRelay{
+		store:     store,
+		publisher: publisher,
+		cfg:       cfg,
+		now:       time.Now,
+	}
+}
+
+// Run polls the outbox until the context is cancelled
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	logger.Info("Outbox relay started", zap.Duration("poll_interval", r.cfg.PollInterval))
+
+	for {
+		if err := r.ProcessBatch(ctx); err != nil {
+			logger.Error("Failed to process outbox batch", zap.Error(err))
+		}
+		if err := r.cleanup(); err != nil {
+			logger.Warn("Failed to clean up outbox", zap.Error(err))
+		}
+		r.updateMetrics()
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Outbox relay stopped")
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch publishes one batch of pending messages
+func (r *OutboxRelay) ProcessBatch(ctx context.Context) error {
+	messages, err := r.store.FetchPending(r.cfg.BatchSize, r.now())
+	if err != nil {
+		return fmt.Errorf("failed to fetch outbox messages: %w", err)
+	}
+
+	// Если событие todo не отправилось, более поздние события того же todo
+	// откладываем, чтобы не нарушить порядок; в следующих пакетах их не вернёт FetchPending
+	blocked := make(map[int64]bool)
+
+	for _, msg := range messages {
+		if blocked[msg.AggregateID] {
+			continue
+		}
+
+		var event TodoEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			// Повторять бессмысленно: payload не станет корректным
+			metrics.OutboxMessagesRelayed.WithLabelValues("parked").Inc()
+			logger.Error("Parking undecodable outbox message",
+				zap.Int64("outbox_id", msg.ID),
+				zap.Int64("todo_id", msg.AggregateID),
+				zap.Error(err))
+			if err := r.store.MarkParked(msg.ID, err.Error(), r.now()); err != nil {
+				return fmt.Errorf("failed to park outbox message %d: %w", msg.ID, err)
+			}
+			continue
+		}
+
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			blocked[msg.AggregateID] = true
+			metrics.OutboxMessagesRelayed.WithLabelValues("error").Inc()
+
+			next := r.now().Add(r.backoff(msg.Attempts))
+			logger.Warn("Failed to relay outbox message",
+				zap.Int64("outbox_id", msg.ID),
+				zap.Int("attempts", msg.Attempts+1),
+				zap.Time("next_attempt_at", next),
+				zap.Error(err))
+
+			if err := r.store.MarkFailed(msg.ID, err.Error(), next); err != nil {
+				return fmt.Errorf("failed to mark outbox message %d as failed: %w", msg.ID, err)
+			}
+			continue
+		}
+
+		if err := r.store.MarkSent(msg.ID, r.now()); err != nil {
+			return fmt.Errorf("failed to mark outbox message %d as sent: %w", msg.ID, err)
+		}
+		metrics.OutboxMessagesRelayed.WithLabelValues("success").Inc()
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the next attempt, doubling with each failure
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	return backoffDelay(r.cfg.InitialBackoff, r.cfg.MaxBackoff, attempts)
+}
+
+func (r *OutboxRelay) cleanup() error {
+	if r.cfg.Retention <= 0 {
+		return nil
+	}
+	_, err := r.store.DeleteSentBefore(r.now().Add(-r.cfg.Retention))
+	return err
+}
+
+func (r *OutboxRelay) updateMetrics() {
+	stats, err := r.store.Stats()
+	if err != nil {
+		logger.Warn("Failed to read outbox stats", zap.Error(err))
+		return
+	}
+
+	metrics.OutboxBacklog.Set(float64(stats.Pending))
+	metrics.OutboxParked.Set(float64(stats.Parked))
+	if stats.OldestCreatedAt != nil {
+		metrics.OutboxLagSeconds.Set(r.now().Sub(*stats.OldestCreatedAt).Seconds())
+	} else {
+		metrics.OutboxLagSeconds.Set(0)
+	}
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	_ = logger.Init(config.LogConfig{Level: "debug", Format: "console"})
+}
+
+type fakeOutboxStore struct {
+	pending []models.OutboxMessage
+	sent    []int64
+	failed  map[int64]time.Time
+	parked  []int64
+}
+
+func (s *fakeOutboxStore) FetchPending(limit int, now time.Time) ([]models.OutboxMessage, error) {
+	return s.pending, nil
+}
+func (s *fakeOutboxStore) MarkSent(id int64, sentAt time.Time) error {
+	s.sent = append(s.sent, id)
+	return nil
+}
+func (s *fakeOutboxStore) MarkFailed(id int64, errMsg string, nextAttemptAt time.Time) error {
+	s.failed[id] = nextAttemptAt
+	return nil
+}
+func (s *fakeOutboxStore) MarkParked(id int64, errMsg string, parkedAt time.Time) error {
+	s.parked = append(s.parked, id)
+	return nil
+}
+func (s *fakeOutboxStore) DeleteSentBefore(before time.Time) (int64, error) { return 0, nil }
+func (s *fakeOutboxStore) Stats() (models.OutboxStats, error)               { return models.OutboxStats{}, nil }
+
+func (p *fakePublisher) Close() error { return nil }
+
+type fakePublisher struct {
+	failFor   map[int64]bool
+	published []TodoEvent
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, event TodoEvent) error {
+	if p.failFor[event.TodoID] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+func outboxMessage(t *testing.T, id int64, event TodoEvent) models.OutboxMessage {
+	msg, err := NewOutboxMessage(event)
+	assert.NoError(t, err)
+	msg.ID = id
+	return *msg
+}
+
+func TestOutboxRelay_ProcessBatch(t *testing.T) {
+	store := &fakeOutboxStore{failed: map[int64]time.Time{}}
+	store.pending = []models.OutboxMessage{
+		outboxMessage(t, 1, CreateTodoCreatedEvent(models.Todo{ID: 10, Task: "ok"})),
+		outboxMessage(t, 2, CreateTodoCreatedEvent(models.Todo{ID: 20, Task: "broken"})),
+		outboxMessage(t, 3, CreateTodoUpdatedEvent(models.Todo{ID: 20}, models.Todo{ID: 20, Task: "broken"})),
+		outboxMessage(t, 4, CreateTodoDeletedEvent(10, "")),
+		{ID: 5, AggregateID: 30, EventType: TypeCreated, Payload: []byte("not json")},
+	}
+	publisher := &fakePublisher{failFor: map[int64]bool{20: true}}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	relay := NewOutboxRelay(store, publisher, config.OutboxConfig{
+		BatchSize:      10,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+	})
+	relay.now = func() time.Time { return now }
+
+	assert.NoError(t, relay.ProcessBatch(context.Background()))
+
+	assert.Equal(t, []int64{1, 4}, store.sent)
+	// Второе событие todo 20 не отправляется, пока не уйдёт первое
+	assert.Len(t, store.failed, 1)
+	assert.Equal(t, now.Add(time.Second), store.failed[2])
+	assert.Len(t, publisher.published, 2)
+	// Повреждённый payload не повторяется
+	assert.Equal(t, []int64{5}, store.parked)
+}
+
+func TestOutboxRelay_KeepsOrderAcrossPolls(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	require.NoError(t, database.EnsureOutboxTable(db))
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	insert := func(event TodoEvent) {
+		msg, err := NewOutboxMessage(event)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO outbox (aggregate_id, event_type, payload, created_at, next_attempt_at) VALUES (?, ?, ?, ?, ?)`,
+			msg.AggregateID, msg.EventType, msg.Payload, now, now)
+		require.NoError(t, err)
+	}
+	insert(CreateTodoCreatedEvent(models.Todo{ID: 20, Task: "First"}))
+	insert(CreateTodoUpdatedEvent(models.Todo{ID: 20, Task: "First"}, models.Todo{ID: 20, Task: "Second"}))
+
+	publisher := &fakePublisher{failFor: map[int64]bool{20: true}}
+	relay := NewOutboxRelay(models.NewSQLiteOutboxStore(db), publisher, config.OutboxConfig{
+		BatchSize:      10,
+		InitialBackoff: time.Minute,
+		MaxBackoff:     time.Hour,
+	})
+	relay.now = func() time.Time { return now }
+	require.NoError(t, relay.ProcessBatch(context.Background()))
+
+	// Брокер снова доступен, но первое событие ждёт повтора: второе не обгоняет его
+	publisher.failFor = nil
+	now = now.Add(time.Second)
+	require.NoError(t, relay.ProcessBatch(context.Background()))
+	assert.Empty(t, publisher.published)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, relay.ProcessBatch(context.Background()))
+	require.Len(t, publisher.published, 2)
+	assert.Equal(t, TypeCreated, publisher.published[0].Type)
+	assert.Equal(t, TypeUpdated, publisher.published[1].Type)
+}
+
+func TestOutboxRelay_Backoff(t *testing.T) {
+	relay := NewOutboxRelay(nil, nil, config.OutboxConfig{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+	})
+
+	assert.Equal(t, time.Second, relay.backoff(0))
+	assert.Equal(t, 2*time.Second, relay.backoff(1))
+	assert.Equal(t, 8*time.Second, relay.backoff(3))
+	assert.Equal(t, 10*time.Second, relay.backoff(10))
+}
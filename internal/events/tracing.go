@@ -0,0 +1,55 @@
+package events
+
+import (
+	"context"
+
+	"todo_app_go/internal/requestctx"
+	"todo_app_go/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startPublishSpan starts a producer span for the event. The span continues the
+// trace of the caller, or the trace stored in the event when it is published
+// later by the outbox relay or the async publisher. The returned event carries
+// the producer span as its traceparent, so consumer spans become its children.
+func startPublishSpan(ctx context.Context, event TodoEvent, system, destination string) (TodoEvent, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = tracing.ContextWithTraceParent(ctx, event.Metadata.TraceParent)
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "publish "+destination,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(messagingAttributes(event, system, destination, "publish")...))
+
+	if traceParent := tracing.TraceParent(ctx); traceParent != "" {
+		event.Metadata.TraceParent = traceParent
+	}
+	return event, span
+}
+
+// startProcessSpan starts a consumer span for the event and returns the handler context
+// with the event metadata; the traceparent in it points at the consumer span
+func startProcessSpan(ctx context.Context, event TodoEvent, system, destination string) (context.Context, trace.Span) {
+	ctx = tracing.ContextWithTraceParent(ctx, event.Metadata.TraceParent)
+	ctx, span := tracing.Tracer().Start(ctx, "process "+destination,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(messagingAttributes(event, system, destination, "process")...))
+
+	ctx = event.Metadata.Context(ctx)
+	if traceParent := tracing.TraceParent(ctx); traceParent != "" {
+		ctx = requestctx.WithTraceParent(ctx, traceParent)
+	}
+	return ctx, span
+}
+
+func messagingAttributes(event TodoEvent, system, destination, operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("messaging.system", system),
+		attribute.String("messaging.destination.name", destination),
+		attribute.String("messaging.operation.type", operation),
+		attribute.String("messaging.message.id", event.ID),
+		attribute.String("event.type", event.Type),
+		attribute.Int64("todo.id", event.TodoID),
+	}
+}
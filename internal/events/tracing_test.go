@@ -0,0 +1,79 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/requestctx"
+	"todo_app_go/internal/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// lockedBuffer collects spans exported from subscriber goroutines
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestMemoryBus_TracePropagation(t *testing.T) {
+	var spans lockedBuffer
+	provider := tracing.NewTestProvider(&spans)
+	defer provider.Shutdown(context.Background())
+
+	bus := NewMemoryBus(config.MemoryBusConfig{BufferSize: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type received struct {
+		traceParent string
+		spanCtx     trace.SpanContext
+	}
+	got := make(chan received, 1)
+	go bus.Subscribe(ctx, func(ctx context.Context, event TodoEvent) error {
+		got <- received{requestctx.TraceParent(ctx), trace.SpanContextFromContext(ctx)}
+		return nil
+	})
+	require.Eventually(t, func() bool {
+		bus.mu.RLock()
+		defer bus.mu.RUnlock()
+		return len(bus.subscribers) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Событие опубликовано позже, вне запроса (как из outbox relay): trace берётся из метаданных
+	event := WithRequestMetadata(requestContext(), CreateTodoCreatedEvent(models.Todo{ID: 1}))
+	require.NoError(t, bus.Publish(context.Background(), event))
+
+	select {
+	case r := <-got:
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", r.spanCtx.TraceID().String())
+		assert.True(t, strings.HasPrefix(r.traceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+r.spanCtx.SpanID().String()))
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered")
+	}
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(spans.String(), `"Name":"process memory"`)
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, spans.String(), `"Name":"publish memory"`)
+}
@@ -0,0 +1,109 @@
+package eventsourcing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"todo_app_go/internal/events"
+	"todo_app_go/internal/models"
+)
+
+// Apply returns the todo state after the event. Every event except deleted carries
+// the full todo state in its payload, so applying it replaces the previous state.
+func Apply(todo *models.Todo, event events.TodoEvent) *models.Todo {
+	switch event.Type {
+	case events.TypeDeleted:
+		return nil
+	case events.TypeCreated, events.TypeUpdated, events.TypeCompleted, events.TypeReopened:
+		next := event.Payload
+		return &next
+	default:
+		// Неизвестные типы не меняют состояние
+		return todo
+	}
+}
+
+// applyToProjection updates the todos table with the event
+func applyToProjection(tx *sql.Tx, event events.TodoEvent) error {
+	todo := Apply(nil, event)
+	if todo == nil {
+		if event.Type != events.TypeDeleted {
+			return nil
+		}
+		_, err := tx.Exec("DELETE FROM todos WHERE id = ?", event.TodoID)
+		return err
+	}
+
+	// Владелец задаётся при создании и не меняется
+	_, err := tx.Exec(`INSERT INTO todos (id, owner_id, task, completed, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET task = excluded.task, completed = excluded.completed, updated_at = excluded.updated_at`,
+		event.TodoID, todo.OwnerID, todo.Task, todo.Completed, todo.CreatedAt, todo.UpdatedAt)
+	return err
+}
+
+// RebuildResult describes a finished projection rebuild
+type RebuildResult struct {
+	Events int
+	Todos  int
+}
+
+// Rebuild recreates the todos table by replaying the whole event log in one transaction
+func Rebuild(ctx context.Context, db *sql.DB) (RebuildResult, error) {
+	var result RebuildResult
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM todos"); err != nil {
+		return result, err
+	}
+
+	log, err := loadEvents(ctx, tx)
+	if err != nil {
+		return result, err
+	}
+
+	for _, event := range log {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if err := applyToProjection(tx, event); err != nil {
+			return result, fmt.Errorf("failed to apply event %s: %w", event.ID, err)
+		}
+		result.Events++
+	}
+
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM todos").Scan(&result.Todos); err != nil {
+		return result, err
+	}
+
+	return result, tx.Commit()
+}
+
+// loadEvents reads the whole event log in append order
+func loadEvents(ctx context.Context, tx *sql.Tx) ([]events.TodoEvent, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT payload FROM events ORDER BY seq")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var log []events.TodoEvent
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, err
+		}
+		var event events.TodoEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+		}
+		log = append(log, event)
+	}
+	return log, rows.Err()
+}
@@ -0,0 +1,375 @@
+package eventsourcing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"todo_app_go/internal/events"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/tracing"
+
+	"go.uber.org/zap"
+)
+
+// Repository makes the append-only events table the source of truth for todos.
+// Every change is stored as a TodoEvent and applied to the todos projection in the
+// same transaction, so reads keep using the todos table.
+type Repository struct {
+	*models.SQLiteTodoRepository
+	db *sql.DB
+	// outbox — дублировать события в outbox для публикации relay
+	outbox bool
+}
+
+// NewRepository creates an event-sourced repository. With outbox enabled every stored
+// event is also written to the outbox table for events.OutboxRelay.
+func NewRepository(db *sql.DB, outbox bool) *Repository {
+	return &Repository{
+		SQLiteTodoRepository: models.NewSQLiteTodoRepository(db),
+		db:                   db,
+		outbox:               outbox,
+	}
+}
+
+// Create adds a new todo by appending a created event
+func (r *Repository) Create(ctx context.Context, ownerID, task string) (*models.Todo, error) {
+	return r.CreateWithEvent(ctx, ownerID, task, nil)
+}
+
+// Update changes a todo by appending an updated, completed or reopened event
+func (r *Repository) Update(ctx context.Context, ownerID string, id int64, req models.TodoUpdateRequest) (*models.Todo, error) {
+	return r.UpdateWithEvent(ctx, ownerID, id, req, nil)
+}
+
+// UpdateStatus updates the completion status of a todo
+func (r *Repository) UpdateStatus(ctx context.Context, ownerID string, id int64, completed bool) error {
+	_, err := r.UpdateWithEvent(ctx, ownerID, id, models.TodoUpdateRequest{Completed: &completed}, nil)
+	return err
+}
+
+// Delete removes a todo by appending a deleted event
+func (r *Repository) Delete(ctx context.Context, ownerID string, id int64) error {
+	return r.DeleteWithEvent(ctx, ownerID, id, nil)
+}
+
+// CreateWithEvent appends a created event. The event is taken from the outbox message
+// built by the service, so the stored and published events share the same ID.
+func (r *Repository) CreateWithEvent(ctx context.Context, ownerID, task string, eventFn models.OutboxEventFunc) (_ *models.Todo, err error) {
+	ctx, span := models.StartDBSpan(ctx, "INSERT", "events")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	todo := &models.Todo{
+		OwnerID:   ownerID,
+		Task:      task,
+		Completed: false,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// ID выдаёт AUTOINCREMENT таблицы todos: он общий с CRUD-режимом, не переиспользуется
+	// и резервируется внутри транзакции, поэтому конкурентные создания не получат одинаковый ID.
+	// Событие затем применяется к уже вставленной строке.
+	result, err := tx.ExecContext(ctx, "INSERT INTO todos (owner_id, task, completed, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		todo.OwnerID, todo.Task, todo.Completed, todo.CreatedAt, todo.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if todo.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+
+	event, err := r.buildEvent(eventFn, todo, func() events.TodoEvent {
+		return events.CreateTodoCreatedEvent(*todo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.store(tx, event); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+// UpdateWithEvent appends an update event built from the state before and after the change
+func (r *Repository) UpdateWithEvent(ctx context.Context, ownerID string, id int64, req models.TodoUpdateRequest, eventFn models.OutboxUpdateEventFunc) (_ *models.Todo, err error) {
+	ctx, span := models.StartDBSpan(ctx, "INSERT", "events")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := getTodo(tx, ownerID, id)
+	if err != nil || before == nil {
+		return nil, err
+	}
+
+	todo := *before
+	if req.Task != nil {
+		todo.Task = *req.Task
+	}
+	if req.Completed != nil {
+		todo.Completed = *req.Completed
+	}
+	todo.UpdatedAt = time.Now()
+
+	var event events.TodoEvent
+	if eventFn != nil {
+		msg, err := eventFn(before, &todo)
+		if err != nil {
+			return nil, err
+		}
+		event, err = decodeEvent(msg)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		event = events.CreateTodoUpdatedEvent(*before, todo)
+	}
+	if err := r.store(tx, event); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// DeleteWithEvent appends a deleted event. Deleting a missing todo or a todo
+// of another owner is a no-op.
+func (r *Repository) DeleteWithEvent(ctx context.Context, ownerID string, id int64, eventFn models.OutboxEventFunc) (err error) {
+	ctx, span := models.StartDBSpan(ctx, "INSERT", "events")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	todo, err := getTodo(tx, ownerID, id)
+	if err != nil || todo == nil {
+		return err
+	}
+
+	event, err := r.buildEvent(eventFn, todo, func() events.TodoEvent {
+		return events.CreateTodoDeletedEvent(id, ownerID)
+	})
+	if err != nil {
+		return err
+	}
+	if err := r.store(tx, event); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetByIDAsOf replays the todo's events up to asOf. It returns nil if the todo
+// did not exist yet, was already deleted at that time or belongs to another owner.
+func (r *Repository) GetByIDAsOf(ctx context.Context, ownerID string, id int64, asOf time.Time) (_ *models.Todo, err error) {
+	ctx, span := models.StartDBSpan(ctx, "SELECT", "events")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT payload FROM events
+		WHERE aggregate_id = ? AND occurred_at <= ? ORDER BY version`, id, asOf.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var todo *models.Todo
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, err
+		}
+		var event events.TodoEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+		}
+		todo = Apply(todo, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if todo != nil && todo.OwnerID != ownerID {
+		return nil, nil
+	}
+	return todo, nil
+}
+
+// Bootstrap reconciles the event log with the todos table after it was written without
+// event sourcing: all todos when an existing database is switched to event sourcing, and
+// changes made while the database was used without it. A todo without events gets a created
+// event, a todo that differs from its replayed state gets an updated event and an aggregate
+// whose row is gone gets a deleted event, so that a rebuild reproduces the current table.
+// It returns the number of appended events.
+func (r *Repository) Bootstrap(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	log, err := loadEvents(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	state := make(map[int64]*models.Todo)
+	for _, event := range log {
+		state[event.TodoID] = Apply(state[event.TodoID], event)
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, owner_id, task, completed, created_at, updated_at FROM todos ORDER BY id")
+	if err != nil {
+		return 0, err
+	}
+	var todos []models.Todo
+	for rows.Next() {
+		var todo models.Todo
+		if err := rows.Scan(&todo.ID, &todo.OwnerID, &todo.Task, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		todos = append(todos, todo)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Историю изменений вне режима восстановить нельзя: события фиксируют только итоговое состояние
+	var pending []events.TodoEvent
+	for _, todo := range todos {
+		replayed := state[todo.ID]
+		delete(state, todo.ID)
+		switch {
+		case replayed == nil:
+			event := events.CreateTodoCreatedEvent(todo)
+			event.Timestamp = todo.CreatedAt
+			pending = append(pending, event)
+		case len(events.DiffTodos(*replayed, todo)) > 0:
+			event := events.CreateTodoUpdatedEvent(*replayed, todo)
+			event.Timestamp = todo.UpdatedAt
+			pending = append(pending, event)
+		}
+	}
+
+	// Оставшиеся в журнале задачи были удалены без событий; время удаления неизвестно
+	var deleted []int64
+	for id, todo := range state {
+		if todo != nil {
+			deleted = append(deleted, id)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i] < deleted[j] })
+	for _, id := range deleted {
+		pending = append(pending, events.CreateTodoDeletedEvent(id, state[id].OwnerID))
+	}
+
+	for _, event := range pending {
+		if err := appendEvent(tx, event); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	if len(pending) > 0 {
+		logger.Info("Event log reconciled with todos", zap.Int("events", len(pending)))
+	}
+	return len(pending), nil
+}
+
+// buildEvent returns the event produced by the service or a default one
+func (r *Repository) buildEvent(eventFn models.OutboxEventFunc, todo *models.Todo, defaultEvent func() events.TodoEvent) (events.TodoEvent, error) {
+	if eventFn == nil {
+		return defaultEvent(), nil
+	}
+	msg, err := eventFn(todo)
+	if err != nil {
+		return events.TodoEvent{}, err
+	}
+	return decodeEvent(msg)
+}
+
+// store appends the event, applies it to the todos projection and optionally writes it to the outbox
+func (r *Repository) store(tx *sql.Tx, event events.TodoEvent) error {
+	if err := appendEvent(tx, event); err != nil {
+		return err
+	}
+	if err := applyToProjection(tx, event); err != nil {
+		return err
+	}
+	if !r.outbox {
+		return nil
+	}
+	msg, err := events.NewOutboxMessage(event)
+	if err != nil {
+		return err
+	}
+	return models.InsertOutboxMessage(tx, msg)
+}
+
+// decodeEvent extracts the TodoEvent stored in an outbox message payload
+func decodeEvent(msg *models.OutboxMessage) (events.TodoEvent, error) {
+	var event events.TodoEvent
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		return event, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+	return event, nil
+}
+
+// appendEvent writes the event with the next version of its aggregate.
+// The unique (aggregate_id, version) constraint rejects concurrent writers.
+func appendEvent(tx *sql.Tx, event events.TodoEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	var version int64
+	if err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) + 1 FROM events WHERE aggregate_id = ?", event.TodoID).
+		Scan(&version); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO events (event_id, aggregate_id, version, event_type, payload, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		event.ID, event.TodoID, version, event.Type, payload, event.Timestamp.UTC())
+	return err
+}
+
+func getTodo(tx *sql.Tx, ownerID string, id int64) (*models.Todo, error) {
+	var todo models.Todo
+	err := tx.QueryRow("SELECT id, owner_id, task, completed, created_at, updated_at FROM todos WHERE id = ? AND owner_id = ?", id, ownerID).
+		Scan(&todo.ID, &todo.OwnerID, &todo.Task, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &todo, nil
+}
@@ -0,0 +1,289 @@
+package eventsourcing
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/events"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	_ = logger.Init(config.LogConfig{Level: "debug", Format: "console"})
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, database.EnsureTodosTableAndColumn(db))
+	require.NoError(t, database.EnsureEventsTable(db))
+	require.NoError(t, database.EnsureOutboxTable(db))
+	return db
+}
+
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+	return n
+}
+
+func TestRepository_WritesEventsAndProjection(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewRepository(db, true)
+
+	todo, err := repo.Create(ctx, "alice", "Write tests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), todo.ID)
+
+	completed := true
+	updated, err := repo.Update(ctx, "alice", todo.ID, models.TodoUpdateRequest{Completed: &completed})
+	require.NoError(t, err)
+	assert.True(t, updated.Completed)
+
+	got, err := repo.GetByID(ctx, "alice", todo.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Completed)
+	assert.Equal(t, "alice", got.OwnerID)
+
+	// Чужую задачу нельзя ни изменить, ни удалить: событий не добавляется
+	foreign, err := repo.Update(ctx, "bob", todo.ID, models.TodoUpdateRequest{Completed: &completed})
+	require.NoError(t, err)
+	assert.Nil(t, foreign)
+	require.NoError(t, repo.Delete(ctx, "bob", todo.ID))
+
+	require.NoError(t, repo.Delete(ctx, "alice", todo.ID))
+	// Повторное удаление не добавляет событий
+	require.NoError(t, repo.Delete(ctx, "alice", todo.ID))
+
+	rows, err := db.Query("SELECT event_type, version FROM events ORDER BY seq")
+	require.NoError(t, err)
+	defer rows.Close()
+	var types []string
+	var versions []int
+	for rows.Next() {
+		var eventType string
+		var version int
+		require.NoError(t, rows.Scan(&eventType, &version))
+		types = append(types, eventType)
+		versions = append(versions, version)
+	}
+	assert.Equal(t, []string{events.TypeCreated, events.TypeCompleted, events.TypeDeleted}, types)
+	assert.Equal(t, []int{1, 2, 3}, versions)
+	assert.Equal(t, 3, countRows(t, db, "outbox"))
+	assert.Equal(t, 0, countRows(t, db, "todos"))
+
+	// Идентификатор удалённой задачи не переиспользуется
+	next, err := repo.Create(ctx, "alice", "Another")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), next.ID)
+
+	// Журнал только дополняется
+	_, err = db.Exec("DELETE FROM events")
+	assert.Error(t, err)
+}
+
+func TestRepository_UsesServiceEvent(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewRepository(db, false)
+
+	var eventID string
+	_, err := repo.CreateWithEvent(ctx, "alice", "From service", func(todo *models.Todo) (*models.OutboxMessage, error) {
+		event := events.CreateTodoCreatedEvent(*todo)
+		eventID = event.ID
+		return events.NewOutboxMessage(event)
+	})
+	require.NoError(t, err)
+
+	var stored string
+	require.NoError(t, db.QueryRow("SELECT event_id FROM events").Scan(&stored))
+	assert.Equal(t, eventID, stored)
+	assert.Equal(t, 0, countRows(t, db, "outbox"))
+}
+
+func TestRepository_GetByIDAsOf(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewRepository(db, false)
+
+	beforeCreate := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	todo, err := repo.Create(ctx, "alice", "Original")
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	afterCreate := time.Now()
+	time.Sleep(5 * time.Millisecond)
+
+	task := "Renamed"
+	_, err = repo.Update(ctx, "alice", todo.ID, models.TodoUpdateRequest{Task: &task})
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	afterUpdate := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, repo.Delete(ctx, "alice", todo.ID))
+
+	past, err := repo.GetByIDAsOf(ctx, "alice", todo.ID, beforeCreate)
+	require.NoError(t, err)
+	assert.Nil(t, past)
+
+	past, err = repo.GetByIDAsOf(ctx, "alice", todo.ID, afterCreate)
+	require.NoError(t, err)
+	require.NotNil(t, past)
+	assert.Equal(t, "Original", past.Task)
+
+	past, err = repo.GetByIDAsOf(ctx, "alice", todo.ID, afterUpdate)
+	require.NoError(t, err)
+	require.NotNil(t, past)
+	assert.Equal(t, "Renamed", past.Task)
+
+	past, err = repo.GetByIDAsOf(ctx, "bob", todo.ID, afterUpdate)
+	require.NoError(t, err)
+	assert.Nil(t, past)
+
+	past, err = repo.GetByIDAsOf(ctx, "alice", todo.ID, time.Now())
+	require.NoError(t, err)
+	assert.Nil(t, past)
+}
+
+func TestRebuild(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewRepository(db, false)
+
+	first, err := repo.Create(ctx, "alice", "First")
+	require.NoError(t, err)
+	second, err := repo.Create(ctx, "alice", "Second")
+	require.NoError(t, err)
+	completed := true
+	_, err = repo.Update(ctx, "alice", second.ID, models.TodoUpdateRequest{Completed: &completed})
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, "alice", first.ID))
+
+	// Проекция расходится с журналом и восстанавливается из него
+	_, err = db.Exec("UPDATE todos SET task = 'corrupted'")
+	require.NoError(t, err)
+
+	result, err := Rebuild(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, RebuildResult{Events: 4, Todos: 1}, result)
+
+	got, err := repo.GetByID(ctx, "alice", second.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "Second", got.Task)
+	assert.True(t, got.Completed)
+}
+
+func TestRepository_Bootstrap(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	legacy := models.NewSQLiteTodoRepository(db)
+	_, err := legacy.Create(ctx, "alice", "Existing")
+	require.NoError(t, err)
+
+	repo := NewRepository(db, false)
+	seeded, err := repo.Bootstrap(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, seeded)
+
+	// Повторный запуск ничего не добавляет
+	seeded, err = repo.Bootstrap(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, seeded)
+
+	result, err := Rebuild(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Todos)
+
+	// Владелец сохраняется в журнале и восстанавливается в проекции
+	todos, err := repo.GetAll(ctx, "alice")
+	require.NoError(t, err)
+	assert.Len(t, todos, 1)
+
+	// Задача, созданная после возврата в CRUD-режим, дописывается в журнал и не теряется при пересборке
+	_, err = legacy.Create(ctx, "alice", "Created without event sourcing")
+	require.NoError(t, err)
+	seeded, err = repo.Bootstrap(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, seeded)
+
+	result, err = Rebuild(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Todos)
+
+	// Изменение и удаление в CRUD-режиме тоже попадают в журнал: пересборка не воскрешает
+	// удалённую задачу, а as_of видит последнее состояние
+	all, err := legacy.GetAll(ctx, "alice")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	updated, deleted := all[0], all[1]
+	task := "Renamed without event sourcing"
+	_, err = legacy.Update(ctx, "alice", updated.ID, models.TodoUpdateRequest{Task: &task})
+	require.NoError(t, err)
+	require.NoError(t, legacy.Delete(ctx, "alice", deleted.ID))
+
+	seeded, err = repo.Bootstrap(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, seeded)
+
+	seeded, err = repo.Bootstrap(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, seeded)
+
+	result, err = Rebuild(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Todos)
+
+	got, err := repo.GetByID(ctx, "alice", updated.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, task, got.Task)
+
+	asOf, err := repo.GetByIDAsOf(ctx, "alice", updated.ID, time.Now())
+	require.NoError(t, err)
+	require.NotNil(t, asOf)
+	assert.Equal(t, task, asOf.Task)
+
+	gone, err := repo.GetByIDAsOf(ctx, "alice", deleted.ID, time.Now())
+	require.NoError(t, err)
+	assert.Nil(t, gone)
+}
+
+func TestRepository_CreateSharesIDsWithTodos(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewRepository(db, false)
+	legacy := models.NewSQLiteTodoRepository(db)
+
+	first, err := repo.Create(ctx, "alice", "First")
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, "alice", first.ID))
+
+	// ID удалённой задачи не переиспользуется ни в CRUD-режиме, ни в журнале
+	crud, err := legacy.Create(ctx, "alice", "CRUD")
+	require.NoError(t, err)
+	assert.Greater(t, crud.ID, first.ID)
+
+	second, err := repo.Create(ctx, "alice", "Second")
+	require.NoError(t, err)
+	assert.Greater(t, second.ID, crud.ID)
+
+	got, err := repo.GetByID(ctx, "alice", crud.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "CRUD", got.Task)
+}
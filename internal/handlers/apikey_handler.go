@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// APIKeyHandler serves management of API keys for machine clients
+type APIKeyHandler struct {
+	service  *services.APIKeyService
+	validate *validator.Validate
+}
+
+func NewAPIKeyHandler(service *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		service:  service,
+		validate: validator.New(),
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Mint an API key with the given scopes (todos:read, todos:write, admin). The key is returned only in this response and is stored hashed
+// @Tags apikeys
+// @Accept json
+// @Produce json
+// @Param apikey body models.APIKeyCreateRequest true "API key to create"
+// @Success 201 {object} models.APIKey
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /apikeys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req models.APIKeyCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationError(c, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		handleValidationError(c, err)
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, services.ErrAPIKeyExpiryInPast), errors.Is(err, services.ErrUnknownScope):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Validation failed", Details: err.Error()})
+		return
+	case err != nil:
+		handleError(c, http.StatusInternalServerError, "Failed to create API key", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description Get the current user's API keys, including revoked and expired ones. Secrets are never returned
+// @Tags apikeys
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /apikeys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.service.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		handleError(c, http.StatusInternalServerError, "Failed to get API keys", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key by its ID; the key stops working immediately
+// @Tags apikeys
+// @Accept json
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 204 "No Content"
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /apikeys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	err := h.service.RevokeAPIKey(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, services.ErrAPIKeyNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "API key not found",
+		})
+		return
+	}
+	if err != nil {
+		handleError(c, http.StatusInternalServerError, "Failed to revoke API key", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
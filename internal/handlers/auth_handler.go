@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// AuthHandler serves registration, login and token endpoints of local accounts
+type AuthHandler struct {
+	service  *services.AuthService
+	validate *validator.Validate
+}
+
+func NewAuthHandler(service *services.AuthService) *AuthHandler {
+	return &AuthHandler{
+		service:  service,
+		validate: validator.New(),
+	}
+}
+
+// Register godoc
+// @Summary Register an account
+// @Description Create a local account with an email and a password (8 to 72 bytes)
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param account body models.RegisterRequest true "Account to create"
+// @Success 201 {object} models.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req models.RegisterRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	user, err := h.service.Register(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, models.ErrEmailTaken):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, services.ErrPasswordTooLong):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Validation failed", Details: err.Error()})
+		return
+	case err != nil:
+		handleError(c, http.StatusInternalServerError, "Failed to register user", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// Login godoc
+// @Summary Log in
+// @Description Exchange an email and a password for an access token and a refresh token. Failed attempts are rate-limited per email and per client IP
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginRequest true "Credentials"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	tokens, err := h.service.Login(c.Request.Context(), req, c.ClientIP())
+	var limited *services.LoginRateLimitError
+	switch {
+	case errors.As(err, &limited):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, services.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		handleError(c, http.StatusInternalServerError, "Failed to log in", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new token pair. The refresh token is single-use: reusing it revokes all sessions of the user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	tokens, err := h.service.Refresh(c.Request.Context(), req.RefreshToken)
+	if errors.Is(err, services.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		handleError(c, http.StatusInternalServerError, "Failed to refresh tokens", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Revoke a refresh token. Unknown tokens are ignored
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body models.RefreshRequest true "Refresh token"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.RefreshRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.service.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		handleError(c, http.StatusInternalServerError, "Failed to log out", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Send a one-time reset token to the account owner. The response is the same whether or not the email is registered
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.PasswordResetRequest true "Account email"
+// @Success 202 "Accepted"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.PasswordResetRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		handleError(c, http.StatusInternalServerError, "Failed to request password reset", err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword godoc
+// @Summary Reset a password
+// @Description Set a new password with a reset token. All sessions of the user are revoked
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.PasswordResetConfirmRequest true "Reset token and new password"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.PasswordResetConfirmRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	err := h.service.ResetPassword(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, services.ErrInvalidResetToken):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, services.ErrPasswordTooLong):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Validation failed", Details: err.Error()})
+		return
+	case err != nil:
+		handleError(c, http.StatusInternalServerError, "Failed to reset password", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// bind decodes and validates the JSON body; on failure the 400 response is already written
+func (h *AuthHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		handleValidationError(c, err)
+		return false
+	}
+	if err := h.validate.Struct(req); err != nil {
+		handleValidationError(c, err)
+		return false
+	}
+	return true
+}
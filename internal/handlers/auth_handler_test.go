@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/services"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newAuthRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	require.NoError(t, database.EnsureUserTables(db))
+
+	issuer, err := auth.NewTokenIssuer(config.JWTConfig{Secret: "secret"}, time.Minute)
+	require.NoError(t, err)
+	service := services.NewAuthService(models.NewSQLiteUserRepository(db), issuer, config.LocalAuthConfig{
+		RefreshTokenTTL: time.Hour,
+		BcryptCost:      bcrypt.MinCost,
+		RateLimit:       config.LoginRateLimitConfig{MaxAttempts: 2, Window: time.Minute},
+	})
+	h := NewAuthHandler(service)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/register", h.Register)
+	r.POST("/auth/login", h.Login)
+	r.POST("/auth/refresh", h.Refresh)
+	r.POST("/auth/logout", h.Logout)
+	return r
+}
+
+func postJSON(r *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthHandler_Flow(t *testing.T) {
+	r := newAuthRouter(t)
+	account := `{"email": "alice@example.com", "password": "correct horse"}`
+
+	w := postJSON(r, "/auth/register", account)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "password")
+
+	w = postJSON(r, "/auth/register", account)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = postJSON(r, "/auth/register", `{"email": "not-an-email", "password": "short"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postJSON(r, "/auth/login", account)
+	require.Equal(t, http.StatusOK, w.Code)
+	var tokens models.TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	assert.NotEmpty(t, tokens.AccessToken)
+
+	w = postJSON(r, "/auth/refresh", `{"refresh_token": "`+tokens.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = postJSON(r, "/auth/refresh", `{"refresh_token": "`+tokens.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = postJSON(r, "/auth/logout", `{"refresh_token": "unknown"}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestAuthHandler_LoginRateLimit(t *testing.T) {
+	r := newAuthRouter(t)
+	wrong := `{"email": "alice@example.com", "password": "guess"}`
+
+	for i := 0; i < 2; i++ {
+		w := postJSON(r, "/auth/login", wrong)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	w := postJSON(r, "/auth/login", wrong)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"todo_app_go/internal/health"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -36,3 +41,53 @@ func TestReadyCheck(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "ready")
 }
+
+func TestReadyCheck_Dependencies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheErr := errors.New("redis: connection refused")
+	var dbErr error
+
+	registry := health.NewRegistry(time.Second)
+	registry.Register(health.Checker{Name: "database", Critical: true, Check: func(ctx context.Context) error { return dbErr }})
+	registry.Register(health.Checker{Name: "cache", Check: func(ctx context.Context) error { return cacheErr }})
+
+	h := (&TodoHandler{}).WithHealth(registry)
+	r := gin.New()
+	r.GET("/health", h.HealthCheck)
+	r.GET("/ready", h.ReadyCheck)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Отказ некритичного кэша: сервис готов, но degraded
+	w := get("/ready")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"degraded"}`, w.Body.String())
+
+	// verbose показывает состояние каждой зависимости
+	w = get("/health?verbose=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"cache":{"status":"down","critical":false`)
+	assert.Contains(t, w.Body.String(), "redis: connection refused")
+
+	// Без verbose liveness не выполняет проверки
+	w = get("/health")
+	assert.JSONEq(t, `{"status":"healthy"}`, w.Body.String())
+
+	// Отказ критичной БД
+	dbErr = errors.New("database is locked")
+	w = get("/ready?verbose=1")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"not ready"`)
+	assert.Contains(t, w.Body.String(), "database is locked")
+
+	// При graceful shutdown /ready отвечает 503 даже при исправных зависимостях
+	dbErr, cacheErr = nil, nil
+	assert.Equal(t, http.StatusOK, get("/ready").Code)
+	registry.SetShuttingDown()
+	assert.Equal(t, http.StatusServiceUnavailable, get("/ready").Code)
+}
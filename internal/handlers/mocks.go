@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"time"
 	"todo_app_go/internal/models"
 
@@ -9,9 +10,10 @@ import (
 
 type mockRepo struct{}
 
-func (m *mockRepo) Create(task string) (*models.Todo, error) {
+func (m *mockRepo) Create(ctx context.Context, ownerID, task string) (*models.Todo, error) {
 	return &models.Todo{
 		ID:        123,
+		OwnerID:   ownerID,
 		Task:      task,
 		Completed: false,
 		CreatedAt: time.Now(),
@@ -19,7 +21,7 @@ func (m *mockRepo) Create(task string) (*models.Todo, error) {
 	}, nil
 }
 
-func (m *mockRepo) GetByID(id int64) (*models.Todo, error) {
+func (m *mockRepo) GetByID(ctx context.Context, ownerID string, id int64) (*models.Todo, error) {
 	if id == 42 {
 		return &models.Todo{ID: 42, Task: "Answer"}, nil
 	}
@@ -32,7 +34,7 @@ func (m *mockRepo) GetByID(id int64) (*models.Todo, error) {
 	return nil, nil // not found
 }
 
-func (m *mockRepo) Update(id int64, req models.TodoUpdateRequest) (*models.Todo, error) {
+func (m *mockRepo) Update(ctx context.Context, ownerID string, id int64, req models.TodoUpdateRequest) (*models.Todo, error) {
 	if id == 42 {
 		return &models.Todo{ID: 42, Task: "Updated"}, nil
 	}
@@ -42,14 +44,16 @@ func (m *mockRepo) Update(id int64, req models.TodoUpdateRequest) (*models.Todo,
 	return nil, nil // not found
 }
 
-func (m *mockRepo) Delete(id int64) error {
+func (m *mockRepo) Delete(ctx context.Context, ownerID string, id int64) error {
 	if id == 500 {
 		return assert.AnError
 	}
 	return nil
 }
 
-func (m *mockRepo) GetAll() ([]models.Todo, error) {
+func (m *mockRepo) GetAll(ctx context.Context, ownerID string) ([]models.Todo, error) {
 	return []models.Todo{{ID: 1, Task: "Test task"}}, nil
 }
-func (m *mockRepo) UpdateStatus(id int64, completed bool) error { return nil }
+func (m *mockRepo) UpdateStatus(ctx context.Context, ownerID string, id int64, completed bool) error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// oidcStateCookie привязывает state к браузеру, начавшему вход: без него чужой
+// callback-URL с кодом злоумышленника залогинил бы жертву в его аккаунт
+const (
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/auth/oidc"
+)
+
+// OIDCHandler serves the OIDC login endpoints
+type OIDCHandler struct {
+	service  *services.OIDCService
+	stateTTL time.Duration
+}
+
+func NewOIDCHandler(service *services.OIDCService, stateTTL time.Duration) *OIDCHandler {
+	return &OIDCHandler{
+		service:  service,
+		stateTTL: stateTTL,
+	}
+}
+
+// Login godoc
+// @Summary Log in with OIDC
+// @Description Start the OIDC authorization code flow with PKCE: redirects to the provider and sets a state cookie for the callback
+// @Tags auth
+// @Success 302 "Redirect to the provider"
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/oidc/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, state, err := h.service.StartLogin(c.Request.Context())
+	if err != nil {
+		handleError(c, http.StatusInternalServerError, "Failed to start OIDC login", err)
+		return
+	}
+
+	// Lax: cookie должна прийти с top-level редиректа от провайдера
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(h.stateTTL.Seconds()), oidcCookiePath, "", isHTTPS(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback godoc
+// @Summary OIDC callback
+// @Description Complete the OIDC login: the provider redirects here with a code. Maps the provider account to a local user (linking by verified email or creating one) and returns a token pair
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login request"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/oidc/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	// Cookie одноразовая, как и сам state
+	cookie, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath, "", isHTTPS(c), true)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "OIDC login failed",
+			Details: strings.TrimSpace(providerErr + " " + c.Query("error_description")),
+		})
+		return
+	}
+
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookie)) != 1 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: services.ErrInvalidOIDCState.Error()})
+		return
+	}
+
+	tokens, err := h.service.Callback(c.Request.Context(), state, code)
+	switch {
+	case errors.Is(err, services.ErrInvalidOIDCState):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, auth.ErrOIDCExchange), errors.Is(err, auth.ErrInvalidIDToken):
+		logger.FromContext(c.Request.Context()).Warn("OIDC login rejected", zap.Error(err))
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "OIDC login failed"})
+		return
+	case errors.Is(err, services.ErrOIDCAccountNotAllowed):
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, services.ErrOIDCEmailNotVerified), errors.Is(err, models.ErrEmailTaken):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		handleError(c, http.StatusInternalServerError, "Failed to complete OIDC login", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// isHTTPS reports whether the client reached the service over TLS, directly or through a proxy
+func isHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+}
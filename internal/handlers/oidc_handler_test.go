@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/auth/oidctest"
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testRedirectURL = "http://todo.test/auth/oidc/callback"
+
+func newOIDCRouter(t *testing.T, provider *oidctest.Provider) (*gin.Engine, *auth.Validator) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	require.NoError(t, database.EnsureUserTables(db))
+
+	jwtCfg := config.JWTConfig{Secret: "secret"}
+	issuer, err := auth.NewTokenIssuer(jwtCfg, time.Minute)
+	require.NoError(t, err)
+	validator, err := auth.NewValidator(jwtCfg)
+	require.NoError(t, err)
+
+	oidcCfg := config.OIDCConfig{
+		Issuer:       provider.Issuer(),
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email"},
+		StateTTL:     time.Minute,
+	}
+	oidcProvider, err := auth.NewOIDCProvider(oidcCfg)
+	require.NoError(t, err)
+
+	users := models.NewSQLiteUserRepository(db)
+	authService := services.NewAuthService(users, issuer, config.LocalAuthConfig{
+		RefreshTokenTTL: time.Hour,
+		BcryptCost:      bcrypt.MinCost,
+	})
+	h := NewOIDCHandler(services.NewOIDCService(authService, users, oidcProvider, oidcCfg), oidcCfg.StateTTL)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/auth/oidc/login", h.Login)
+	r.GET("/auth/oidc/callback", h.Callback)
+	return r, validator
+}
+
+// startOIDCLogin calls /auth/oidc/login, lets the fake provider sign the user in and
+// returns the callback URL the browser is sent to, together with the state cookie
+func startOIDCLogin(t *testing.T, r *gin.Engine) (*url.URL, *http.Cookie) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/auth/oidc/login", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	assert.Equal(t, testRedirectURL, authURL.Query().Get("redirect_uri"))
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, authURL.Query().Get("state"), cookies[0].Value)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL.String())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return callback, cookies[0]
+}
+
+func callOIDCCallback(r *gin.Engine, callback *url.URL, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", callback.RequestURI(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestOIDCHandler_Flow(t *testing.T) {
+	provider := oidctest.NewProvider("todo-app", "client-secret")
+	defer provider.Close()
+	r, validator := newOIDCRouter(t, provider)
+
+	callback, cookie := startOIDCLogin(t, r)
+	w := callOIDCCallback(r, callback, cookie)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var tokens models.TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	claims, err := validator.Validate(t.Context(), tokens.AccessToken)
+	require.NoError(t, err)
+	userID := claims.Subject
+
+	// State одноразовый: повтор того же callback отклоняется
+	w = callOIDCCallback(r, callback, cookie)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Повторный вход того же аккаунта провайдера попадает в того же пользователя
+	callback, cookie = startOIDCLogin(t, r)
+	w = callOIDCCallback(r, callback, cookie)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	claims, err = validator.Validate(t.Context(), tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, userID, claims.Subject)
+}
+
+func TestOIDCHandler_StateMustMatchCookie(t *testing.T) {
+	provider := oidctest.NewProvider("todo-app", "")
+	defer provider.Close()
+	r, _ := newOIDCRouter(t, provider)
+
+	callback, _ := startOIDCLogin(t, r)
+	w := callOIDCCallback(r, callback, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	_, otherCookie := startOIDCLogin(t, r)
+	w = callOIDCCallback(r, callback, otherCookie)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOIDCHandler_ProviderError(t *testing.T) {
+	provider := oidctest.NewProvider("todo-app", "")
+	defer provider.Close()
+	r, _ := newOIDCRouter(t, provider)
+
+	w := callOIDCCallback(r, &url.URL{Path: "/auth/oidc/callback", RawQuery: "error=access_denied&state=x"}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "access_denied")
+}
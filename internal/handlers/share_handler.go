@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// ShareHandler serves share links, the public view behind them and comments on todos.
+// Like the todo routes, the member routes also work under /workspaces/{workspace_id}.
+type ShareHandler struct {
+	service  *services.ShareService
+	validate *validator.Validate
+}
+
+func NewShareHandler(service *services.ShareService) *ShareHandler {
+	return &ShareHandler{
+		service:  service,
+		validate: validator.New(),
+	}
+}
+
+// ShareTodo godoc
+// @Summary Share a todo
+// @Description Create a public link to the todo with the read or comment scope. The token is returned only in this response; the link works until it expires or is revoked
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param share body models.ShareCreateRequest true "Scope and expiry of the link"
+// @Success 201 {object} models.Share
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /todos/{id}/share [post]
+func (h *ShareHandler) ShareTodo(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, http.StatusBadRequest, "Invalid todo ID", err)
+		return
+	}
+	var req models.ShareCreateRequest
+	if !bindJSON(c, h.validate, &req) {
+		return
+	}
+
+	share, err := h.service.ShareTodo(c.Request.Context(), id, req)
+	if err != nil {
+		handleShareError(c, "Failed to share todo", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, share)
+}
+
+// ShareList godoc
+// @Summary Share the todo list
+// @Description Create a public link to the whole todo list with the read or comment scope. The token is returned only in this response
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param share body models.ShareCreateRequest true "Scope and expiry of the link"
+// @Success 201 {object} models.Share
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /shares [post]
+func (h *ShareHandler) ShareList(c *gin.Context) {
+	var req models.ShareCreateRequest
+	if !bindJSON(c, h.validate, &req) {
+		return
+	}
+
+	share, err := h.service.ShareList(c.Request.Context(), req)
+	if err != nil {
+		handleShareError(c, "Failed to share todo list", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, share)
+}
+
+// ListShares godoc
+// @Summary List share links
+// @Description Get the links to the todo list and its todos, including revoked and expired ones. Tokens are never returned
+// @Tags shares
+// @Produce json
+// @Success 200 {array} models.Share
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /shares [get]
+func (h *ShareHandler) ListShares(c *gin.Context) {
+	shares, err := h.service.ListShares(c.Request.Context())
+	if err != nil {
+		handleServiceError(c, "Failed to get share links", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shares)
+}
+
+// RevokeShare godoc
+// @Summary Revoke a share link
+// @Description Revoke a link to the todo list or one of its todos; it stops working immediately
+// @Tags shares
+// @Produce json
+// @Param share_id path string true "Share ID"
+// @Success 204 "No Content"
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /shares/{share_id} [delete]
+func (h *ShareHandler) RevokeShare(c *gin.Context) {
+	if err := h.service.RevokeShare(c.Request.Context(), c.Param("share_id")); err != nil {
+		handleShareError(c, "Failed to revoke share link", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListComments godoc
+// @Summary List comments on a todo
+// @Description Get the comments on a todo, including those left through share links
+// @Tags shares
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Success 200 {array} models.Comment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /todos/{id}/comments [get]
+func (h *ShareHandler) ListComments(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, http.StatusBadRequest, "Invalid todo ID", err)
+		return
+	}
+
+	comments, err := h.service.ListComments(c.Request.Context(), id)
+	if err != nil {
+		handleShareError(c, "Failed to get comments", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// AddComment godoc
+// @Summary Comment on a todo
+// @Description Add a comment on a todo as the current user
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param comment body models.CommentCreateRequest true "Comment"
+// @Success 201 {object} models.Comment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /todos/{id}/comments [post]
+func (h *ShareHandler) AddComment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		handleError(c, http.StatusBadRequest, "Invalid todo ID", err)
+		return
+	}
+	var req models.CommentCreateRequest
+	if !bindJSON(c, h.validate, &req) {
+		return
+	}
+
+	comment, err := h.service.AddComment(c.Request.Context(), id, req)
+	if err != nil {
+		handleShareError(c, "Failed to add comment", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// GetShared godoc
+// @Summary Open a share link
+// @Description Get the shared todo or todo list with its comments. Public: the token is the only credential
+// @Tags shares
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} models.SharedView
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared/{token} [get]
+func (h *ShareHandler) GetShared(c *gin.Context) {
+	noStore(c)
+	view, err := h.service.GetShared(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		handleShareError(c, "Failed to open share link", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// AddSharedComment godoc
+// @Summary Comment through a share link
+// @Description Add a comment through a link with the comment scope. todo_id is required when the whole list is shared. Public: the token is the only credential
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param token path string true "Share token"
+// @Param comment body models.SharedCommentCreateRequest true "Comment"
+// @Success 201 {object} models.Comment
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shared/{token}/comments [post]
+func (h *ShareHandler) AddSharedComment(c *gin.Context) {
+	noStore(c)
+	var req models.SharedCommentCreateRequest
+	if !bindJSON(c, h.validate, &req) {
+		return
+	}
+
+	comment, err := h.service.AddSharedComment(c.Request.Context(), c.Param("token"), req)
+	if err != nil {
+		handleShareError(c, "Failed to add comment", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// noStore keeps responses to share links out of caches and the token out of Referer headers
+func noStore(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+}
+
+func handleShareError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidShare):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Share link not found"})
+	case errors.Is(err, services.ErrShareNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Share not found"})
+	case errors.Is(err, services.ErrTodoNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Todo not found"})
+	case errors.Is(err, services.ErrShareReadOnly):
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "Forbidden", Details: err.Error()})
+	case errors.Is(err, services.ErrShareExpiryInPast):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Validation failed", Details: err.Error()})
+	default:
+		handleServiceError(c, message, err)
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/middleware"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/policy"
+	"todo_app_go/internal/requestctx"
+	"todo_app_go/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newShareRouter(t *testing.T) (*gin.Engine, *models.SQLiteWorkspaceRepository) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	require.NoError(t, database.EnsureTodosTableAndColumn(db))
+	require.NoError(t, database.EnsureWorkspaceTables(db))
+	require.NoError(t, database.EnsureShareTables(db))
+
+	workspaces := models.NewSQLiteWorkspaceRepository(db)
+	todoService := services.NewTodoService(models.NewSQLiteTodoRepository(db), nil, nil).WithWorkspaces(workspaces, policy.New())
+	todoHandler := NewTodoHandler(todoService)
+	h := NewShareHandler(services.NewShareService(todoService, models.NewSQLiteShareRepository(db), models.NewSQLiteCommentRepository(db)))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/shared/:token", h.GetShared)
+	r.POST("/shared/:token/comments", h.AddSharedComment)
+
+	api := r.Group("", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(requestctx.WithUserID(c.Request.Context(), c.GetHeader(testUserHeader)))
+		c.Next()
+	})
+	for _, group := range []*gin.RouterGroup{api, api.Group("/workspaces/:workspace_id", middleware.Workspace())} {
+		group.POST("/todos", todoHandler.CreateTodo)
+		group.POST("/todos/:id/share", h.ShareTodo)
+		group.GET("/todos/:id/comments", h.ListComments)
+		group.POST("/todos/:id/comments", h.AddComment)
+		group.GET("/shares", h.ListShares)
+		group.POST("/shares", h.ShareList)
+		group.DELETE("/shares/:share_id", h.RevokeShare)
+	}
+	return r, workspaces
+}
+
+func createTodoAs(t *testing.T, r *gin.Engine, user, base, task string) models.Todo {
+	t.Helper()
+	w := doAs(r, user, "POST", base+"/todos", `{"task": "`+task+`"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var todo models.Todo
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &todo))
+	return todo
+}
+
+func shareAs(t *testing.T, r *gin.Engine, user, path, body string) models.Share {
+	t.Helper()
+	w := doAs(r, user, "POST", path, body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var share models.Share
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &share))
+	require.NotEmpty(t, share.Token)
+	return share
+}
+
+func TestShareHandler_TodoLink(t *testing.T) {
+	r, _ := newShareRouter(t)
+	todo := createTodoAs(t, r, "alice", "", "Shared task")
+	createTodoAs(t, r, "alice", "", "Private task")
+	todoPath := "/todos/" + strconv.FormatInt(todo.ID, 10)
+
+	w := doAs(r, "alice", "POST", todoPath+"/share", `{"scope": "write"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doAs(r, "bob", "POST", todoPath+"/share", `{"scope": "read"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Ссылка только на чтение показывает одну todo и не даёт комментировать
+	readOnly := shareAs(t, r, "alice", todoPath+"/share", `{"scope": "read"}`)
+	w = doAs(r, "", "GET", "/shared/"+readOnly.Token, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Body.String(), "Shared task")
+	assert.NotContains(t, w.Body.String(), "Private task")
+	w = doAs(r, "", "POST", "/shared/"+readOnly.Token+"/comments", `{"author": "Guest", "body": "Hi"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Ссылка с комментариями: комментарий виден и по ссылке, и владельцу
+	commentable := shareAs(t, r, "alice", todoPath+"/share", `{"scope": "comment"}`)
+	w = doAs(r, "", "POST", "/shared/"+commentable.Token+"/comments", `{"author": "Guest", "body": "Looks good"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = doAs(r, "", "POST", "/shared/"+commentable.Token+"/comments", `{"todo_id": 999, "author": "Guest", "body": "Other"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doAs(r, "", "GET", "/shared/"+commentable.Token, "")
+	assert.Contains(t, w.Body.String(), "Looks good")
+	w = doAs(r, "alice", "GET", todoPath+"/comments", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"author":"Guest"`)
+
+	// Отозванная и неизвестная ссылки неотличимы
+	w = doAs(r, "alice", "DELETE", "/shares/"+readOnly.ID, "")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	w = doAs(r, "", "GET", "/shared/"+readOnly.Token, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doAs(r, "", "GET", "/shared/unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doAs(r, "alice", "DELETE", "/shares/"+readOnly.ID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShareHandler_WorkspaceListLink(t *testing.T) {
+	r, workspaces := newShareRouter(t)
+	ctx := requestctx.WithUserID(t.Context(), "alice")
+	require.NoError(t, workspaces.CreateWorkspace(ctx, &models.Workspace{ID: "ws", Name: "Team", CreatedBy: "alice"}))
+	require.NoError(t, workspaces.CreateInvite(ctx, &models.WorkspaceInvite{ID: "inv", WorkspaceID: "ws", Role: "viewer", InvitedBy: "alice", TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}))
+	_, err := workspaces.AcceptInvite(ctx, "hash", "bob")
+	require.NoError(t, err)
+	base := "/workspaces/ws"
+
+	first := createTodoAs(t, r, "alice", base, "First")
+	createTodoAs(t, r, "alice", base, "Second")
+	createTodoAs(t, r, "alice", "", "Personal")
+
+	// Viewer комментирует, но не делится
+	w := doAs(r, "bob", "POST", base+"/shares", `{"scope": "read"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doAs(r, "bob", "POST", base+"/todos/"+strconv.FormatInt(first.ID, 10)+"/comments", `{"body": "Member note"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = doAs(r, "carol", "GET", base+"/shares", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	share := shareAs(t, r, "alice", base+"/shares", `{"scope": "comment"}`)
+	w = doAs(r, "", "GET", "/shared/"+share.Token, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var view models.SharedView
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &view))
+	assert.Len(t, view.Todos, 2)
+	require.Len(t, view.Comments, 1)
+	assert.Equal(t, "bob", view.Comments[0].Author)
+
+	// Для ссылки на список нужно указать todo, и только из этого списка
+	w = doAs(r, "", "POST", "/shared/"+share.Token+"/comments", `{"author": "Guest", "body": "Hi"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doAs(r, "", "POST", "/shared/"+share.Token+"/comments", `{"todo_id": `+strconv.FormatInt(first.ID, 10)+`, "author": "Guest", "body": "Hi"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Ссылки пространства не видны в личном списке
+	w = doAs(r, "alice", "GET", "/shares", "")
+	assert.Equal(t, "[]", w.Body.String())
+	w = doAs(r, "alice", "GET", base+"/shares", "")
+	assert.Contains(t, w.Body.String(), share.ID)
+}
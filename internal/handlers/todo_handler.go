@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"todo_app_go/internal/health"
 	"todo_app_go/internal/logger"
-	"todo_app_go/internal/metrics"
 	"todo_app_go/internal/models"
+	"todo_app_go/internal/policy"
 	"todo_app_go/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +20,7 @@ import (
 type TodoHandler struct {
 	service  *services.TodoService
 	validate *validator.Validate
+	health   *health.Registry
 }
 
 func NewTodoHandler(service *services.TodoService) *TodoHandler {
@@ -26,6 +30,12 @@ func NewTodoHandler(service *services.TodoService) *TodoHandler {
 	}
 }
 
+// WithHealth включает проверки зависимостей в /health?verbose=1 и /ready
+func (h *TodoHandler) WithHealth(registry *health.Registry) *TodoHandler {
+	h.health = registry
+	return h
+}
+
 // CreateTodo godoc
 // @Summary Create a new todo
 // @Description Create a new todo item
@@ -35,23 +45,27 @@ func NewTodoHandler(service *services.TodoService) *TodoHandler {
 // @Param todo body models.TodoCreateRequest true "Todo to create"
 // @Success 201 {object} models.Todo
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /todos [post]
 func (h *TodoHandler) CreateTodo(c *gin.Context) {
 	var req models.TodoCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.handleValidationError(c, err)
+		handleValidationError(c, err)
 		return
 	}
 
 	if err := h.validate.Struct(req); err != nil {
-		h.handleValidationError(c, err)
+		handleValidationError(c, err)
 		return
 	}
 
 	todo, err := h.service.CreateTodo(c.Request.Context(), req)
 	if err != nil {
-		h.handleError(c, http.StatusInternalServerError, "Failed to create todo", err)
+		handleServiceError(c, "Failed to create todo", err)
 		return
 	}
 
@@ -65,20 +79,41 @@ func (h *TodoHandler) CreateTodo(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Todo ID"
+// @Param as_of query string false "Return the todo as of this RFC 3339 timestamp (event sourcing mode)"
 // @Success 200 {object} models.Todo
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /todos/{id} [get]
 func (h *TodoHandler) GetTodo(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		h.handleError(c, http.StatusBadRequest, "Invalid todo ID", err)
+		handleError(c, http.StatusBadRequest, "Invalid todo ID", err)
 		return
 	}
 
-	todo, err := h.service.GetTodo(c.Request.Context(), id)
+	var todo *models.Todo
+	if asOfParam := c.Query("as_of"); asOfParam != "" {
+		asOf, parseErr := time.Parse(time.RFC3339Nano, asOfParam)
+		if parseErr != nil {
+			handleError(c, http.StatusBadRequest, "Invalid as_of timestamp", parseErr)
+			return
+		}
+		todo, err = h.service.GetTodoAsOf(c.Request.Context(), id, asOf)
+		if errors.Is(err, services.ErrHistoryUnavailable) {
+			handleError(c, http.StatusNotImplemented, "Time travel queries require event sourcing mode", err)
+			return
+		}
+	} else {
+		todo, err = h.service.GetTodo(c.Request.Context(), id)
+	}
 	if err != nil {
-		h.handleError(c, http.StatusInternalServerError, "Failed to get todo", err)
+		handleServiceError(c, "Failed to get todo", err)
 		return
 	}
 
@@ -99,12 +134,16 @@ func (h *TodoHandler) GetTodo(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {array} models.Todo
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /todos [get]
 func (h *TodoHandler) GetAllTodos(c *gin.Context) {
 	todos, err := h.service.GetAllTodos(c.Request.Context())
 	if err != nil {
-		h.handleError(c, http.StatusInternalServerError, "Failed to get todos", err)
+		handleServiceError(c, "Failed to get todos", err)
 		return
 	}
 
@@ -121,30 +160,34 @@ func (h *TodoHandler) GetAllTodos(c *gin.Context) {
 // @Param todo body models.TodoUpdateRequest true "Todo updates"
 // @Success 200 {object} models.Todo
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /todos/{id} [put]
 func (h *TodoHandler) UpdateTodo(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		h.handleError(c, http.StatusBadRequest, "Invalid todo ID", err)
+		handleError(c, http.StatusBadRequest, "Invalid todo ID", err)
 		return
 	}
 
 	var req models.TodoUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.handleValidationError(c, err)
+		handleValidationError(c, err)
 		return
 	}
 
 	if err := h.validate.Struct(req); err != nil {
-		h.handleValidationError(c, err)
+		handleValidationError(c, err)
 		return
 	}
 
 	todo, err := h.service.UpdateTodo(c.Request.Context(), id, req)
 	if err != nil {
-		h.handleError(c, http.StatusInternalServerError, "Failed to update todo", err)
+		handleServiceError(c, "Failed to update todo", err)
 		return
 	}
 
@@ -167,18 +210,29 @@ func (h *TodoHandler) UpdateTodo(c *gin.Context) {
 // @Param id path int true "Todo ID"
 // @Success 204 "No Content"
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /todos/{id} [delete]
 func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		h.handleError(c, http.StatusBadRequest, "Invalid todo ID", err)
+		handleError(c, http.StatusBadRequest, "Invalid todo ID", err)
 		return
 	}
 
 	err = h.service.DeleteTodo(c.Request.Context(), id)
+	if errors.Is(err, services.ErrTodoNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Todo not found",
+		})
+		return
+	}
 	if err != nil {
-		h.handleError(c, http.StatusInternalServerError, "Failed to delete todo", err)
+		handleServiceError(c, "Failed to delete todo", err)
 		return
 	}
 
@@ -187,49 +241,101 @@ func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 
 // HealthCheck godoc
 // @Summary Health check
-// @Description Check if the service is healthy
+// @Description Liveness check. With verbose=1 also runs dependency checks and returns per-component status and latency
 // @Tags health
 // @Accept json
 // @Produce json
+// @Param verbose query string false "Include dependency checks (1 or true)"
 // @Success 200 {object} HealthResponse
 // @Router /health [get]
 func (h *TodoHandler) HealthCheck(c *gin.Context) {
+	// Liveness не зависит от внешних сервисов: отказ Redis не повод перезапускать pod
+	if h.health == nil || !isVerbose(c) {
+		c.JSON(http.StatusOK, HealthResponse{
+			Status: "healthy",
+		})
+		return
+	}
+
+	report := h.health.Check(c.Request.Context())
 	c.JSON(http.StatusOK, HealthResponse{
-		Status: "healthy",
+		Status:     report.Status,
+		Components: report.Components,
 	})
 }
 
 // ReadyCheck godoc
 // @Summary Ready check
-// @Description Check if the service is ready to serve requests
+// @Description Check if the service is ready to serve requests. Returns 503 if a critical dependency is down or the service is shutting down
 // @Tags health
 // @Accept json
 // @Produce json
+// @Param verbose query string false "Include per-component status (1 or true)"
 // @Success 200 {object} HealthResponse
+// @Failure 503 {object} HealthResponse
 // @Router /ready [get]
 func (h *TodoHandler) ReadyCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, HealthResponse{
-		Status: "ready",
-	})
+	if h.health == nil {
+		c.JSON(http.StatusOK, HealthResponse{
+			Status: "ready",
+		})
+		return
+	}
+
+	report, ready := h.health.Ready(c.Request.Context())
+	response := HealthResponse{Status: health.ReadyStatus(report, ready)}
+	statusCode := http.StatusOK
+	if !ready {
+		statusCode = http.StatusServiceUnavailable
+	}
+	if isVerbose(c) {
+		response.Components = report.Components
+	}
+	c.JSON(statusCode, response)
 }
 
-// Вспомогательные методы
-func (h *TodoHandler) handleError(c *gin.Context, statusCode int, message string, err error) {
-	logger.Error(message, zap.Error(err))
+func isVerbose(c *gin.Context) bool {
+	verbose := c.Query("verbose")
+	return verbose == "1" || verbose == "true"
+}
 
-	// Увеличиваем метрики ошибок
-	metrics.HttpRequestsTotal.WithLabelValues(c.Request.Method, c.FullPath(), strconv.Itoa(statusCode)).Inc()
+// Вспомогательные методы
+func handleError(c *gin.Context, statusCode int, message string, err error) {
+	logger.FromContext(c.Request.Context()).Error(message, zap.Error(err))
 
 	c.JSON(statusCode, ErrorResponse{
 		Error: message,
 	})
 }
 
-func (h *TodoHandler) handleValidationError(c *gin.Context, err error) {
-	logger.Error("Validation error", zap.Error(err))
+// handleServiceError answers authorization failures of the services with 403 and 404
+// and any other error with 500
+func handleServiceError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, policy.ErrForbidden):
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "Forbidden", Details: err.Error()})
+	case errors.Is(err, services.ErrWorkspaceNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Workspace not found"})
+	default:
+		handleError(c, http.StatusInternalServerError, message, err)
+	}
+}
+
+// bindJSON binds the request body and validates it, answering 400 if either fails
+func bindJSON(c *gin.Context, validate *validator.Validate, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		handleValidationError(c, err)
+		return false
+	}
+	if err := validate.Struct(req); err != nil {
+		handleValidationError(c, err)
+		return false
+	}
+	return true
+}
 
-	// Увеличиваем метрики ошибок валидации
-	metrics.HttpRequestsTotal.WithLabelValues(c.Request.Method, c.FullPath(), "400").Inc()
+func handleValidationError(c *gin.Context, err error) {
+	logger.FromContext(c.Request.Context()).Error("Validation error", zap.Error(err))
 
 	c.JSON(http.StatusBadRequest, ErrorResponse{
 		Error:   "Validation failed",
@@ -244,5 +350,6 @@ type ErrorResponse struct {
 }
 
 type HealthResponse struct {
-	Status string `json:"status"`
+	Status     string                            `json:"status"`
+	Components map[string]health.ComponentStatus `json:"components,omitempty"`
 }
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"todo_app_go/internal/config"
 	"todo_app_go/internal/logger"
 	"todo_app_go/internal/services"
 
@@ -14,7 +15,7 @@ import (
 )
 
 func init() {
-	_ = logger.Init("debug", "console")
+	_ = logger.Init(config.LogConfig{Level: "debug", Format: "console"})
 }
 
 func TestGetAllTodos(t *testing.T) {
@@ -87,6 +88,27 @@ func TestGetTodo_NotFound(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "Todo not found")
 }
 
+func TestGetTodo_AsOf(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepo{}
+	service := services.NewTodoService(repo, nil, nil)
+	h := NewTodoHandler(service)
+
+	r := gin.New()
+	r.GET("/todos/:id", h.GetTodo)
+
+	req, _ := http.NewRequest("GET", "/todos/42?as_of=yesterday", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Без event sourcing история недоступна
+	req, _ = http.NewRequest("GET", "/todos/42?as_of=2024-01-15T10:30:00Z", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
 func TestUpdateTodo_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	repo := &mockRepo{}
@@ -141,6 +163,23 @@ func TestDeleteTodo_Success(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, w.Code)
 }
 
+func TestDeleteTodo_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &mockRepo{}
+	service := services.NewTodoService(repo, nil, nil)
+	h := NewTodoHandler(service)
+
+	r := gin.New()
+	r.DELETE("/todos/:id", h.DeleteTodo)
+
+	req, _ := http.NewRequest("DELETE", "/todos/99", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "Todo not found")
+}
+
 func TestDeleteTodo_Error(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	repo := &mockRepo{}
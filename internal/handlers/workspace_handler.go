@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// WorkspaceHandler serves workspaces, their members and invites. Todos of a workspace are
+// served by TodoHandler under /workspaces/{workspace_id}/todos.
+type WorkspaceHandler struct {
+	service  *services.WorkspaceService
+	validate *validator.Validate
+}
+
+func NewWorkspaceHandler(service *services.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		service:  service,
+		validate: validator.New(),
+	}
+}
+
+// CreateWorkspace godoc
+// @Summary Create a workspace
+// @Description Create a shared todo list; the caller becomes its owner
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Param workspace body models.WorkspaceCreateRequest true "Workspace to create"
+// @Success 201 {object} models.Workspace
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /workspaces [post]
+func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
+	var req models.WorkspaceCreateRequest
+	if !bindJSON(c, h.validate, &req) {
+		return
+	}
+
+	workspace, err := h.service.CreateWorkspace(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, http.StatusInternalServerError, "Failed to create workspace", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, workspace)
+}
+
+// ListWorkspaces godoc
+// @Summary List workspaces
+// @Description Get the workspaces the caller is a member of, with the caller's role
+// @Tags workspaces
+// @Produce json
+// @Success 200 {array} models.Workspace
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /workspaces [get]
+func (h *WorkspaceHandler) ListWorkspaces(c *gin.Context) {
+	workspaces, err := h.service.ListWorkspaces(c.Request.Context())
+	if err != nil {
+		handleError(c, http.StatusInternalServerError, "Failed to get workspaces", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+}
+
+// ListMembers godoc
+// @Summary List workspace members
+// @Description Get the members of a workspace and their roles; any member may list them
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {array} models.WorkspaceMember
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /workspaces/{workspace_id}/members [get]
+func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
+	members, err := h.service.ListMembers(c.Request.Context(), c.Param("workspace_id"))
+	if err != nil {
+		handleServiceError(c, "Failed to get workspace members", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// CreateInvite godoc
+// @Summary Invite to a workspace
+// @Description Create a one-time invite with a role (owner only). The token is returned only in this response and expires in 7 days
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param invite body models.InviteCreateRequest true "Role of the invitee"
+// @Success 201 {object} models.WorkspaceInvite
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /workspaces/{workspace_id}/invites [post]
+func (h *WorkspaceHandler) CreateInvite(c *gin.Context) {
+	var req models.InviteCreateRequest
+	if !bindJSON(c, h.validate, &req) {
+		return
+	}
+
+	invite, err := h.service.CreateInvite(c.Request.Context(), c.Param("workspace_id"), req)
+	if err != nil {
+		handleServiceError(c, "Failed to create invite", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invite)
+}
+
+// AcceptInvite godoc
+// @Summary Accept a workspace invite
+// @Description Join the workspace of the invite with its role. A member who accepts another invite keeps their role
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Param invite body models.InviteAcceptRequest true "Invite token"
+// @Success 200 {object} models.WorkspaceInvite
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /invites/accept [post]
+func (h *WorkspaceHandler) AcceptInvite(c *gin.Context) {
+	var req models.InviteAcceptRequest
+	if !bindJSON(c, h.validate, &req) {
+		return
+	}
+
+	invite, err := h.service.AcceptInvite(c.Request.Context(), req.Token)
+	if errors.Is(err, services.ErrInvalidInvite) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		handleError(c, http.StatusInternalServerError, "Failed to accept invite", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invite)
+}
+
+// UpdateMember godoc
+// @Summary Change a member's role
+// @Description Change the role of a workspace member (owner only). The last owner cannot be demoted
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param user_id path string true "User ID"
+// @Param member body models.MemberUpdateRequest true "New role"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /workspaces/{workspace_id}/members/{user_id} [put]
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	var req models.MemberUpdateRequest
+	if !bindJSON(c, h.validate, &req) {
+		return
+	}
+
+	err := h.service.UpdateMemberRole(c.Request.Context(), c.Param("workspace_id"), c.Param("user_id"), req)
+	if !handleMemberError(c, "Failed to update member", err) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveMember godoc
+// @Summary Remove a member
+// @Description Remove a member from the workspace (owner only); any member may remove themselves. The last owner cannot be removed
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param user_id path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /workspaces/{workspace_id}/members/{user_id} [delete]
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	err := h.service.RemoveMember(c.Request.Context(), c.Param("workspace_id"), c.Param("user_id"))
+	if !handleMemberError(c, "Failed to remove member", err) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleMemberError writes the response for a failed member change and reports whether err was nil
+func handleMemberError(c *gin.Context, message string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Member not found"})
+	case errors.Is(err, models.ErrLastOwner):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		handleServiceError(c, message, err)
+	}
+	return false
+}
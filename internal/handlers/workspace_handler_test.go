@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/middleware"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/policy"
+	"todo_app_go/internal/requestctx"
+	"todo_app_go/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserHeader = "X-Test-User"
+
+func newWorkspaceRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	require.NoError(t, database.EnsureTodosTableAndColumn(db))
+	require.NoError(t, database.EnsureWorkspaceTables(db))
+
+	workspaces := models.NewSQLiteWorkspaceRepository(db)
+	p := policy.New()
+	todoHandler := NewTodoHandler(services.NewTodoService(models.NewSQLiteTodoRepository(db), nil, nil).WithWorkspaces(workspaces, p))
+	h := NewWorkspaceHandler(services.NewWorkspaceService(workspaces, p))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// Вместо middleware.Auth: пользователь берётся из тестового заголовка
+	r.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(requestctx.WithUserID(c.Request.Context(), c.GetHeader(testUserHeader)))
+		c.Next()
+	})
+	r.GET("/todos", todoHandler.GetAllTodos)
+	r.POST("/todos", todoHandler.CreateTodo)
+	r.GET("/workspaces", h.ListWorkspaces)
+	r.POST("/workspaces", h.CreateWorkspace)
+	r.POST("/invites/accept", h.AcceptInvite)
+	ws := r.Group("/workspaces/:workspace_id", middleware.Workspace())
+	ws.GET("/todos", todoHandler.GetAllTodos)
+	ws.POST("/todos", todoHandler.CreateTodo)
+	ws.DELETE("/todos/:id", todoHandler.DeleteTodo)
+	ws.GET("/members", h.ListMembers)
+	ws.POST("/invites", h.CreateInvite)
+	ws.PUT("/members/:user_id", h.UpdateMember)
+	ws.DELETE("/members/:user_id", h.RemoveMember)
+	return r
+}
+
+func doAs(r *gin.Engine, user, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(testUserHeader, user)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestWorkspaceHandler_RolesAndInvites(t *testing.T) {
+	r := newWorkspaceRouter(t)
+
+	w := doAs(r, "alice", "POST", "/workspaces", `{"name": "Team"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var workspace models.Workspace
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &workspace))
+	assert.Equal(t, "owner", workspace.Role)
+	base := "/workspaces/" + workspace.ID
+
+	w = doAs(r, "alice", "POST", base+"/todos", `{"task": "Shared task"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var todo models.Todo
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &todo))
+
+	// Личный список не смешивается со списком пространства
+	w = doAs(r, "alice", "GET", "/todos", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "Shared task")
+
+	// Не участник не видит пространство
+	w = doAs(r, "bob", "GET", base+"/todos", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Только владелец приглашает
+	w = doAs(r, "alice", "POST", base+"/invites", `{"role": "viewer"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var invite models.WorkspaceInvite
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &invite))
+	require.NotEmpty(t, invite.Token)
+
+	w = doAs(r, "bob", "POST", "/invites/accept", `{"token": "`+invite.Token+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = doAs(r, "carol", "POST", "/invites/accept", `{"token": "`+invite.Token+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Viewer читает, но не пишет и не приглашает
+	w = doAs(r, "bob", "GET", base+"/todos", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Shared task")
+	w = doAs(r, "bob", "POST", base+"/todos", `{"task": "Nope"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doAs(r, "bob", "POST", base+"/invites", `{"role": "owner"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doAs(r, "bob", "PUT", base+"/members/bob", `{"role": "owner"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Editor меняет todo пространства
+	w = doAs(r, "alice", "PUT", base+"/members/bob", `{"role": "editor"}`)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	w = doAs(r, "bob", "DELETE", base+"/todos/"+strconv.FormatInt(todo.ID, 10), "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doAs(r, "bob", "GET", "/workspaces", "")
+	assert.Contains(t, w.Body.String(), `"role":"editor"`)
+	w = doAs(r, "bob", "GET", base+"/members", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"user_id":"alice"`)
+
+	// Последний владелец не может понизить себя или уйти; участник может уйти сам
+	w = doAs(r, "alice", "PUT", base+"/members/alice", `{"role": "viewer"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doAs(r, "alice", "DELETE", base+"/members/alice", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doAs(r, "alice", "PUT", base+"/members/carol", `{"role": "viewer"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doAs(r, "bob", "DELETE", base+"/members/bob", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doAs(r, "bob", "GET", base+"/todos", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
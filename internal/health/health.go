@@ -0,0 +1,188 @@
+// Package health runs dependency checks for the liveness and readiness endpoints.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"todo_app_go/internal/metrics"
+)
+
+// Статусы компонента
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Общий статус сервиса
+const (
+	StatusHealthy      = "healthy"
+	StatusDegraded     = "degraded"
+	StatusUnhealthy    = "unhealthy"
+	StatusShuttingDown = "shutting_down"
+)
+
+// ErrTimeout is reported for a check that did not finish within its timeout
+var ErrTimeout = errors.New("health check timed out")
+
+// CheckFunc probes a dependency and returns an error if it is unavailable
+type CheckFunc func(ctx context.Context) error
+
+// Checker describes a registered dependency check
+type Checker struct {
+	Name  string
+	Check CheckFunc
+	// Timeout ограничивает проверку; 0 — таймаут реестра по умолчанию
+	Timeout time.Duration
+	// Critical — при отказе сервис не готов принимать трафик,
+	// отказ некритичной зависимости только переводит его в degraded
+	Critical bool
+}
+
+// ComponentStatus is the result of a single check
+type ComponentStatus struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the aggregated result of all checks
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+// Registry holds the checkers of a process
+type Registry struct {
+	mu             sync.RWMutex
+	checkers       []Checker
+	defaultTimeout time.Duration
+	shuttingDown   atomic.Bool
+}
+
+func NewRegistry(defaultTimeout time.Duration) *Registry {
+	return &Registry{defaultTimeout: defaultTimeout}
+}
+
+// Register adds a checker
+func (r *Registry) Register(checker Checker) {
+	if checker.Timeout <= 0 {
+		checker.Timeout = r.defaultTimeout
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers = append(r.checkers, checker)
+}
+
+// SetShuttingDown makes the service unready, so that load balancers stop sending
+// new requests before the server starts draining
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether SetShuttingDown was called
+func (r *Registry) ShuttingDown() bool {
+	return r.shuttingDown.Load()
+}
+
+// Check runs all checkers concurrently
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checkers := append([]Checker(nil), r.checkers...)
+	r.mu.RUnlock()
+
+	results := make([]ComponentStatus, len(checkers))
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			results[i] = run(ctx, checker)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusHealthy, Components: make(map[string]ComponentStatus, len(checkers))}
+	for i, checker := range checkers {
+		result := results[i]
+		report.Components[checker.Name] = result
+
+		up := 0.0
+		if result.Status == StatusUp {
+			up = 1
+		}
+		metrics.HealthCheckStatus.WithLabelValues(checker.Name).Set(up)
+
+		switch {
+		case result.Status == StatusUp:
+		case checker.Critical:
+			report.Status = StatusUnhealthy
+		case report.Status == StatusHealthy:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// Ready runs the checks and reports whether the service can accept traffic:
+// it is not shutting down and every critical dependency is up
+func (r *Registry) Ready(ctx context.Context) (Report, bool) {
+	if r.ShuttingDown() {
+		return Report{Status: StatusShuttingDown}, false
+	}
+	report := r.Check(ctx)
+	return report, report.Status != StatusUnhealthy
+}
+
+// ReadyStatus returns the status reported by readiness endpoints: ready, degraded or not ready
+func ReadyStatus(report Report, ready bool) string {
+	switch {
+	case !ready:
+		return "not ready"
+	case report.Status == StatusDegraded:
+		return "degraded"
+	default:
+		return "ready"
+	}
+}
+
+// run executes one check; a check that ignores its context is abandoned after the timeout
+func run(ctx context.Context, checker Checker) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, checker.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- checker.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ErrTimeout
+	}
+
+	status := ComponentStatus{
+		Status:    StatusUp,
+		Critical:  checker.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// DBCheck runs a query that needs a connection and a read lock on the schema
+func DBCheck(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		var n int
+		return db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&n)
+	}
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func up(ctx context.Context) error { return nil }
+
+func down(ctx context.Context) error { return errors.New("connection refused") }
+
+func TestRegistry_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		checkers []Checker
+		status   string
+		ready    bool
+	}{
+		{
+			name:     "all up",
+			checkers: []Checker{{Name: "database", Check: up, Critical: true}, {Name: "cache", Check: up}},
+			status:   StatusHealthy,
+			ready:    true,
+		},
+		{
+			name:     "optional dependency down",
+			checkers: []Checker{{Name: "database", Check: up, Critical: true}, {Name: "cache", Check: down}},
+			status:   StatusDegraded,
+			ready:    true,
+		},
+		{
+			name:     "critical dependency down",
+			checkers: []Checker{{Name: "database", Check: down, Critical: true}, {Name: "cache", Check: down}},
+			status:   StatusUnhealthy,
+			ready:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry(time.Second)
+			for _, checker := range tt.checkers {
+				registry.Register(checker)
+			}
+
+			report, ready := registry.Ready(context.Background())
+			assert.Equal(t, tt.status, report.Status)
+			assert.Equal(t, tt.ready, ready)
+			assert.Len(t, report.Components, len(tt.checkers))
+		})
+	}
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	registry := NewRegistry(20 * time.Millisecond)
+	// Проверка, которая игнорирует контекст, не должна задерживать ответ
+	registry.Register(Checker{Name: "stuck", Critical: true, Check: func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}})
+
+	start := time.Now()
+	report := registry.Check(context.Background())
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, StatusUnhealthy, report.Status)
+	assert.Equal(t, StatusDown, report.Components["stuck"].Status)
+	assert.Equal(t, ErrTimeout.Error(), report.Components["stuck"].Error)
+}
+
+func TestRegistry_ShuttingDown(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register(Checker{Name: "database", Check: up, Critical: true})
+
+	_, ready := registry.Ready(context.Background())
+	assert.True(t, ready)
+
+	registry.SetShuttingDown()
+	report, ready := registry.Ready(context.Background())
+	assert.False(t, ready)
+	assert.Equal(t, StatusShuttingDown, report.Status)
+	assert.Equal(t, "not ready", ReadyStatus(report, ready))
+}
@@ -1,27 +1,30 @@
 package logger
 
 import (
-	"os"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
 
+	"todo_app_go/internal/config"
+
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var log *zap.Logger
-
-func Init(level string, format string) error {
-	var zapLevel zapcore.Level
-	switch level {
-	case "debug":
-		zapLevel = zapcore.DebugLevel
-	case "info":
-		zapLevel = zapcore.InfoLevel
-	case "warn":
-		zapLevel = zapcore.WarnLevel
-	case "error":
-		zapLevel = zapcore.ErrorLevel
-	default:
-		zapLevel = zapcore.InfoLevel
+var (
+	log *zap.Logger
+	// level — текущий уровень всех приёмников, меняется через LevelHandler
+	level = zap.NewAtomicLevel()
+)
+
+// Init builds the global logger from cfg: the configured outputs, sampling of debug and
+// info messages and redaction of sensitive fields. An unknown level, format or output is an error.
+func Init(cfg config.LogConfig) error {
+	lvl, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q", cfg.Level)
 	}
 
 	encoderConfig := zap.NewProductionEncoderConfig()
@@ -30,26 +33,120 @@ func Init(level string, format string) error {
 	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
 
 	var encoder zapcore.Encoder
-	if format == "json" {
+	switch cfg.Format {
+	case "json":
 		encoder = zapcore.NewJSONEncoder(encoderConfig)
-	} else {
+	case "text", "console", "":
 		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return fmt.Errorf("unknown log format %q", cfg.Format)
 	}
 
-	core := zapcore.NewCore(
-		encoder,
-		zapcore.AddSync(os.Stdout),
-		zapLevel,
-	)
+	sink, err := newSink(cfg)
+	if err != nil {
+		return err
+	}
 
-	log = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
+	level.SetLevel(lvl)
+	log = zap.New(newCore(cfg, encoder, sink), zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
 	return nil
 }
 
+// newCore combines the encoder and sink with the shared level, redaction and sampling
+func newCore(cfg config.LogConfig, encoder zapcore.Encoder, sink zapcore.WriteSyncer) zapcore.Core {
+	if !cfg.Sampling.Enabled {
+		return newRedactCore(zapcore.NewCore(encoder, sink, level), cfg.Redact)
+	}
+
+	// Сэмплируются только debug и info: предупреждения и ошибки пишутся всегда
+	low := zap.LevelEnablerFunc(func(l zapcore.Level) bool { return l < zapcore.WarnLevel && level.Enabled(l) })
+	high := zap.LevelEnablerFunc(func(l zapcore.Level) bool { return l >= zapcore.WarnLevel && level.Enabled(l) })
+
+	return zapcore.NewTee(
+		zapcore.NewSamplerWithOptions(newRedactCore(zapcore.NewCore(encoder, sink, low), cfg.Redact),
+			time.Second, cfg.Sampling.Initial, cfg.Sampling.Thereafter),
+		newRedactCore(zapcore.NewCore(encoder.Clone(), sink, high), cfg.Redact),
+	)
+}
+
+// LevelHandler returns the current level on GET and changes it at runtime on PUT
+// ({"level":"debug"}); an invalid level is rejected with 400
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		before := level.Level()
+		level.ServeHTTP(w, r)
+		if after := level.Level(); after != before {
+			log.Warn("Log level changed", zap.Stringer("from", before), zap.Stringer("to", after))
+		}
+	})
+}
+
 func Get() *zap.Logger {
 	return log
 }
 
+// Set replaces the global logger; used by tests to capture log entries
+func Set(l *zap.Logger) {
+	log = l
+}
+
+type fieldsKey struct{}
+
+// WithFields returns ctx carrying fields for FromContext. A field replaces one with the same
+// key added earlier, so re-attaching the request ID of an event does not duplicate it.
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	existing := ContextFields(ctx)
+	merged := make([]zap.Field, 0, len(existing)+len(fields))
+	for _, f := range existing {
+		if !hasKey(fields, f.Key) {
+			merged = append(merged, f)
+		}
+	}
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// ContextFields returns the fields attached to ctx by WithFields
+func ContextFields(ctx context.Context) []zap.Field {
+	fields, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	return fields
+}
+
+// FromContext returns a child logger with the fields attached to ctx (request_id, user_id,
+// route) and the trace and span IDs of the span in ctx
+func FromContext(ctx context.Context) *zap.Logger {
+	contextFields := ContextFields(ctx)
+	traceFields := TraceFields(ctx)
+	if len(contextFields) == 0 && len(traceFields) == 0 {
+		return log
+	}
+	fields := make([]zap.Field, 0, len(contextFields)+len(traceFields))
+	fields = append(fields, contextFields...)
+	fields = append(fields, traceFields...)
+	return log.With(fields...)
+}
+
+func hasKey(fields []zap.Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// TraceFields returns trace_id and span_id fields for the span in ctx, if any
+func TraceFields(ctx context.Context) []zap.Field {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", spanCtx.TraceID().String()),
+		zap.String("span_id", spanCtx.SpanID().String()),
+	}
+}
+
 func Info(msg string, fields ...zap.Field) {
 	log.Info(msg, fields...)
 }
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo_app_go/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	Set(zap.New(core))
+
+	ctx := WithFields(context.Background(), zap.String("request_id", "req-1"), zap.String("route", "/todos/:id"))
+	// Повторное значение заменяет прежнее, а не дублирует поле
+	ctx = WithFields(ctx, zap.String("request_id", "req-2"), zap.String("user_id", "alice"))
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(ctx, "op")
+	defer span.End()
+
+	FromContext(ctx).Info("hello")
+
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	fields := entry.ContextMap()
+	assert.Len(t, entry.Context, 5)
+	assert.Equal(t, "req-2", fields["request_id"])
+	assert.Equal(t, "alice", fields["user_id"])
+	assert.Equal(t, "/todos/:id", fields["route"])
+	assert.Equal(t, span.SpanContext().TraceID().String(), fields["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), fields["span_id"])
+
+	// Без полей и span'а возвращается глобальный logger
+	assert.Same(t, Get(), FromContext(context.Background()))
+}
+
+func TestInit_InvalidConfig(t *testing.T) {
+	assert.EqualError(t, Init(config.LogConfig{Level: "verbose", Format: "json"}), `invalid log level "verbose"`)
+	assert.Error(t, Init(config.LogConfig{Level: "info", Format: "xml"}))
+	assert.Error(t, Init(config.LogConfig{Level: "info", Format: "json", Outputs: []string{"kafka"}}))
+}
+
+func TestNewCore_Redact(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := config.LogConfig{Redact: []string{"authorization", "task"}}
+	level.SetLevel(zapcore.InfoLevel)
+	l := zap.New(newCore(cfg, zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf)))
+
+	l.With(zap.String("Authorization", "Bearer secret")).Info("request",
+		zap.String("task", "buy a ring"), zap.Int64("todo_id", 7))
+
+	out := buf.String()
+	assert.NotContains(t, out, "secret")
+	assert.NotContains(t, out, "buy a ring")
+	assert.Contains(t, out, `"Authorization":"[REDACTED]"`)
+	assert.Contains(t, out, `"task":"[REDACTED]"`)
+	assert.Contains(t, out, `"todo_id":7`)
+}
+
+func TestNewCore_Sampling(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := config.LogConfig{Sampling: config.LogSamplingConfig{Enabled: true, Initial: 2, Thereafter: 100}}
+	level.SetLevel(zapcore.InfoLevel)
+	l := zap.New(newCore(cfg, zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf)))
+
+	for i := 0; i < 10; i++ {
+		l.Info("noisy")
+		l.Warn("important")
+	}
+
+	// Info после первых двух отбрасывается, warn пишется всегда
+	assert.Equal(t, 2, strings.Count(buf.String(), `"noisy"`))
+	assert.Equal(t, 10, strings.Count(buf.String(), `"important"`))
+}
+
+func TestLevelHandler(t *testing.T) {
+	require.NoError(t, Init(config.LogConfig{Level: "info", Format: "json"}))
+	handler := LevelHandler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"debug"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, Get().Core().Enabled(zapcore.DebugLevel))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"loud"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/log/level", nil))
+	assert.JSONEq(t, `{"level":"debug"}`, w.Body.String())
+}
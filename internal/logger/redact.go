@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted заменяет значения чувствительных полей
+const Redacted = "[REDACTED]"
+
+// redactCore replaces the values of configured field keys before they reach the encoder,
+// both for fields passed to a log call and for those added with Logger.With
+type redactCore struct {
+	zapcore.Core
+	keys map[string]struct{}
+}
+
+func newRedactCore(core zapcore.Core, keys []string) zapcore.Core {
+	if len(keys) == 0 {
+		return core
+	}
+	set := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		set[strings.ToLower(k)] = struct{}{}
+	}
+	return &redactCore{Core: core, keys: set}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.redact(fields)), keys: c.keys}
+}
+
+func (c *redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, c.redact(fields))
+}
+
+// redact copies fields only when one of them has to be replaced
+func (c *redactCore) redact(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		if _, ok := c.keys[strings.ToLower(f.Key)]; !ok {
+			continue
+		}
+		if out == nil {
+			out = append([]zapcore.Field(nil), fields...)
+		}
+		out[i] = zap.String(f.Key, Redacted)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
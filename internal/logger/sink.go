@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+
+	"todo_app_go/internal/config"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Приёмники логов (log.outputs)
+const (
+	OutputStdout = "stdout"
+	OutputFile   = "file"
+	OutputSyslog = "syslog"
+)
+
+// newSink opens every configured output; entries are written to all of them
+func newSink(cfg config.LogConfig) (zapcore.WriteSyncer, error) {
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{OutputStdout}
+	}
+
+	var sinks []zapcore.WriteSyncer
+	for _, output := range outputs {
+		switch output {
+		case OutputStdout:
+			sinks = append(sinks, zapcore.Lock(os.Stdout))
+		case OutputFile:
+			// lumberjack ротирует файл по размеру и удаляет старые копии по возрасту и количеству
+			sinks = append(sinks, zapcore.AddSync(&lumberjack.Logger{
+				Filename:   cfg.File.Path,
+				MaxSize:    cfg.File.MaxSizeMB,
+				MaxAge:     cfg.File.MaxAgeDays,
+				MaxBackups: cfg.File.MaxBackups,
+				Compress:   cfg.File.Compress,
+			}))
+		case OutputSyslog:
+			sink, err := newSyslogSink(cfg.Syslog)
+			if err != nil {
+				return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+			}
+			sinks = append(sinks, sink)
+		default:
+			return nil, fmt.Errorf("unknown log output %q", output)
+		}
+	}
+	return zapcore.NewMultiWriteSyncer(sinks...), nil
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package logger
+
+import (
+	"log/syslog"
+
+	"todo_app_go/internal/config"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// newSyslogSink connects to syslog. Уровень записи остаётся в теле сообщения,
+// приоритет syslog у всех записей одинаковый
+func newSyslogSink(cfg config.LogSyslogConfig) (zapcore.WriteSyncer, error) {
+	w, err := syslog.Dial(cfg.Network, cfg.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, cfg.Tag)
+	if err != nil {
+		return nil, err
+	}
+	return zapcore.AddSync(w), nil
+}
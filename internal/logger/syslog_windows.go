@@ -0,0 +1,15 @@
+//go:build windows
+
+package logger
+
+import (
+	"errors"
+
+	"todo_app_go/internal/config"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func newSyslogSink(config.LogSyslogConfig) (zapcore.WriteSyncer, error) {
+	return nil, errors.New("syslog is not supported on windows")
+}
@@ -7,6 +7,7 @@ import (
 
 var (
 	// HTTP метрики
+	// endpoint — шаблон маршрута (/todos/:id), status — класс ответа (2xx, 4xx, ...)
 	HttpRequestsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_requests_total",
@@ -21,6 +22,24 @@ var (
 			Help:    "HTTP request duration in seconds",
 			Buckets: prometheus.DefBuckets,
 		},
+		[]string{"method", "endpoint", "status"},
+	)
+
+	HttpRequestSize = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_size_bytes",
+			Help:    "HTTP request body size in bytes",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		},
+		[]string{"method", "endpoint"},
+	)
+
+	HttpResponseSize = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "HTTP response body size in bytes",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		},
 		[]string{"method", "endpoint"},
 	)
 
@@ -42,6 +61,45 @@ var (
 		[]string{"operation"},
 	)
 
+	// Бизнес-метрики. Счётчики готовы к разбивке по проекту или тегу,
+	// когда они появятся в модели todo
+	TodosOpen = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "todos_open",
+			Help: "Number of todos that are not completed",
+		},
+	)
+
+	TodosCompleted = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "todos_completed",
+			Help: "Number of completed todos",
+		},
+	)
+
+	TodosCreatedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "todos_created_total",
+			Help: "Total number of todos created",
+		},
+	)
+
+	TodosCompletedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "todos_completed_total",
+			Help: "Total number of todos marked as completed",
+		},
+	)
+
+	// Время от создания до выполнения: от минуты до месяца
+	TodoTimeToComplete = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "todo_time_to_complete_seconds",
+			Help:    "Time from todo creation to completion in seconds",
+			Buckets: []float64{60, 300, 900, 3600, 4 * 3600, 12 * 3600, 86400, 3 * 86400, 7 * 86400, 30 * 86400},
+		},
+	)
+
 	// Cache метрики
 	CacheHitsTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
@@ -72,18 +130,197 @@ var (
 		},
 	)
 
+	// Метрики шины событий для драйверов nats и memory
+	EventsPublishedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "events_published_total",
+			Help: "Total number of events published to the event bus",
+		},
+		[]string{"driver"},
+	)
+
+	EventsConsumedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "events_consumed_total",
+			Help: "Total number of events consumed from the event bus",
+		},
+		[]string{"driver", "status"},
+	)
+
+	// Метрики асинхронного publisher
+	EventsQueueDepth = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "events_publish_queue_depth",
+			Help: "Number of events waiting in the async publish queue",
+		},
+	)
+
+	EventsDroppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "events_dropped_total",
+			Help: "Total number of events dropped by the async publisher",
+		},
+		[]string{"reason"},
+	)
+
+	EventsBatchSize = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "events_publish_batch_size",
+			Help:    "Number of events in each published batch",
+			Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500},
+		},
+	)
+
+	KafkaPoisonMessagesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_poison_messages_total",
+			Help: "Total number of Kafka messages that could not be processed",
+		},
+		[]string{"reason"},
+	)
+
+	KafkaMessagesDeadLettered = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_messages_dead_lettered_total",
+			Help: "Total number of Kafka messages written to the DLQ topic",
+		},
+		[]string{"reason"},
+	)
+
+	// Метрики проекций worker
+	ProjectionEventsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "projection_events_total",
+			Help: "Total number of events applied by projection handlers",
+		},
+		[]string{"projection", "status"},
+	)
+
+	ProjectionEventsDeduplicated = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "projection_events_deduplicated_total",
+			Help: "Total number of redelivered events skipped because they were already processed",
+		},
+		[]string{"projection"},
+	)
+
+	ProjectionDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "projection_duration_seconds",
+			Help:    "Projection handler duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"projection"},
+	)
+
+	// Outbox метрики
+	OutboxBacklog = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_backlog",
+			Help: "Number of outbox messages waiting to be published",
+		},
+	)
+
+	OutboxParked = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_parked",
+			Help: "Number of outbox messages that cannot be published and are no longer retried",
+		},
+	)
+
+	OutboxLagSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_lag_seconds",
+			Help: "Age of the oldest unpublished outbox message in seconds",
+		},
+	)
+
+	OutboxMessagesRelayed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_messages_relayed_total",
+			Help: "Total number of outbox relay attempts",
+		},
+		[]string{"status"},
+	)
+
 	// Системные метрики
 	ActiveConnections = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "active_connections",
-			Help: "Number of active connections",
+			Help: "Number of HTTP requests currently being served",
 		},
 	)
 
-	DatabaseConnections = promauto.NewGauge(
+	// Результат последней проверки зависимости: 1 — доступна, 0 — нет
+	HealthCheckStatus = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "health_check_status",
+			Help: "Result of the last health check of a dependency (1 = up, 0 = down)",
+		},
+		[]string{"component"},
+	)
+
+	// Метрики пула соединений (sql.DBStats); state — in_use или idle
+	DatabaseConnections = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "database_connections",
 			Help: "Number of database connections",
 		},
+		[]string{"driver", "state"},
+	)
+
+	DatabaseMaxOpenConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "database_max_open_connections",
+			Help: "Maximum number of open database connections",
+		},
+		[]string{"driver"},
+	)
+
+	DatabaseWaitCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "database_wait_count_total",
+			Help: "Total number of times a query waited for a free database connection",
+		},
+		[]string{"driver"},
+	)
+
+	DatabaseWaitDuration = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "database_wait_duration_seconds_total",
+			Help: "Total time spent waiting for a free database connection",
+		},
+		[]string{"driver"},
+	)
+
+	// Учётные записи: operation — register, login, refresh, logout, password_reset, oidc_login,
+	// apikey_create, apikey_revoke, apikey_verify;
+	// status — success или причина отказа
+	AuthOperationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_operations_total",
+			Help: "Total number of account operations",
+		},
+		[]string{"operation", "status"},
+	)
+
+	// Общие пространства: operation — create, invite, accept, update_role, remove_member;
+	// status — success, forbidden, not_found или другая причина отказа
+	WorkspaceOperationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "workspace_operations_total",
+			Help: "Total number of workspace operations",
+		},
+		[]string{"operation", "status"},
+	)
+
+	// Публичные ссылки и комментарии: operation — create, revoke, view, comment;
+	// status — success, forbidden, not_found, invalid или другая причина отказа
+	ShareOperationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "share_operations_total",
+			Help: "Total number of share link and comment operations",
+		},
+		[]string{"operation", "status"},
 	)
 )
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/requestctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	logger.Set(zap.NewNop())
+	validator, err := auth.NewValidator(config.JWTConfig{Secret: "secret", Audience: "todo-api"})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Auth(validator, nil))
+
+	var userID string
+	r.GET("/todos", func(c *gin.Context) {
+		userID = requestctx.UserID(c.Request.Context())
+		c.String(http.StatusOK, "ok")
+	})
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "alice",
+		Audience:  jwt.ClaimStrings{"todo-api"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	// Внешний издатель может выдать любой sub; ключ пространства пользователем быть не может
+	spoofed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "workspace:team",
+		Audience:  jwt.ClaimStrings{"todo-api"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+		error  string
+	}{
+		{"valid token", "Bearer " + token, http.StatusOK, ""},
+		{"lowercase scheme", "bearer " + token, http.StatusOK, ""},
+		{"no header", "", http.StatusUnauthorized, "missing bearer token"},
+		{"basic auth", "Basic YWxpY2U6c2VjcmV0", http.StatusUnauthorized, "missing bearer token"},
+		{"tampered token", "Bearer " + token + "x", http.StatusUnauthorized, "invalid token"},
+		{"workspace subject", "Bearer " + spoofed, http.StatusUnauthorized, "invalid token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID = ""
+			req, _ := http.NewRequest("GET", "/todos", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, "alice", userID)
+				return
+			}
+			assert.Empty(t, userID)
+			assert.Equal(t, `Bearer realm="api"`, w.Header().Get("WWW-Authenticate"))
+			var body map[string]string
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.error, body["error"])
+		})
+	}
+}
+
+type fakeKeyVerifier map[string][]string
+
+func (f fakeKeyVerifier) VerifyAPIKey(_ context.Context, key string) (string, []string, error) {
+	scopes, ok := f[key]
+	if !ok {
+		return "", nil, errors.New("invalid API key")
+	}
+	return "bot", scopes, nil
+}
+
+func TestAuthMiddleware_APIKeyScopes(t *testing.T) {
+	logger.Set(zap.NewNop())
+	validator, err := auth.NewValidator(config.JWTConfig{Secret: "secret", Audience: "todo-api"})
+	require.NoError(t, err)
+	keys := fakeKeyVerifier{
+		"todo_reader_x": {auth.ScopeTodosRead},
+		"todo_admin_x":  {auth.ScopeAdmin},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Auth(validator, keys))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, requestctx.UserID(c.Request.Context())) }
+	r.GET("/todos", RequireScope(auth.ScopeTodosRead), ok)
+	r.POST("/todos", RequireScope(auth.ScopeTodosWrite), ok)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "alice",
+		Audience:  jwt.ClaimStrings{"todo-api"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		status  int
+		user    string
+	}{
+		{"X-API-Key read", "GET", map[string]string{HeaderAPIKey: "todo_reader_x"}, http.StatusOK, "bot"},
+		{"bearer API key read", "GET", map[string]string{"Authorization": "Bearer todo_reader_x"}, http.StatusOK, "bot"},
+		{"read key cannot write", "POST", map[string]string{HeaderAPIKey: "todo_reader_x"}, http.StatusForbidden, ""},
+		{"admin implies write", "POST", map[string]string{HeaderAPIKey: "todo_admin_x"}, http.StatusOK, "bot"},
+		{"unknown key", "GET", map[string]string{HeaderAPIKey: "todo_unknown"}, http.StatusUnauthorized, ""},
+		{"JWT has all scopes", "POST", map[string]string{"Authorization": "Bearer " + token}, http.StatusOK, "alice"},
+		{"X-API-Key wins over bearer", "POST", map[string]string{HeaderAPIKey: "todo_reader_x", "Authorization": "Bearer " + token}, http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "/todos", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			switch tt.status {
+			case http.StatusOK:
+				assert.Equal(t, tt.user, w.Body.String())
+			case http.StatusForbidden:
+				assert.Equal(t, `Bearer error="insufficient_scope", scope="todos:write"`, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -2,12 +2,25 @@ package middleware
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
 	"time"
 
 	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/requestctx"
+	"todo_app_go/internal/tracing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -16,7 +29,7 @@ func CORSGin() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, "+HeaderAPIKey+", X-Request-ID, traceparent")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(200)
@@ -30,23 +43,122 @@ func CORSGin() gin.HandlerFunc {
 // RequestID middleware adds a unique request ID to each request
 func RequestID() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
-		requestID := c.GetHeader("X-Request-ID")
+		requestID := c.GetHeader(requestctx.HeaderRequestID)
 		if requestID == "" {
 			requestID = uuid.New().String()
 		}
-		c.Header("X-Request-ID", requestID)
+		c.Header(requestctx.HeaderRequestID, requestID)
 		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(requestctx.WithRequestID(c.Request.Context(), requestID))
 		c.Next()
 	})
 }
 
+// Tracing middleware starts a server span for each request, continuing the W3C trace
+// from the incoming traceparent header, and stores the span's traceparent in the
+// context so that published events join the same trace
+func Tracing() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", requestPath(c)),
+				attribute.String("client.address", c.ClientIP()),
+			))
+		defer span.End()
+
+		ctx = requestctx.WithTraceParent(ctx, tracing.TraceParent(ctx))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// Actor middleware stores the caller identity passed in the X-Actor header by a gateway
+// from trustedProxies (IP addresses or CIDRs); the header is ignored from other clients.
+// The actor of authenticated requests is the user set by the auth middleware.
+func Actor(trustedProxies []string) (gin.HandlerFunc, error) {
+	prefixes := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if actor := c.GetHeader(requestctx.HeaderActor); actor != "" && trustedProxy(prefixes, c.RemoteIP()) {
+			c.Request = c.Request.WithContext(requestctx.WithActor(c.Request.Context(), actor))
+		}
+		c.Next()
+	}), nil
+}
+
+func trustedProxy(prefixes []netip.Prefix, remoteIP string) bool {
+	addr, err := netip.ParseAddr(remoteIP)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Workspace middleware stores the :workspace_id route parameter in the request context,
+// so that todo routes nested under /workspaces/:workspace_id act on the workspace's todos
+func Workspace() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if workspaceID := c.Param("workspace_id"); workspaceID != "" {
+			c.Request = c.Request.WithContext(requestctx.WithWorkspaceID(c.Request.Context(), workspaceID))
+		}
+		c.Next()
+	})
+}
+
+// requestPath returns the request path with the :token route parameter redacted, so that
+// share links do not end up in logs and traces
+func requestPath(c *gin.Context) string {
+	if token := c.Param("token"); token != "" {
+		return strings.Replace(c.Request.URL.Path, token, logger.Redacted, 1)
+	}
+	return c.Request.URL.Path
+}
+
 // Logger middleware for structured logging
 func Logger() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		start := time.Now()
-		path := c.Request.URL.Path
+		path := requestPath(c)
 		raw := c.Request.URL.RawQuery
 
+		// Шаблон маршрута попадает во все логи запроса через logger.FromContext
+		if route := c.FullPath(); route != "" {
+			c.Request = c.Request.WithContext(logger.WithFields(c.Request.Context(), zap.String("route", route)))
+		}
+
 		// Process request
 		c.Next()
 
@@ -55,23 +167,61 @@ func Logger() gin.HandlerFunc {
 		status := c.Writer.Status()
 		clientIP := c.ClientIP()
 		method := c.Request.Method
-		requestID := c.GetString("request_id")
 
 		if raw != "" {
 			path = path + "?" + raw
 		}
 
-		logger.Info("HTTP Request",
+		logger.FromContext(c.Request.Context()).Info("HTTP Request",
 			zap.String("method", method),
 			zap.String("path", path),
 			zap.Int("status", status),
 			zap.Duration("latency", latency),
 			zap.String("client_ip", clientIP),
-			zap.String("request_id", requestID),
 		)
 	})
 }
 
+// Metrics middleware records request count, latency and body sizes by route template,
+// method and status class, and tracks the number of in-flight requests
+func Metrics() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		start := time.Now()
+		metrics.ActiveConnections.Inc()
+		defer metrics.ActiveConnections.Dec()
+
+		c.Next()
+
+		// Шаблон маршрута вместо пути, чтобы id не раздували число рядов
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			endpoint = "unmatched"
+		}
+		method := c.Request.Method
+		status := statusClass(c.Writer.Status())
+
+		metrics.HttpRequestsTotal.WithLabelValues(method, endpoint, status).Inc()
+		metrics.HttpRequestDuration.WithLabelValues(method, endpoint, status).Observe(time.Since(start).Seconds())
+
+		requestSize := c.Request.ContentLength
+		if requestSize < 0 {
+			requestSize = 0
+		}
+		metrics.HttpRequestSize.WithLabelValues(method, endpoint).Observe(float64(requestSize))
+
+		// Size() равен -1, если тело ответа не записывалось
+		responseSize := c.Writer.Size()
+		if responseSize < 0 {
+			responseSize = 0
+		}
+		metrics.HttpResponseSize.WithLabelValues(method, endpoint).Observe(float64(responseSize))
+	})
+}
+
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
 // Timeout middleware adds timeout to requests
 func Timeout(timeout time.Duration) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
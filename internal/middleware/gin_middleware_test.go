@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/requestctx"
+	"todo_app_go/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestContextMiddleware(t *testing.T) {
+	var spans bytes.Buffer
+	provider := tracing.NewTestProvider(&spans)
+	defer provider.Shutdown(context.Background())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID(), Tracing(), trustedActor(t))
+
+	var requestID, traceParent, actor string
+	r.GET("/test", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		requestID = requestctx.RequestID(ctx)
+		traceParent = requestctx.TraceParent(ctx)
+		actor = requestctx.Actor(ctx)
+		c.String(http.StatusOK, "ok")
+	})
+
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	req.Header.Set("traceparent", incoming)
+	req.Header.Set("X-Actor", "alice")
+	req.RemoteAddr = "10.0.0.5:41000"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-42", requestID)
+	assert.Equal(t, "alice", actor)
+	// Trace продолжается: тот же trace-id, новый parent-id
+	assert.True(t, strings.HasPrefix(traceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-"))
+	assert.NotEqual(t, incoming, traceParent)
+	// Серверный span назван по шаблону маршрута
+	assert.Contains(t, spans.String(), `"Name":"GET /test"`)
+
+	// Без заголовков начинается новый trace
+	req, _ = http.NewRequest("GET", "/test", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.NotEmpty(t, requestID)
+	assert.Empty(t, actor)
+	_, _, ok := requestctx.ParseTraceParent(traceParent)
+	assert.True(t, ok)
+
+	// X-Actor от клиента не из доверенной сети игнорируется
+	req, _ = http.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Actor", "mallory")
+	req.RemoteAddr = "203.0.113.7:41000"
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Empty(t, actor)
+}
+
+func trustedActor(t *testing.T) gin.HandlerFunc {
+	t.Helper()
+	actor, err := Actor([]string{"10.0.0.0/8", "::1"})
+	require.NoError(t, err)
+	return actor
+}
+
+func TestContextLogger(t *testing.T) {
+	var spans bytes.Buffer
+	provider := tracing.NewTestProvider(&spans)
+	defer provider.Shutdown(context.Background())
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger.Set(zap.New(core))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID(), Tracing(), trustedActor(t), Logger())
+	r.GET("/todos/:id", func(c *gin.Context) {
+		logger.FromContext(c.Request.Context()).Info("Handling todo")
+		c.String(http.StatusOK, "ok")
+	})
+
+	req, _ := http.NewRequest("GET", "/todos/7", nil)
+	req.Header.Set("X-Request-ID", "req-7")
+	req.Header.Set("X-Actor", "alice")
+	req.RemoteAddr = "10.0.0.5:41000"
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Лог обработчика и итоговый лог запроса несут одинаковую корреляцию
+	require.Equal(t, 2, logs.Len())
+	for _, entry := range logs.All() {
+		fields := entry.ContextMap()
+		assert.Equal(t, "req-7", fields["request_id"], entry.Message)
+		assert.Equal(t, "alice", fields["actor"], entry.Message)
+		assert.Equal(t, "/todos/:id", fields["route"], entry.Message)
+		assert.NotEmpty(t, fields["trace_id"], entry.Message)
+	}
+}
+
+func TestLogger_RedactsShareToken(t *testing.T) {
+	var spans bytes.Buffer
+	provider := tracing.NewTestProvider(&spans)
+	defer provider.Shutdown(context.Background())
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger.Set(zap.New(core))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Tracing(), Logger())
+	r.GET("/shared/:token", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	req, _ := http.NewRequest("GET", "/shared/s3cr3t-token", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, "/shared/"+logger.Redacted, logs.All()[0].ContextMap()["path"])
+	assert.Contains(t, spans.String(), `"Name":"GET /shared/:token"`)
+	assert.NotContains(t, spans.String(), "s3cr3t-token")
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Metrics())
+
+	var inFlight float64
+	r.POST("/items/:id", func(c *gin.Context) {
+		inFlight = testutil.ToFloat64(metrics.ActiveConnections)
+		c.String(http.StatusCreated, "created")
+	})
+
+	created := testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues("POST", "/items/:id", "2xx"))
+	unmatched := testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues("GET", "unmatched", "4xx"))
+
+	for _, id := range []string{"1", "2"} {
+		req, _ := http.NewRequest("POST", "/items/"+id, strings.NewReader(`{"task":"x"}`))
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	req, _ := http.NewRequest("GET", "/missing", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Разные id попадают в один ряд шаблона маршрута
+	assert.Equal(t, created+2, testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues("POST", "/items/:id", "2xx")))
+	assert.Equal(t, unmatched+1, testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues("GET", "unmatched", "4xx")))
+	assert.Equal(t, float64(1), inFlight)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.ActiveConnections))
+	assert.GreaterOrEqual(t, testutil.CollectAndCount(metrics.HttpRequestSize), 2)
+	assert.GreaterOrEqual(t, testutil.CollectAndCount(metrics.HttpResponseSize), 2)
+}
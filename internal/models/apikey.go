@@ -0,0 +1,151 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"todo_app_go/internal/tracing"
+)
+
+// APIKey is a long-lived credential for machine clients; only the hash of the key is kept
+type APIKey struct {
+	ID     string `json:"id"`
+	UserID string `json:"-"`
+	Name   string `json:"name"`
+	// Public part of the key, e.g. todo_1a2b3c4d, to tell keys apart
+	Prefix  string   `json:"prefix"`
+	KeyHash string   `json:"-"`
+	Scopes  []string `json:"scopes"`
+	// Full key; returned only once, in the response to creation
+	Key        string     `json:"key,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyCreateRequest represents a request to mint an API key
+type APIKeyCreateRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=todos:read todos:write admin"`
+	// Optional expiry; the key never expires if omitted
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyRepository stores API keys
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	// GetAPIKeyByHash returns the key with the hash, including revoked and expired keys, or nil
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error)
+	// RevokeAPIKey revokes the user's key and reports whether an active key was found
+	RevokeAPIKey(ctx context.Context, userID, id string) (bool, error)
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// SQLiteAPIKeyRepository implements APIKeyRepository using SQLite
+type SQLiteAPIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteAPIKeyRepository creates a new SQLite API key repository
+func NewSQLiteAPIKeyRepository(db *sql.DB) *SQLiteAPIKeyRepository {
+	return &SQLiteAPIKeyRepository{db: db}
+}
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at, revoked_at"
+
+// CreateAPIKey inserts the key
+func (r *SQLiteAPIKeyRepository) CreateAPIKey(ctx context.Context, key *APIKey) (err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "api_keys")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, strings.Join(key.Scopes, " "), key.ExpiresAt, key.CreatedAt)
+	return err
+}
+
+// GetAPIKeyByHash returns the key with the hash or nil
+func (r *SQLiteAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (_ *APIKey, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "api_keys")
+	defer func() { tracing.End(span, err) }()
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?", hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+// ListAPIKeys returns the user's keys, newest first
+func (r *SQLiteAPIKeyRepository) ListAPIKeys(ctx context.Context, userID string) (_ []APIKey, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "api_keys")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = ? ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey marks the key as revoked
+func (r *SQLiteAPIKeyRepository) RevokeAPIKey(ctx context.Context, userID, id string) (_ bool, err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "api_keys")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := r.db.ExecContext(ctx, "UPDATE api_keys SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL", time.Now(), id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// TouchAPIKey records the time the key was last used
+func (r *SQLiteAPIKeyRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) (err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "api_keys")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = ? WHERE id = ?", usedAt, id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var key APIKey
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes,
+		&expiresAt, &lastUsedAt, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	key.Scopes = strings.Fields(scopes)
+	key.ExpiresAt = nullTimePtr(expiresAt)
+	key.LastUsedAt = nullTimePtr(lastUsedAt)
+	key.RevokedAt = nullTimePtr(revokedAt)
+	return &key, nil
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
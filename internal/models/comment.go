@@ -0,0 +1,95 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"todo_app_go/internal/tracing"
+)
+
+// Comment is a note on a todo left by a member or by the holder of a comment share link
+type Comment struct {
+	ID     int64 `json:"id"`
+	TodoID int64 `json:"todo_id"`
+	// OwnerID is the owner key of the todo, the same as Todo.OwnerID
+	OwnerID string `json:"-"`
+	// Author is the user ID of a member or the name given by the holder of a share link
+	Author string `json:"author"`
+	// ShareID is set for comments left through a share link
+	ShareID   string    `json:"share_id,omitempty"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CommentCreateRequest represents a member's comment on a todo
+type CommentCreateRequest struct {
+	Body string `json:"body" validate:"required,min=1,max=2000"`
+}
+
+// SharedCommentCreateRequest represents a comment left through a share link
+type SharedCommentCreateRequest struct {
+	// Todo to comment on; may be omitted when a single todo is shared
+	TodoID int64  `json:"todo_id,omitempty" validate:"omitempty,min=1"`
+	Author string `json:"author" validate:"required,max=100"`
+	Body   string `json:"body" validate:"required,min=1,max=2000"`
+}
+
+// CommentRepository stores comments on todos
+type CommentRepository interface {
+	CreateComment(ctx context.Context, comment *Comment) error
+	// ListComments returns the comments on the owner's todo, or on all the owner's todos if todoID is nil
+	ListComments(ctx context.Context, ownerID string, todoID *int64) ([]Comment, error)
+}
+
+// SQLiteCommentRepository implements CommentRepository using SQLite
+type SQLiteCommentRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteCommentRepository creates a new SQLite comment repository
+func NewSQLiteCommentRepository(db *sql.DB) *SQLiteCommentRepository {
+	return &SQLiteCommentRepository{db: db}
+}
+
+// CreateComment inserts the comment and sets its ID
+func (r *SQLiteCommentRepository) CreateComment(ctx context.Context, comment *Comment) (err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "todo_comments")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := r.db.ExecContext(ctx, "INSERT INTO todo_comments (todo_id, owner_id, author, share_id, body, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		comment.TodoID, comment.OwnerID, comment.Author, comment.ShareID, comment.Body, comment.CreatedAt)
+	if err != nil {
+		return err
+	}
+	comment.ID, err = result.LastInsertId()
+	return err
+}
+
+// ListComments returns the comments, oldest first
+func (r *SQLiteCommentRepository) ListComments(ctx context.Context, ownerID string, todoID *int64) (_ []Comment, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "todo_comments")
+	defer func() { tracing.End(span, err) }()
+
+	query := "SELECT id, todo_id, owner_id, author, share_id, body, created_at FROM todo_comments WHERE owner_id = ?"
+	args := []interface{}{ownerID}
+	if todoID != nil {
+		query += " AND todo_id = ?"
+		args = append(args, *todoID)
+	}
+	rows, err := r.db.QueryContext(ctx, query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.ID, &c.TodoID, &c.OwnerID, &c.Author, &c.ShareID, &c.Body, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"todo_app_go/internal/tracing"
+)
+
+// OIDCLogin is a started OIDC login waiting for the provider callback. The state is
+// stored as a hash; the nonce and the PKCE verifier never leave the server.
+type OIDCLogin struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// UserIdentity links an account of an OIDC provider (issuer and subject) to a local user
+type UserIdentity struct {
+	Issuer    string
+	Subject   string
+	UserID    string
+	Email     string
+	CreatedAt time.Time
+}
+
+// IdentityRepository stores OIDC login state and provider identities of local users
+type IdentityRepository interface {
+	CreateOIDCLogin(ctx context.Context, login *OIDCLogin) error
+	// ConsumeOIDCLogin deletes the login with the state hash and returns it,
+	// or nil if it does not exist or has expired
+	ConsumeOIDCLogin(ctx context.Context, stateHash string) (*OIDCLogin, error)
+
+	// GetUserByIdentity returns the user linked to the provider account or nil
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (*User, error)
+	// CreateIdentity links the provider account to an existing user
+	CreateIdentity(ctx context.Context, identity *UserIdentity) error
+	// CreateUserWithIdentity creates a user without a password and links the provider account
+	CreateUserWithIdentity(ctx context.Context, user *User, identity *UserIdentity) error
+}
+
+// CreateOIDCLogin stores a started login
+func (r *SQLiteUserRepository) CreateOIDCLogin(ctx context.Context, login *OIDCLogin) (err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "oidc_logins")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO oidc_logins (state_hash, nonce, code_verifier, expires_at, created_at) VALUES (?, ?, ?, ?, ?)",
+		login.StateHash, login.Nonce, login.CodeVerifier, login.ExpiresAt, login.CreatedAt)
+	return err
+}
+
+// ConsumeOIDCLogin deletes the login so that a state can be used only once
+func (r *SQLiteUserRepository) ConsumeOIDCLogin(ctx context.Context, stateHash string) (_ *OIDCLogin, err error) {
+	ctx, span := StartDBSpan(ctx, "DELETE", "oidc_logins")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var login OIDCLogin
+	err = tx.QueryRowContext(ctx, "SELECT state_hash, nonce, code_verifier, expires_at, created_at FROM oidc_logins WHERE state_hash = ?", stateHash).
+		Scan(&login.StateHash, &login.Nonce, &login.CodeVerifier, &login.ExpiresAt, &login.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Заодно удаляются брошенные входы, до которых не дошёл callback
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM oidc_logins WHERE state_hash = ? OR expires_at <= ?", stateHash, now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if !now.Before(login.ExpiresAt) {
+		return nil, nil
+	}
+	return &login, nil
+}
+
+// GetUserByIdentity returns the user linked to the issuer and subject or nil
+func (r *SQLiteUserRepository) GetUserByIdentity(ctx context.Context, issuer, subject string) (_ *User, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "users")
+	defer func() { tracing.End(span, err) }()
+
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT u.id, u.email, u.password_hash, u.created_at, u.updated_at
+		FROM users u JOIN user_identities i ON i.user_id = u.id
+		WHERE i.issuer = ? AND i.subject = ?`, issuer, subject))
+}
+
+// CreateIdentity links the provider account to an existing user
+func (r *SQLiteUserRepository) CreateIdentity(ctx context.Context, identity *UserIdentity) (err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "user_identities")
+	defer func() { tracing.End(span, err) }()
+
+	return insertIdentity(ctx, r.db, identity)
+}
+
+// CreateUserWithIdentity creates the user and the identity in one transaction;
+// a duplicate email returns ErrEmailTaken
+func (r *SQLiteUserRepository) CreateUserWithIdentity(ctx context.Context, user *User, identity *UserIdentity) (err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "users")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO users (id, email, password_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		user.ID, user.Email, user.PasswordHash, user.CreatedAt, user.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+	identity.UserID = user.ID
+	if err := insertIdentity(ctx, tx, identity); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertIdentity(ctx context.Context, db execer, identity *UserIdentity) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO user_identities (issuer, subject, user_id, email, created_at) VALUES (?, ?, ?, ?, ?)",
+		identity.Issuer, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt)
+	return err
+}
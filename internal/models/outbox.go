@@ -0,0 +1,149 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// OutboxMessage represents an event stored in the outbox table
+type OutboxMessage struct {
+	ID            int64
+	AggregateID   int64
+	EventType     string
+	Payload       []byte
+	Attempts      int
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+	SentAt        *time.Time
+	// ParkedAt is set for messages that can never be published; they are kept for inspection
+	ParkedAt *time.Time
+}
+
+// OutboxStats describes the current outbox backlog
+type OutboxStats struct {
+	Pending         int64
+	Parked          int64
+	OldestCreatedAt *time.Time
+}
+
+// OutboxEventFunc builds an outbox message for a todo change inside the write transaction
+type OutboxEventFunc func(todo *Todo) (*OutboxMessage, error)
+
+// OutboxUpdateEventFunc builds an outbox message from the todo state before and after an update
+type OutboxUpdateEventFunc func(before, after *Todo) (*OutboxMessage, error)
+
+// OutboxTodoRepository writes todo changes together with their events in one transaction
+type OutboxTodoRepository interface {
+	CreateWithEvent(ctx context.Context, ownerID, task string, event OutboxEventFunc) (*Todo, error)
+	UpdateWithEvent(ctx context.Context, ownerID string, id int64, req TodoUpdateRequest, event OutboxUpdateEventFunc) (*Todo, error)
+	DeleteWithEvent(ctx context.Context, ownerID string, id int64, event OutboxEventFunc) error
+}
+
+// OutboxStore defines the operations used by the outbox relay
+type OutboxStore interface {
+	FetchPending(limit int, now time.Time) ([]OutboxMessage, error)
+	MarkSent(id int64, sentAt time.Time) error
+	MarkFailed(id int64, errMsg string, nextAttemptAt time.Time) error
+	// MarkParked stops delivery of a message that can never be published
+	MarkParked(id int64, errMsg string, parkedAt time.Time) error
+	DeleteSentBefore(before time.Time) (int64, error)
+	Stats() (OutboxStats, error)
+}
+
+// SQLiteOutboxStore implements OutboxStore for SQLite
+type SQLiteOutboxStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteOutboxStore creates a new SQLiteOutboxStore
+func NewSQLiteOutboxStore(db *sql.DB) *SQLiteOutboxStore {
+	return &SQLiteOutboxStore{db: db}
+}
+
+// InsertOutboxMessage stores a message using the given transaction
+func InsertOutboxMessage(tx *sql.Tx, msg *OutboxMessage) error {
+	now := time.Now()
+	_, err := tx.Exec(`INSERT INTO outbox (aggregate_id, event_type, payload, attempts, created_at, next_attempt_at)
+		VALUES (?, ?, ?, 0, ?, ?)`,
+		msg.AggregateID, msg.EventType, msg.Payload, now, now)
+	return err
+}
+
+// FetchPending returns unsent messages that are due for delivery, oldest first. A message
+// is not returned while an earlier message of the same todo waits for its next attempt,
+// so events of a todo are published in order. Earlier due messages come in the same batch.
+func (s *SQLiteOutboxStore) FetchPending(limit int, now time.Time) ([]OutboxMessage, error) {
+	rows, err := s.db.Query(`SELECT id, aggregate_id, event_type, payload, attempts, last_error, created_at, next_attempt_at
+		FROM outbox WHERE sent_at IS NULL AND parked_at IS NULL AND next_attempt_at <= ?
+			AND NOT EXISTS (SELECT 1 FROM outbox o2 WHERE o2.aggregate_id = outbox.aggregate_id
+				AND o2.sent_at IS NULL AND o2.parked_at IS NULL AND o2.id < outbox.id AND o2.next_attempt_at > ?)
+		ORDER BY id LIMIT ?`, now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		var msg OutboxMessage
+		var lastError sql.NullString
+		if err := rows.Scan(&msg.ID, &msg.AggregateID, &msg.EventType, &msg.Payload, &msg.Attempts,
+			&lastError, &msg.CreatedAt, &msg.NextAttemptAt); err != nil {
+			return nil, err
+		}
+		msg.LastError = lastError.String
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// MarkSent marks a message as delivered
+func (s *SQLiteOutboxStore) MarkSent(id int64, sentAt time.Time) error {
+	_, err := s.db.Exec("UPDATE outbox SET sent_at = ?, last_error = NULL WHERE id = ?", sentAt, id)
+	return err
+}
+
+// MarkFailed records a failed delivery attempt and schedules the next one
+func (s *SQLiteOutboxStore) MarkFailed(id int64, errMsg string, nextAttemptAt time.Time) error {
+	_, err := s.db.Exec("UPDATE outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?",
+		errMsg, nextAttemptAt, id)
+	return err
+}
+
+// MarkParked records the error and excludes the message from delivery
+func (s *SQLiteOutboxStore) MarkParked(id int64, errMsg string, parkedAt time.Time) error {
+	_, err := s.db.Exec("UPDATE outbox SET attempts = attempts + 1, last_error = ?, parked_at = ? WHERE id = ?",
+		errMsg, parkedAt, id)
+	return err
+}
+
+// DeleteSentBefore removes delivered messages older than the given time
+func (s *SQLiteOutboxStore) DeleteSentBefore(before time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM outbox WHERE sent_at IS NOT NULL AND sent_at < ?", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Stats returns the number of pending and parked messages and the age of the oldest pending one
+func (s *SQLiteOutboxStore) Stats() (OutboxStats, error) {
+	var stats OutboxStats
+	if err := s.db.QueryRow(`SELECT COUNT(*) FILTER (WHERE parked_at IS NULL), COUNT(*) FILTER (WHERE parked_at IS NOT NULL)
+		FROM outbox WHERE sent_at IS NULL`).Scan(&stats.Pending, &stats.Parked); err != nil {
+		return stats, err
+	}
+	if stats.Pending == 0 {
+		return stats, nil
+	}
+
+	var oldest time.Time
+	err := s.db.QueryRow("SELECT created_at FROM outbox WHERE sent_at IS NULL AND parked_at IS NULL ORDER BY id LIMIT 1").Scan(&oldest)
+	if err != nil {
+		return stats, err
+	}
+	stats.OldestCreatedAt = &oldest
+	return stats, nil
+}
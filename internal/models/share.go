@@ -0,0 +1,150 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"todo_app_go/internal/tracing"
+)
+
+// Scopes of a share link
+const (
+	// ShareScopeRead lets the holder of the link only read
+	ShareScopeRead = "read"
+	// ShareScopeComment also lets the holder of the link comment on the shared todos
+	ShareScopeComment = "comment"
+)
+
+// Share is a public link to a todo or to a whole todo list; only the hash of the token is kept
+type Share struct {
+	ID string `json:"id"`
+	// OwnerID is the owner key of the shared todos: a user ID or a workspace
+	OwnerID string `json:"-"`
+	// Shared todo; omitted when the whole list is shared
+	TodoID    *int64 `json:"todo_id,omitempty"`
+	Scope     string `json:"scope"`
+	TokenHash string `json:"-"`
+	// Share token; returned only once, in the response to creation
+	Token     string     `json:"token,omitempty"`
+	CreatedBy string     `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the link can still be used
+func (s *Share) Active(now time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || now.Before(*s.ExpiresAt))
+}
+
+// ShareCreateRequest represents a request to share a todo or a todo list
+type ShareCreateRequest struct {
+	Scope string `json:"scope" validate:"required,oneof=read comment"`
+	// Optional expiry; the link never expires if omitted
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// SharedView is what the holder of a share link sees
+type SharedView struct {
+	Scope     string     `json:"scope"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Todos     []Todo     `json:"todos"`
+	Comments  []Comment  `json:"comments"`
+}
+
+// ShareRepository stores share links
+type ShareRepository interface {
+	CreateShare(ctx context.Context, share *Share) error
+	// GetShareByHash returns the share with the token hash, including revoked and expired shares, or nil
+	GetShareByHash(ctx context.Context, hash string) (*Share, error)
+	ListShares(ctx context.Context, ownerID string) ([]Share, error)
+	// RevokeShare revokes the owner's share and reports whether an active share was found
+	RevokeShare(ctx context.Context, ownerID, id string) (bool, error)
+}
+
+// SQLiteShareRepository implements ShareRepository using SQLite
+type SQLiteShareRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteShareRepository creates a new SQLite share repository
+func NewSQLiteShareRepository(db *sql.DB) *SQLiteShareRepository {
+	return &SQLiteShareRepository{db: db}
+}
+
+const shareColumns = "id, owner_id, todo_id, scope, token_hash, created_by, expires_at, created_at, revoked_at"
+
+// CreateShare inserts the share
+func (r *SQLiteShareRepository) CreateShare(ctx context.Context, share *Share) (err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "shares")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO shares (id, owner_id, todo_id, scope, token_hash, created_by, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		share.ID, share.OwnerID, share.TodoID, share.Scope, share.TokenHash, share.CreatedBy, share.ExpiresAt, share.CreatedAt)
+	return err
+}
+
+// GetShareByHash returns the share with the token hash or nil
+func (r *SQLiteShareRepository) GetShareByHash(ctx context.Context, hash string) (_ *Share, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "shares")
+	defer func() { tracing.End(span, err) }()
+
+	share, err := scanShare(r.db.QueryRowContext(ctx, "SELECT "+shareColumns+" FROM shares WHERE token_hash = ?", hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return share, err
+}
+
+// ListShares returns the owner's shares, newest first
+func (r *SQLiteShareRepository) ListShares(ctx context.Context, ownerID string) (_ []Share, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "shares")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+shareColumns+" FROM shares WHERE owner_id = ? ORDER BY created_at DESC", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []Share{}
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, *share)
+	}
+	return shares, rows.Err()
+}
+
+// RevokeShare marks the share as revoked
+func (r *SQLiteShareRepository) RevokeShare(ctx context.Context, ownerID, id string) (_ bool, err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "shares")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := r.db.ExecContext(ctx, "UPDATE shares SET revoked_at = ? WHERE id = ? AND owner_id = ? AND revoked_at IS NULL", time.Now(), id, ownerID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func scanShare(row rowScanner) (*Share, error) {
+	var share Share
+	var todoID sql.NullInt64
+	var expiresAt, revokedAt sql.NullTime
+	err := row.Scan(&share.ID, &share.OwnerID, &todoID, &share.Scope, &share.TokenHash, &share.CreatedBy,
+		&expiresAt, &share.CreatedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if todoID.Valid {
+		share.TodoID = &todoID.Int64
+	}
+	share.ExpiresAt = nullTimePtr(expiresAt)
+	share.RevokedAt = nullTimePtr(revokedAt)
+	return &share, nil
+}
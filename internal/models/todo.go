@@ -1,13 +1,21 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"time"
+
+	"todo_app_go/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Todo represents a todo item in the system
 type Todo struct {
-	ID        int64     `json:"id"`
+	ID int64 `json:"id"`
+	// OwnerID is the user who created the todo (token subject); empty when authentication is disabled
+	OwnerID   string    `json:"owner_id"`
 	Task      string    `json:"task" validate:"required,min=1,max=500"`
 	Completed bool      `json:"completed"`
 	CreatedAt time.Time `json:"created_at"`
@@ -25,14 +33,25 @@ type TodoUpdateRequest struct {
 	Completed *bool   `json:"completed,omitempty"`
 }
 
-// TodoRepository defines the interface for todo storage operations
+// TodoRepository defines the interface for todo storage operations.
+// Every operation is scoped to the owner: todos of other owners are reported as missing.
 type TodoRepository interface {
-	Create(task string) (*Todo, error)
-	GetByID(id int64) (*Todo, error)
-	GetAll() ([]Todo, error)
-	Update(id int64, req TodoUpdateRequest) (*Todo, error)
-	UpdateStatus(id int64, completed bool) error
-	Delete(id int64) error
+	Create(ctx context.Context, ownerID, task string) (*Todo, error)
+	GetByID(ctx context.Context, ownerID string, id int64) (*Todo, error)
+	GetAll(ctx context.Context, ownerID string) ([]Todo, error)
+	Update(ctx context.Context, ownerID string, id int64, req TodoUpdateRequest) (*Todo, error)
+	UpdateStatus(ctx context.Context, ownerID string, id int64, completed bool) error
+	Delete(ctx context.Context, ownerID string, id int64) error
+}
+
+// TodoCounter counts todos by completion status; used to seed the business metrics gauges
+type TodoCounter interface {
+	CountByStatus(ctx context.Context) (open, completed int64, err error)
+}
+
+// TodoHistoryRepository reconstructs past todo states from the event log
+type TodoHistoryRepository interface {
+	GetByIDAsOf(ctx context.Context, ownerID string, id int64, asOf time.Time) (*Todo, error)
 }
 
 // SQLiteTodoRepository implements TodoRepository for SQLite
@@ -46,10 +65,13 @@ func NewSQLiteTodoRepository(db *sql.DB) *SQLiteTodoRepository {
 }
 
 // Create adds a new todo to the database
-func (r *SQLiteTodoRepository) Create(task string) (*Todo, error) {
+func (r *SQLiteTodoRepository) Create(ctx context.Context, ownerID, task string) (_ *Todo, err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "todos")
+	defer func() { tracing.End(span, err) }()
+
 	now := time.Now()
-	result, err := r.db.Exec("INSERT INTO todos (task, completed, created_at, updated_at) VALUES (?, ?, ?, ?)",
-		task, false, now, now)
+	result, err := r.db.ExecContext(ctx, "INSERT INTO todos (owner_id, task, completed, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		ownerID, task, false, now, now)
 	if err != nil {
 		return nil, err
 	}
@@ -61,6 +83,7 @@ func (r *SQLiteTodoRepository) Create(task string) (*Todo, error) {
 
 	return &Todo{
 		ID:        id,
+		OwnerID:   ownerID,
 		Task:      task,
 		Completed: false,
 		CreatedAt: now,
@@ -68,11 +91,14 @@ func (r *SQLiteTodoRepository) Create(task string) (*Todo, error) {
 	}, nil
 }
 
-// GetByID retrieves a todo by ID
-func (r *SQLiteTodoRepository) GetByID(id int64) (*Todo, error) {
+// GetByID retrieves a todo of the owner by ID
+func (r *SQLiteTodoRepository) GetByID(ctx context.Context, ownerID string, id int64) (_ *Todo, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "todos")
+	defer func() { tracing.End(span, err) }()
+
 	var todo Todo
-	err := r.db.QueryRow("SELECT id, task, completed, created_at, updated_at FROM todos WHERE id = ?", id).
-		Scan(&todo.ID, &todo.Task, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
+	err = r.db.QueryRowContext(ctx, "SELECT id, owner_id, task, completed, created_at, updated_at FROM todos WHERE id = ? AND owner_id = ?", id, ownerID).
+		Scan(&todo.ID, &todo.OwnerID, &todo.Task, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -82,9 +108,12 @@ func (r *SQLiteTodoRepository) GetByID(id int64) (*Todo, error) {
 	return &todo, nil
 }
 
-// GetAll retrieves all todos from the database
-func (r *SQLiteTodoRepository) GetAll() ([]Todo, error) {
-	rows, err := r.db.Query("SELECT id, task, completed, created_at, updated_at FROM todos ORDER BY created_at DESC")
+// GetAll retrieves all todos of the owner
+func (r *SQLiteTodoRepository) GetAll(ctx context.Context, ownerID string) (_ []Todo, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "todos")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, "SELECT id, owner_id, task, completed, created_at, updated_at FROM todos WHERE owner_id = ? ORDER BY created_at DESC", ownerID)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +122,7 @@ func (r *SQLiteTodoRepository) GetAll() ([]Todo, error) {
 	var todos []Todo
 	for rows.Next() {
 		var todo Todo
-		err := rows.Scan(&todo.ID, &todo.Task, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
+		err := rows.Scan(&todo.ID, &todo.OwnerID, &todo.Task, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -103,9 +132,12 @@ func (r *SQLiteTodoRepository) GetAll() ([]Todo, error) {
 }
 
 // Update updates a todo
-func (r *SQLiteTodoRepository) Update(id int64, req TodoUpdateRequest) (*Todo, error) {
+func (r *SQLiteTodoRepository) Update(ctx context.Context, ownerID string, id int64, req TodoUpdateRequest) (_ *Todo, err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "todos")
+	defer func() { tracing.End(span, err) }()
+
 	// Сначала получаем текущий todo
-	todo, err := r.GetByID(id)
+	todo, err := r.GetByID(ctx, ownerID, id)
 	if err != nil {
 		return nil, err
 	}
@@ -123,8 +155,8 @@ func (r *SQLiteTodoRepository) Update(id int64, req TodoUpdateRequest) (*Todo, e
 	todo.UpdatedAt = time.Now()
 
 	// Обновляем в базе
-	_, err = r.db.Exec("UPDATE todos SET task = ?, completed = ?, updated_at = ? WHERE id = ?",
-		todo.Task, todo.Completed, todo.UpdatedAt, id)
+	_, err = r.db.ExecContext(ctx, "UPDATE todos SET task = ?, completed = ?, updated_at = ? WHERE id = ? AND owner_id = ?",
+		todo.Task, todo.Completed, todo.UpdatedAt, id, ownerID)
 	if err != nil {
 		return nil, err
 	}
@@ -133,14 +165,175 @@ func (r *SQLiteTodoRepository) Update(id int64, req TodoUpdateRequest) (*Todo, e
 }
 
 // UpdateStatus updates the completion status of a todo
-func (r *SQLiteTodoRepository) UpdateStatus(id int64, completed bool) error {
-	_, err := r.db.Exec("UPDATE todos SET completed = ?, updated_at = ? WHERE id = ?",
-		completed, time.Now(), id)
+func (r *SQLiteTodoRepository) UpdateStatus(ctx context.Context, ownerID string, id int64, completed bool) (err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "todos")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, "UPDATE todos SET completed = ?, updated_at = ? WHERE id = ? AND owner_id = ?",
+		completed, time.Now(), id, ownerID)
 	return err
 }
 
-// Delete removes a todo from the database
-func (r *SQLiteTodoRepository) Delete(id int64) error {
-	_, err := r.db.Exec("DELETE FROM todos WHERE id = ?", id)
+// CountByStatus returns the number of open and completed todos of all owners
+func (r *SQLiteTodoRepository) CountByStatus(ctx context.Context) (open, completed int64, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "todos")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(CASE WHEN completed THEN 0 ELSE 1 END), 0), COALESCE(SUM(CASE WHEN completed THEN 1 ELSE 0 END), 0) FROM todos").
+		Scan(&open, &completed)
+	return open, completed, err
+}
+
+// Delete removes a todo of the owner from the database
+func (r *SQLiteTodoRepository) Delete(ctx context.Context, ownerID string, id int64) (err error) {
+	ctx, span := StartDBSpan(ctx, "DELETE", "todos")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, "DELETE FROM todos WHERE id = ? AND owner_id = ?", id, ownerID)
 	return err
 }
+
+// CreateWithEvent adds a new todo and stores its outbox event in the same transaction
+func (r *SQLiteTodoRepository) CreateWithEvent(ctx context.Context, ownerID, task string, event OutboxEventFunc) (_ *Todo, err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "todos")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, "INSERT INTO todos (owner_id, task, completed, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		ownerID, task, false, now, now)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	todo := &Todo{
+		ID:        id,
+		OwnerID:   ownerID,
+		Task:      task,
+		Completed: false,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := writeOutboxEvent(tx, todo, event); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+// UpdateWithEvent updates a todo and stores its outbox event in the same transaction
+func (r *SQLiteTodoRepository) UpdateWithEvent(ctx context.Context, ownerID string, id int64, req TodoUpdateRequest, event OutboxUpdateEventFunc) (_ *Todo, err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "todos")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var todo Todo
+	err = tx.QueryRowContext(ctx, "SELECT id, owner_id, task, completed, created_at, updated_at FROM todos WHERE id = ? AND owner_id = ?", id, ownerID).
+		Scan(&todo.ID, &todo.OwnerID, &todo.Task, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	before := todo
+
+	if req.Task != nil {
+		todo.Task = *req.Task
+	}
+	if req.Completed != nil {
+		todo.Completed = *req.Completed
+	}
+	todo.UpdatedAt = time.Now()
+
+	_, err = tx.ExecContext(ctx, "UPDATE todos SET task = ?, completed = ?, updated_at = ? WHERE id = ?",
+		todo.Task, todo.Completed, todo.UpdatedAt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if event != nil {
+		msg, err := event(&before, &todo)
+		if err != nil {
+			return nil, err
+		}
+		if err := InsertOutboxMessage(tx, msg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// DeleteWithEvent removes a todo and stores its outbox event in the same transaction
+func (r *SQLiteTodoRepository) DeleteWithEvent(ctx context.Context, ownerID string, id int64, event OutboxEventFunc) (err error) {
+	ctx, span := StartDBSpan(ctx, "DELETE", "todos")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM todos WHERE id = ? AND owner_id = ?", id, ownerID)
+	if err != nil {
+		return err
+	}
+	// Событие об удалении чужой или несуществующей todo не пишется
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		return err
+	}
+
+	if err := writeOutboxEvent(tx, &Todo{ID: id, OwnerID: ownerID}, event); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// writeOutboxEvent builds the event for the todo and inserts it into the outbox
+func writeOutboxEvent(tx *sql.Tx, todo *Todo, event OutboxEventFunc) error {
+	if event == nil {
+		return nil
+	}
+	msg, err := event(todo)
+	if err != nil {
+		return err
+	}
+	return InsertOutboxMessage(tx, msg)
+}
+
+// StartDBSpan starts a client span for a SQLite operation on the table
+func StartDBSpan(ctx context.Context, operation, table string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, operation+" "+table,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", table),
+		))
+}
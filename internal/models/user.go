@@ -0,0 +1,300 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"todo_app_go/internal/tracing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrEmailTaken is returned when a user with the email already exists
+var ErrEmailTaken = errors.New("email is already registered")
+
+// User is a local account
+type User struct {
+	ID           string    `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RefreshToken is a stored refresh token; only the hash of the token is kept
+type RefreshToken struct {
+	ID        int64
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// PasswordReset is a one-time password reset token; only the hash of the token is kept
+type PasswordReset struct {
+	TokenHash string
+	UserID    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+// RegisterRequest represents a request to create an account
+type RegisterRequest struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// LoginRequest represents a password login
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// RefreshRequest carries a refresh token for /auth/refresh and /auth/logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// PasswordResetRequest starts a password reset
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// PasswordResetConfirmRequest sets a new password with a reset token
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// TokenResponse is an issued access and refresh token pair
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // seconds until the access token expires
+	RefreshToken string `json:"refresh_token"`
+}
+
+// UserRepository stores local accounts, refresh tokens and password reset tokens
+type UserRepository interface {
+	CreateUser(ctx context.Context, user *User) error
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	GetUserByID(ctx context.Context, id string) (*User, error)
+
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	// RotateRefreshToken revokes the active token with oldHash and stores next for the same user.
+	// It returns the old token as it was before rotation, or nil if it does not exist;
+	// next is stored only if the old token was active and not expired.
+	RotateRefreshToken(ctx context.Context, oldHash string, next *RefreshToken) (*RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, hash string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+
+	CreatePasswordReset(ctx context.Context, reset *PasswordReset) error
+	// ResetPassword consumes an unused, unexpired reset token, sets the new password hash and
+	// revokes the user's refresh tokens. It returns the user ID, or "" if the token is not valid.
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, error)
+}
+
+// SQLiteUserRepository implements UserRepository using SQLite
+type SQLiteUserRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteUserRepository creates a new SQLite user repository
+func NewSQLiteUserRepository(db *sql.DB) *SQLiteUserRepository {
+	return &SQLiteUserRepository{db: db}
+}
+
+// CreateUser inserts the user; a duplicate email returns ErrEmailTaken
+func (r *SQLiteUserRepository) CreateUser(ctx context.Context, user *User) (err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "users")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO users (id, email, password_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		user.ID, user.Email, user.PasswordHash, user.CreatedAt, user.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// GetUserByEmail returns the user with the email or nil
+func (r *SQLiteUserRepository) GetUserByEmail(ctx context.Context, email string) (_ *User, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "users")
+	defer func() { tracing.End(span, err) }()
+
+	return scanUser(r.db.QueryRowContext(ctx, "SELECT id, email, password_hash, created_at, updated_at FROM users WHERE email = ?", email))
+}
+
+// GetUserByID returns the user with the ID or nil
+func (r *SQLiteUserRepository) GetUserByID(ctx context.Context, id string) (_ *User, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "users")
+	defer func() { tracing.End(span, err) }()
+
+	return scanUser(r.db.QueryRowContext(ctx, "SELECT id, email, password_hash, created_at, updated_at FROM users WHERE id = ?", id))
+}
+
+func scanUser(row *sql.Row) (*User, error) {
+	var user User
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateRefreshToken stores a new refresh token
+func (r *SQLiteUserRepository) CreateRefreshToken(ctx context.Context, token *RefreshToken) (err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "refresh_tokens")
+	defer func() { tracing.End(span, err) }()
+
+	return insertRefreshToken(ctx, r.db, token)
+}
+
+// RotateRefreshToken replaces an active refresh token with next in one transaction
+func (r *SQLiteUserRepository) RotateRefreshToken(ctx context.Context, oldHash string, next *RefreshToken) (_ *RefreshToken, err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "refresh_tokens")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var old RefreshToken
+	var revokedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT id, user_id, token_hash, expires_at, created_at, revoked_at FROM refresh_tokens WHERE token_hash = ?", oldHash).
+		Scan(&old.ID, &old.UserID, &old.TokenHash, &old.ExpiresAt, &old.CreatedAt, &revokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if revokedAt.Valid {
+		old.RevokedAt = &revokedAt.Time
+		return &old, nil
+	}
+
+	now := time.Now()
+	if !now.Before(old.ExpiresAt) {
+		return &old, nil
+	}
+
+	// Условие revoked_at IS NULL защищает от двух одновременных обменов одного токена
+	result, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", now, old.ID)
+	if err != nil {
+		return nil, err
+	}
+	if rotated, err := result.RowsAffected(); err != nil || rotated == 0 {
+		old.RevokedAt = &now
+		return &old, err
+	}
+
+	next.UserID = old.UserID
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return nil, err
+	}
+	return &old, tx.Commit()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertRefreshToken(ctx context.Context, db execer, token *RefreshToken) error {
+	result, err := db.ExecContext(ctx, "INSERT INTO refresh_tokens (user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?)",
+		token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return err
+	}
+	token.ID, err = result.LastInsertId()
+	return err
+}
+
+// RevokeRefreshToken revokes the refresh token with the hash; unknown tokens are ignored
+func (r *SQLiteUserRepository) RevokeRefreshToken(ctx context.Context, hash string) (err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "refresh_tokens")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE token_hash = ? AND revoked_at IS NULL", time.Now(), hash)
+	return err
+}
+
+// RevokeUserRefreshTokens revokes all active refresh tokens of the user
+func (r *SQLiteUserRepository) RevokeUserRefreshTokens(ctx context.Context, userID string) (err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "refresh_tokens")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", time.Now(), userID)
+	return err
+}
+
+// CreatePasswordReset stores a password reset token
+func (r *SQLiteUserRepository) CreatePasswordReset(ctx context.Context, reset *PasswordReset) (err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "password_resets")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO password_resets (token_hash, user_id, expires_at, created_at) VALUES (?, ?, ?, ?)",
+		reset.TokenHash, reset.UserID, reset.ExpiresAt, reset.CreatedAt)
+	return err
+}
+
+// ResetPassword consumes the reset token and changes the password in one transaction
+func (r *SQLiteUserRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (_ string, err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "users")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var reset PasswordReset
+	var usedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT token_hash, user_id, expires_at, used_at FROM password_resets WHERE token_hash = ?", tokenHash).
+		Scan(&reset.TokenHash, &reset.UserID, &reset.ExpiresAt, &usedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	now := time.Now()
+	if usedAt.Valid || !now.Before(reset.ExpiresAt) {
+		return "", nil
+	}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"UPDATE password_resets SET used_at = ? WHERE token_hash = ?", []interface{}{now, tokenHash}},
+		{"UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?", []interface{}{passwordHash, now, reset.UserID}},
+		// Сессии, открытые старым паролем, больше не действуют
+		{"UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", []interface{}{now, reset.UserID}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return reset.UserID, nil
+}
@@ -0,0 +1,305 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"todo_app_go/internal/tracing"
+)
+
+// ErrLastOwner is returned when a change would leave a workspace without an owner
+var ErrLastOwner = errors.New("workspace must keep at least one owner")
+
+// workspaceOwnerPrefix отделяет ключи пространств от ID пользователей в todos.owner_id
+const workspaceOwnerPrefix = "workspace:"
+
+// WorkspaceOwner returns the owner key of the todos of a workspace. Personal todos are
+// owned by the user ID; the prefix keeps the two namespaces apart.
+func WorkspaceOwner(workspaceID string) string {
+	return workspaceOwnerPrefix + workspaceID
+}
+
+// IsWorkspaceOwner reports whether the owner key belongs to a workspace. A user ID must never
+// look like one: a user with such an ID would own the workspace's todos without being a member.
+func IsWorkspaceOwner(ownerID string) bool {
+	return strings.HasPrefix(ownerID, workspaceOwnerPrefix)
+}
+
+// Workspace is a shared todo list with members
+type Workspace struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	// Role of the current user; set in the list of the user's workspaces
+	Role string `json:"role,omitempty"`
+}
+
+// WorkspaceMember is a user's membership in a workspace
+type WorkspaceMember struct {
+	WorkspaceID string    `json:"workspace_id"`
+	UserID      string    `json:"user_id"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WorkspaceInvite is a one-time invitation to join a workspace with a role;
+// only the hash of the token is kept
+type WorkspaceInvite struct {
+	ID          string `json:"id"`
+	WorkspaceID string `json:"workspace_id"`
+	Role        string `json:"role"`
+	InvitedBy   string `json:"invited_by"`
+	TokenHash   string `json:"-"`
+	// Invite token; returned only once, in the response to creation
+	Token      string     `json:"token,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	AcceptedBy string     `json:"accepted_by,omitempty"`
+}
+
+// WorkspaceCreateRequest represents a request to create a workspace
+type WorkspaceCreateRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// InviteCreateRequest represents a request to invite someone to a workspace
+type InviteCreateRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+// InviteAcceptRequest carries the invite token to accept
+type InviteAcceptRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// MemberUpdateRequest changes a member's role
+type MemberUpdateRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+// WorkspaceRepository stores workspaces, their members and invites
+type WorkspaceRepository interface {
+	// CreateWorkspace creates the workspace with its creator as the owner
+	CreateWorkspace(ctx context.Context, workspace *Workspace) error
+	// ListWorkspaces returns the user's workspaces with the user's role
+	ListWorkspaces(ctx context.Context, userID string) ([]Workspace, error)
+	// GetMemberRole returns the user's role in the workspace, or "" if the user is not a member
+	GetMemberRole(ctx context.Context, workspaceID, userID string) (string, error)
+	ListMembers(ctx context.Context, workspaceID string) ([]WorkspaceMember, error)
+	// UpdateMemberRole changes the role and reports whether the member exists;
+	// demoting the last owner returns ErrLastOwner
+	UpdateMemberRole(ctx context.Context, workspaceID, userID, role string) (bool, error)
+	// RemoveMember removes the member and reports whether it existed;
+	// removing the last owner returns ErrLastOwner
+	RemoveMember(ctx context.Context, workspaceID, userID string) (bool, error)
+
+	CreateInvite(ctx context.Context, invite *WorkspaceInvite) error
+	// AcceptInvite consumes an unused, unexpired invite and adds the user with the invite's
+	// role; an existing member keeps their role. It returns the invite, or nil if it is not valid.
+	AcceptInvite(ctx context.Context, tokenHash, userID string) (*WorkspaceInvite, error)
+}
+
+// SQLiteWorkspaceRepository implements WorkspaceRepository using SQLite
+type SQLiteWorkspaceRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteWorkspaceRepository creates a new SQLite workspace repository
+func NewSQLiteWorkspaceRepository(db *sql.DB) *SQLiteWorkspaceRepository {
+	return &SQLiteWorkspaceRepository{db: db}
+}
+
+// CreateWorkspace inserts the workspace and the owner membership in one transaction
+func (r *SQLiteWorkspaceRepository) CreateWorkspace(ctx context.Context, workspace *Workspace) (err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "workspaces")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO workspaces (id, name, created_by, created_at) VALUES (?, ?, ?, ?)",
+		workspace.ID, workspace.Name, workspace.CreatedBy, workspace.CreatedAt); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO workspace_members (workspace_id, user_id, role, created_at) VALUES (?, ?, ?, ?)",
+		workspace.ID, workspace.CreatedBy, "owner", workspace.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListWorkspaces returns the user's workspaces, oldest first
+func (r *SQLiteWorkspaceRepository) ListWorkspaces(ctx context.Context, userID string) (_ []Workspace, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "workspaces")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT w.id, w.name, w.created_by, w.created_at, m.role
+		FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = ? ORDER BY w.created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workspaces := []Workspace{}
+	for rows.Next() {
+		var w Workspace
+		if err := rows.Scan(&w.ID, &w.Name, &w.CreatedBy, &w.CreatedAt, &w.Role); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, w)
+	}
+	return workspaces, rows.Err()
+}
+
+// GetMemberRole returns the user's role in the workspace or ""
+func (r *SQLiteWorkspaceRepository) GetMemberRole(ctx context.Context, workspaceID, userID string) (_ string, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "workspace_members")
+	defer func() { tracing.End(span, err) }()
+
+	var role string
+	err = r.db.QueryRowContext(ctx, "SELECT role FROM workspace_members WHERE workspace_id = ? AND user_id = ?", workspaceID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// ListMembers returns the members of the workspace, oldest first
+func (r *SQLiteWorkspaceRepository) ListMembers(ctx context.Context, workspaceID string) (_ []WorkspaceMember, err error) {
+	ctx, span := StartDBSpan(ctx, "SELECT", "workspace_members")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, "SELECT workspace_id, user_id, role, created_at FROM workspace_members WHERE workspace_id = ? ORDER BY created_at", workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []WorkspaceMember{}
+	for rows.Next() {
+		var m WorkspaceMember
+		if err := rows.Scan(&m.WorkspaceID, &m.UserID, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// UpdateMemberRole changes the member's role in one transaction with the last owner check
+func (r *SQLiteWorkspaceRepository) UpdateMemberRole(ctx context.Context, workspaceID, userID, role string) (_ bool, err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "workspace_members")
+	defer func() { tracing.End(span, err) }()
+
+	return r.changeMember(ctx, workspaceID, userID, role != "owner",
+		"UPDATE workspace_members SET role = ? WHERE workspace_id = ? AND user_id = ?", role, workspaceID, userID)
+}
+
+// RemoveMember deletes the membership in one transaction with the last owner check
+func (r *SQLiteWorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID string) (_ bool, err error) {
+	ctx, span := StartDBSpan(ctx, "DELETE", "workspace_members")
+	defer func() { tracing.End(span, err) }()
+
+	return r.changeMember(ctx, workspaceID, userID, true,
+		"DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?", workspaceID, userID)
+}
+
+// changeMember runs the statement on an existing membership. If dropsOwner is set and the
+// member is the only owner, the statement is not run.
+func (r *SQLiteWorkspaceRepository) changeMember(ctx context.Context, workspaceID, userID string, dropsOwner bool, query string, args ...interface{}) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRowContext(ctx, "SELECT role FROM workspace_members WHERE workspace_id = ? AND user_id = ?", workspaceID, userID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	if dropsOwner && role == "owner" {
+		var owners int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM workspace_members WHERE workspace_id = ? AND role = 'owner'", workspaceID).Scan(&owners); err != nil {
+			return false, err
+		}
+		if owners <= 1 {
+			return false, ErrLastOwner
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// CreateInvite stores the invite
+func (r *SQLiteWorkspaceRepository) CreateInvite(ctx context.Context, invite *WorkspaceInvite) (err error) {
+	ctx, span := StartDBSpan(ctx, "INSERT", "workspace_invites")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO workspace_invites (id, workspace_id, role, invited_by, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		invite.ID, invite.WorkspaceID, invite.Role, invite.InvitedBy, invite.TokenHash, invite.ExpiresAt, invite.CreatedAt)
+	return err
+}
+
+// AcceptInvite consumes the invite and adds the membership in one transaction
+func (r *SQLiteWorkspaceRepository) AcceptInvite(ctx context.Context, tokenHash, userID string) (_ *WorkspaceInvite, err error) {
+	ctx, span := StartDBSpan(ctx, "UPDATE", "workspace_invites")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var invite WorkspaceInvite
+	var acceptedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT id, workspace_id, role, invited_by, expires_at, created_at, accepted_at FROM workspace_invites WHERE token_hash = ?", tokenHash).
+		Scan(&invite.ID, &invite.WorkspaceID, &invite.Role, &invite.InvitedBy, &invite.ExpiresAt, &invite.CreatedAt, &acceptedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if acceptedAt.Valid || !now.Before(invite.ExpiresAt) {
+		return nil, nil
+	}
+
+	// Приглашение не понижает роль: участник, принявший его повторно, сохраняет свою
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"UPDATE workspace_invites SET accepted_at = ?, accepted_by = ? WHERE id = ?", []interface{}{now, userID, invite.ID}},
+		{"INSERT OR IGNORE INTO workspace_members (workspace_id, user_id, role, created_at) VALUES (?, ?, ?, ?)", []interface{}{invite.WorkspaceID, userID, invite.Role, now}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	invite.AcceptedAt = &now
+	invite.AcceptedBy = userID
+	return &invite, nil
+}
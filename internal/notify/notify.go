@@ -0,0 +1,96 @@
+// Package notify delivers messages to users, such as password reset tokens.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// Notifier delivers account messages to the user. Implementations decide the channel:
+// email, chat or an external service behind a webhook.
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, email, token string, expiresAt time.Time) error
+}
+
+// New creates the notifier configured in auth.local.notifier
+func New(cfg config.LocalAuthConfig) (Notifier, error) {
+	switch cfg.Notifier {
+	case "", "log":
+		return LogNotifier{}, nil
+	case "webhook":
+		if cfg.WebhookURL == "" {
+			return nil, fmt.Errorf("webhook notifier requires auth.local.webhook_url")
+		}
+		return NewWebhookNotifier(cfg.WebhookURL), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", cfg.Notifier)
+	}
+}
+
+// LogNotifier writes messages to the application log. Only for development:
+// anyone with access to the logs can reset passwords.
+type LogNotifier struct{}
+
+// SendPasswordReset logs the reset token
+func (LogNotifier) SendPasswordReset(ctx context.Context, email, token string, expiresAt time.Time) error {
+	logger.FromContext(ctx).Info("Password reset requested",
+		zap.String("email", email),
+		zap.String("reset_token", token),
+		zap.Time("expires_at", expiresAt))
+	return nil
+}
+
+// WebhookMessage is the JSON body posted by WebhookNotifier
+type WebhookMessage struct {
+	Type      string    `json:"type"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// WebhookNotifier posts messages to an HTTP endpoint that delivers them, e.g. a mail service
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// SendPasswordReset posts a password_reset message
+func (n *WebhookNotifier) SendPasswordReset(ctx context.Context, email, token string, expiresAt time.Time) error {
+	return n.post(ctx, WebhookMessage{Type: "password_reset", Email: email, Token: token, ExpiresAt: expiresAt})
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, msg WebhookMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Package policy decides what members of a workspace may do. It knows nothing about
+// HTTP or storage: callers resolve the member's role and ask whether an action is allowed.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrForbidden is returned when the role does not allow the action
+var ErrForbidden = errors.New("forbidden")
+
+// Role is a member's role in a workspace
+type Role string
+
+const (
+	// RoleOwner manages members and invites and has every editor right
+	RoleOwner Role = "owner"
+	// RoleEditor creates, updates, deletes and shares todos
+	RoleEditor Role = "editor"
+	// RoleViewer reads and comments on todos and reads the member list
+	RoleViewer Role = "viewer"
+)
+
+// Valid reports whether the role is known
+func (r Role) Valid() bool {
+	switch r {
+	case RoleOwner, RoleEditor, RoleViewer:
+		return true
+	}
+	return false
+}
+
+// Action is an operation on a workspace or its todos
+type Action string
+
+const (
+	ActionTodoRead   Action = "todo:read"
+	ActionTodoCreate Action = "todo:create"
+	ActionTodoUpdate Action = "todo:update"
+	ActionTodoDelete Action = "todo:delete"
+	// ActionTodoShare creates and revokes public links to todos
+	ActionTodoShare   Action = "todo:share"
+	ActionTodoComment Action = "todo:comment"
+
+	ActionWorkspaceRead Action = "workspace:read"
+	ActionMemberInvite  Action = "member:invite"
+	ActionMemberUpdate  Action = "member:update"
+	ActionMemberRemove  Action = "member:remove"
+)
+
+// Policy maps roles to the actions they allow
+type Policy struct {
+	grants map[Role]map[Action]bool
+}
+
+// New returns the default policy: viewers read and comment, editors also change and
+// share todos, owners also manage members
+func New() *Policy {
+	read := []Action{ActionTodoRead, ActionTodoComment, ActionWorkspaceRead}
+	write := slices.Concat(read, []Action{ActionTodoCreate, ActionTodoUpdate, ActionTodoDelete, ActionTodoShare})
+	manage := slices.Concat(write, []Action{ActionMemberInvite, ActionMemberUpdate, ActionMemberRemove})
+
+	return NewWithGrants(map[Role][]Action{
+		RoleViewer: read,
+		RoleEditor: write,
+		RoleOwner:  manage,
+	})
+}
+
+// NewWithGrants returns a policy with explicit grants; roles not listed are allowed nothing
+func NewWithGrants(grants map[Role][]Action) *Policy {
+	p := &Policy{grants: make(map[Role]map[Action]bool, len(grants))}
+	for role, actions := range grants {
+		p.grants[role] = make(map[Action]bool, len(actions))
+		for _, action := range actions {
+			p.grants[role][action] = true
+		}
+	}
+	return p
+}
+
+// defaultPolicy is used by a nil *Policy
+var defaultPolicy = New()
+
+// Allowed reports whether the role allows the action. A nil policy uses the default grants.
+func (p *Policy) Allowed(role Role, action Action) bool {
+	if p == nil {
+		p = defaultPolicy
+	}
+	return p.grants[role][action]
+}
+
+// Authorize returns an error wrapping ErrForbidden if the role does not allow the action
+func (p *Policy) Authorize(role Role, action Action) error {
+	if !p.Allowed(role, action) {
+		return fmt.Errorf("%w: role %q may not %s", ErrForbidden, role, action)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_DefaultGrants(t *testing.T) {
+	p := New()
+
+	tests := []struct {
+		role    Role
+		action  Action
+		allowed bool
+	}{
+		{RoleViewer, ActionTodoRead, true},
+		{RoleViewer, ActionWorkspaceRead, true},
+		{RoleViewer, ActionTodoCreate, false},
+		{RoleViewer, ActionTodoDelete, false},
+		{RoleViewer, ActionTodoComment, true},
+		{RoleViewer, ActionTodoShare, false},
+		{RoleViewer, ActionMemberInvite, false},
+		{RoleEditor, ActionTodoRead, true},
+		{RoleEditor, ActionTodoCreate, true},
+		{RoleEditor, ActionTodoUpdate, true},
+		{RoleEditor, ActionTodoDelete, true},
+		{RoleEditor, ActionTodoShare, true},
+		{RoleEditor, ActionMemberInvite, false},
+		{RoleEditor, ActionMemberUpdate, false},
+		{RoleOwner, ActionTodoDelete, true},
+		{RoleOwner, ActionMemberInvite, true},
+		{RoleOwner, ActionMemberUpdate, true},
+		{RoleOwner, ActionMemberRemove, true},
+		{Role(""), ActionTodoRead, false},
+		{Role("admin"), ActionTodoRead, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role)+" "+string(tt.action), func(t *testing.T) {
+			assert.Equal(t, tt.allowed, p.Allowed(tt.role, tt.action))
+			if tt.allowed {
+				assert.NoError(t, p.Authorize(tt.role, tt.action))
+			} else {
+				assert.ErrorIs(t, p.Authorize(tt.role, tt.action), ErrForbidden)
+			}
+		})
+	}
+}
+
+func TestPolicy_CustomGrants(t *testing.T) {
+	p := NewWithGrants(map[Role][]Action{RoleViewer: {ActionTodoRead, ActionTodoCreate}})
+
+	assert.True(t, p.Allowed(RoleViewer, ActionTodoCreate))
+	assert.False(t, p.Allowed(RoleEditor, ActionTodoRead))
+}
+
+func TestPolicy_NilUsesDefault(t *testing.T) {
+	var p *Policy
+	assert.True(t, p.Allowed(RoleEditor, ActionTodoCreate))
+	assert.ErrorIs(t, p.Authorize(RoleViewer, ActionTodoCreate), ErrForbidden)
+}
+
+func TestRole_Valid(t *testing.T) {
+	assert.True(t, RoleOwner.Valid())
+	assert.True(t, RoleEditor.Valid())
+	assert.True(t, RoleViewer.Valid())
+	assert.False(t, Role("admin").Valid())
+}
@@ -0,0 +1,91 @@
+package projections
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"todo_app_go/internal/events"
+)
+
+// AuditEntry is a single record of the audit log
+type AuditEntry struct {
+	ID         int64                `json:"id"`
+	EventID    string               `json:"event_id"`
+	EventType  string               `json:"event_type"`
+	TodoID     int64                `json:"todo_id"`
+	Changes    []events.FieldChange `json:"changes,omitempty"`
+	OccurredAt time.Time            `json:"occurred_at"`
+	RecordedAt time.Time            `json:"recorded_at"`
+}
+
+// AuditLogProjection appends every todo event to the audit_log table once per event ID
+type AuditLogProjection struct {
+	db *sql.DB
+}
+
+func NewAuditLogProjection(db *sql.DB) *AuditLogProjection {
+	return &AuditLogProjection{db: db}
+}
+
+func (p *AuditLogProjection) Name() string {
+	return "audit_log"
+}
+
+func (p *AuditLogProjection) Handle(ctx context.Context, event events.TodoEvent) error {
+	return p.insert(ctx, p.db, event)
+}
+
+// HandleTx appends the event within the caller's transaction
+func (p *AuditLogProjection) HandleTx(ctx context.Context, tx *sql.Tx, event events.TodoEvent) error {
+	return p.insert(ctx, tx, event)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (p *AuditLogProjection) insert(ctx context.Context, db execer, event events.TodoEvent) error {
+	var changes []byte
+	if len(event.Changes) > 0 {
+		var err error
+		if changes, err = json.Marshal(event.Changes); err != nil {
+			return err
+		}
+	}
+
+	// Повторная доставка не дублирует запись; события без ID (legacy) записываются всегда
+	_, err := db.ExecContext(ctx, `INSERT INTO audit_log (event_id, event_type, todo_id, changes, occurred_at, recorded_at)
+		SELECT ?, ?, ?, ?, ?, ?
+		WHERE ? = '' OR NOT EXISTS (SELECT 1 FROM audit_log WHERE event_id = ?)`,
+		event.ID, event.Type, event.TodoID, changes, event.Timestamp, time.Now(), event.ID, event.ID)
+	return err
+}
+
+// ForTodo returns the audit trail of a todo, oldest first
+func (p *AuditLogProjection) ForTodo(ctx context.Context, todoID int64) ([]AuditEntry, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT id, event_id, event_type, todo_id, changes, occurred_at, recorded_at
+		FROM audit_log WHERE todo_id = ? ORDER BY occurred_at, id`, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		var changes []byte
+		if err := rows.Scan(&entry.ID, &entry.EventID, &entry.EventType, &entry.TodoID, &changes,
+			&entry.OccurredAt, &entry.RecordedAt); err != nil {
+			return nil, err
+		}
+		if len(changes) > 0 {
+			if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,166 @@
+package projections
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo_app_go/internal/dedup"
+	"todo_app_go/internal/events"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+// Handler updates a read model from todo events
+type Handler interface {
+	Name() string
+	Handle(ctx context.Context, event events.TodoEvent) error
+}
+
+// TxHandler is a Handler that can apply an event within a transaction it does not own.
+// With a dedup.TxStore the dispatcher records the processed key in the same transaction.
+type TxHandler interface {
+	Handler
+	HandleTx(ctx context.Context, tx *sql.Tx, event events.TodoEvent) error
+}
+
+// Dispatcher routes events to the projection handlers registered for their type
+type Dispatcher struct {
+	handlers map[string][]Handler
+	all      []Handler
+	dedup    dedup.Store
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// WithDedup makes every handler skip events it has already processed. Keys are tracked per
+// projection, so a retry after a partial failure does not re-apply the projections that succeeded.
+// A TxHandler commits its key together with its update when the store is a dedup.TxStore;
+// other handlers mark the key only after they succeed, so a crash in between re-applies the
+// event on redelivery and such handlers must be idempotent per event ID.
+func (d *Dispatcher) WithDedup(store dedup.Store) *Dispatcher {
+	d.dedup = store
+	return d
+}
+
+// RegisterBuiltin registers the stats and audit log projections and returns the stats read model
+func RegisterBuiltin(d *Dispatcher, db *sql.DB) *StatsProjection {
+	stats := NewStatsProjection(db)
+	d.Register(stats,
+		events.TypeCreated, events.TypeUpdated, events.TypeCompleted, events.TypeReopened, events.TypeDeleted)
+	d.Register(NewAuditLogProjection(db))
+	return stats
+}
+
+// Register subscribes a handler to the given event types, or to all events if none are given
+func (d *Dispatcher) Register(handler Handler, eventTypes ...string) {
+	if len(eventTypes) == 0 {
+		d.all = append(d.all, handler)
+		return
+	}
+	for _, eventType := range eventTypes {
+		d.handlers[eventType] = append(d.handlers[eventType], handler)
+	}
+}
+
+// Dispatch passes the event to every matching handler and stops at the first error,
+// so that the consumer retries the whole event
+func (d *Dispatcher) Dispatch(ctx context.Context, event events.TodoEvent) error {
+	handlers := append(append([]Handler{}, d.handlers[event.Type]...), d.all...)
+	if len(handlers) == 0 {
+		logger.FromContext(ctx).Debug("No projection handlers for event", zap.String("event_type", event.Type))
+		return nil
+	}
+
+	for _, h := range handlers {
+		// События без ID (legacy) не дедуплицируются
+		if d.dedup == nil || event.ID == "" {
+			if err := d.handle(ctx, h, event, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		applied, err := d.handleOnce(ctx, h, h.Name()+":"+event.ID, event)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			metrics.ProjectionEventsDeduplicated.WithLabelValues(h.Name()).Inc()
+			logger.FromContext(ctx).Debug("Skipping already processed event",
+				zap.String("projection", h.Name()),
+				zap.String("event_id", event.ID))
+		}
+	}
+	return nil
+}
+
+// handleOnce applies the event unless the key is already processed and reports whether it did
+func (d *Dispatcher) handleOnce(ctx context.Context, h Handler, key string, event events.TodoEvent) (bool, error) {
+	txStore, ok := d.dedup.(dedup.TxStore)
+	if txHandler, isTx := h.(TxHandler); ok && isTx {
+		tx, err := txStore.BeginTx(ctx)
+		if err != nil {
+			return false, fmt.Errorf("projection %s: %w", h.Name(), err)
+		}
+		defer tx.Rollback()
+
+		// Ключ вставляется первым: конкурентная доставка ждёт фиксации и видит его
+		claimed, err := txStore.ClaimTx(ctx, tx, key)
+		if err != nil {
+			return false, fmt.Errorf("projection %s: dedup claim: %w", h.Name(), err)
+		}
+		if !claimed {
+			return false, nil
+		}
+		if err := d.handle(ctx, txHandler, event, tx); err != nil {
+			return false, err
+		}
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("projection %s: %w", h.Name(), err)
+		}
+		return true, nil
+	}
+
+	// Ключ записывается только после успешной обработки: сбой между ними приводит к повторному
+	// применению, а не к потере события
+	seen, err := d.dedup.Seen(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("projection %s: dedup lookup: %w", h.Name(), err)
+	}
+	if seen {
+		return false, nil
+	}
+	if err := d.handle(ctx, h, event, nil); err != nil {
+		return false, err
+	}
+	if err := d.dedup.Mark(ctx, key); err != nil {
+		return false, fmt.Errorf("projection %s: dedup mark: %w", h.Name(), err)
+	}
+	return true, nil
+}
+
+// handle runs the handler, within tx if it is set, and records its metrics
+func (d *Dispatcher) handle(ctx context.Context, h Handler, event events.TodoEvent, tx *sql.Tx) error {
+	start := time.Now()
+	var err error
+	if tx != nil {
+		err = h.(TxHandler).HandleTx(ctx, tx, event)
+	} else {
+		err = h.Handle(ctx, event)
+	}
+	metrics.ProjectionDuration.WithLabelValues(h.Name()).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.ProjectionEventsTotal.WithLabelValues(h.Name(), "error").Inc()
+		return fmt.Errorf("projection %s: %w", h.Name(), err)
+	}
+	metrics.ProjectionEventsTotal.WithLabelValues(h.Name(), "success").Inc()
+	return nil
+}
@@ -0,0 +1,174 @@
+package projections
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/dedup"
+	"todo_app_go/internal/events"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = logger.Init(config.LogConfig{Level: "debug", Format: "console"})
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	assert.NoError(t, database.EnsureProjectionTables(db))
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestStatsProjection(t *testing.T) {
+	db := newTestDB(t)
+	stats := NewStatsProjection(db)
+	ctx := context.Background()
+
+	open := models.Todo{ID: 1, Task: "Read"}
+	done := models.Todo{ID: 1, Task: "Read", Completed: true}
+
+	for _, event := range []events.TodoEvent{
+		events.CreateTodoCreatedEvent(open),
+		events.CreateTodoCreatedEvent(models.Todo{ID: 2, Task: "Write"}),
+		events.CreateTodoUpdatedEvent(open, done),
+		events.CreateTodoUpdatedEvent(done, done),
+		events.CreateTodoDeletedEvent(1, ""),
+	} {
+		assert.NoError(t, stats.Handle(ctx, event))
+	}
+
+	snapshot, err := stats.Snapshot(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), snapshot.CreatedTotal)
+	assert.Equal(t, int64(1), snapshot.CompletedTotal)
+	assert.Equal(t, int64(1), snapshot.DeletedTotal)
+	assert.Equal(t, int64(1), snapshot.OpenCount)
+	assert.Equal(t, int64(0), snapshot.CompletedCount)
+}
+
+func TestAuditLogProjection(t *testing.T) {
+	db := newTestDB(t)
+	audit := NewAuditLogProjection(db)
+	ctx := context.Background()
+
+	before := models.Todo{ID: 5, Task: "Old"}
+	after := models.Todo{ID: 5, Task: "New"}
+	created := events.CreateTodoCreatedEvent(before)
+	assert.NoError(t, audit.Handle(ctx, created))
+	assert.NoError(t, audit.Handle(ctx, events.CreateTodoUpdatedEvent(before, after)))
+	// Повторная доставка не дублирует запись
+	assert.NoError(t, audit.Handle(ctx, created))
+
+	entries, err := audit.ForTodo(ctx, 5)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, events.TypeCreated, entries[0].EventType)
+	assert.Equal(t, events.TypeUpdated, entries[1].EventType)
+	assert.Equal(t, "task", entries[1].Changes[0].Field)
+}
+
+type recordingHandler struct {
+	name string
+	err  error
+	seen []string
+}
+
+func (h *recordingHandler) Name() string { return h.name }
+func (h *recordingHandler) Handle(ctx context.Context, event events.TodoEvent) error {
+	h.seen = append(h.seen, event.Type)
+	return h.err
+}
+
+func TestDispatcher(t *testing.T) {
+	created := &recordingHandler{name: "created"}
+	all := &recordingHandler{name: "all"}
+
+	d := NewDispatcher()
+	d.Register(created, events.TypeCreated)
+	d.Register(all)
+
+	ctx := context.Background()
+	assert.NoError(t, d.Dispatch(ctx, events.CreateTodoCreatedEvent(models.Todo{ID: 1})))
+	assert.NoError(t, d.Dispatch(ctx, events.CreateTodoDeletedEvent(1, "")))
+
+	assert.Equal(t, []string{events.TypeCreated}, created.seen)
+	assert.Equal(t, []string{events.TypeCreated, events.TypeDeleted}, all.seen)
+
+	created.err = errors.New("boom")
+	err := d.Dispatch(ctx, events.CreateTodoCreatedEvent(models.Todo{ID: 2}))
+	assert.ErrorContains(t, err, "projection created")
+}
+
+func TestDispatcher_Dedup(t *testing.T) {
+	db := newTestDB(t)
+	assert.NoError(t, database.EnsureProcessedEventsTable(db))
+
+	first := &recordingHandler{name: "first"}
+	second := &recordingHandler{name: "second", err: errors.New("unavailable")}
+
+	d := NewDispatcher().WithDedup(dedup.NewSQLiteStore(db, time.Hour))
+	d.Register(first)
+	d.Register(second)
+
+	ctx := context.Background()
+	event := events.CreateTodoCreatedEvent(models.Todo{ID: 1})
+	assert.Error(t, d.Dispatch(ctx, event))
+
+	// Повторная доставка: first уже применил событие, second обрабатывает его снова
+	second.err = nil
+	assert.NoError(t, d.Dispatch(ctx, event))
+	assert.NoError(t, d.Dispatch(ctx, event))
+
+	assert.Len(t, first.seen, 1)
+	assert.Len(t, second.seen, 2)
+}
+
+// failingAfterWrite writes the audit entry and then fails, like a crash before commit
+type failingAfterWrite struct {
+	*AuditLogProjection
+	err error
+}
+
+func (h *failingAfterWrite) HandleTx(ctx context.Context, tx *sql.Tx, event events.TodoEvent) error {
+	if err := h.AuditLogProjection.HandleTx(ctx, tx, event); err != nil {
+		return err
+	}
+	return h.err
+}
+
+func TestDispatcher_DedupInTransaction(t *testing.T) {
+	db := newTestDB(t)
+	assert.NoError(t, database.EnsureProcessedEventsTable(db))
+
+	audit := &failingAfterWrite{AuditLogProjection: NewAuditLogProjection(db), err: errors.New("crash")}
+	d := NewDispatcher().WithDedup(dedup.NewSQLiteStore(db, time.Hour))
+	d.Register(audit)
+
+	ctx := context.Background()
+	event := events.CreateTodoCreatedEvent(models.Todo{ID: 7})
+	assert.Error(t, d.Dispatch(ctx, event))
+
+	// Запись проекции и ключ откатываются вместе: повтор применяет событие ровно один раз
+	entries, err := audit.ForTodo(ctx, 7)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	audit.err = nil
+	assert.NoError(t, d.Dispatch(ctx, event))
+	assert.NoError(t, d.Dispatch(ctx, event))
+	entries, err = audit.ForTodo(ctx, 7)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,118 @@
+package projections
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo_app_go/internal/events"
+)
+
+// Stats is the statistics read model
+type Stats struct {
+	CreatedTotal   int64     `json:"created_total"`
+	CompletedTotal int64     `json:"completed_total"`
+	ReopenedTotal  int64     `json:"reopened_total"`
+	DeletedTotal   int64     `json:"deleted_total"`
+	OpenCount      int64     `json:"open_count"`
+	CompletedCount int64     `json:"completed_count"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// StatsProjection maintains todo counters in the todo_stats table
+type StatsProjection struct {
+	db *sql.DB
+}
+
+func NewStatsProjection(db *sql.DB) *StatsProjection {
+	return &StatsProjection{db: db}
+}
+
+func (p *StatsProjection) Name() string {
+	return "stats"
+}
+
+func (p *StatsProjection) Handle(ctx context.Context, event events.TodoEvent) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := p.HandleTx(ctx, tx, event); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// HandleTx applies the event within the caller's transaction
+func (p *StatsProjection) HandleTx(ctx context.Context, tx *sql.Tx, event events.TodoEvent) error {
+	// Предыдущий статус todo в read-модели
+	var known, wasCompleted bool
+	err := tx.QueryRowContext(ctx, "SELECT completed FROM todo_stats_items WHERE todo_id = ?", event.TodoID).Scan(&wasCompleted)
+	switch {
+	case err == nil:
+		known = true
+	case err != sql.ErrNoRows:
+		return err
+	}
+
+	var update string
+	switch event.Type {
+	case events.TypeCreated:
+		if known {
+			return nil
+		}
+		update = "created_total = created_total + 1, open_count = open_count + 1"
+		if _, err := tx.ExecContext(ctx, "INSERT INTO todo_stats_items (todo_id, completed) VALUES (?, 0)", event.TodoID); err != nil {
+			return err
+		}
+	case events.TypeCompleted, events.TypeReopened, events.TypeUpdated:
+		completed := event.Payload.Completed
+		if !known || completed == wasCompleted {
+			return nil
+		}
+		if completed {
+			update = "completed_total = completed_total + 1, open_count = open_count - 1, completed_count = completed_count + 1"
+		} else {
+			update = "reopened_total = reopened_total + 1, open_count = open_count + 1, completed_count = completed_count - 1"
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE todo_stats_items SET completed = ? WHERE todo_id = ?", completed, event.TodoID); err != nil {
+			return err
+		}
+	case events.TypeDeleted:
+		if !known {
+			return nil
+		}
+		if wasCompleted {
+			update = "deleted_total = deleted_total + 1, completed_count = completed_count - 1"
+		} else {
+			update = "deleted_total = deleted_total + 1, open_count = open_count - 1"
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM todo_stats_items WHERE todo_id = ?", event.TodoID); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	query := fmt.Sprintf("UPDATE todo_stats SET %s, updated_at = ? WHERE id = 1", update)
+	_, err = tx.ExecContext(ctx, query, time.Now())
+	return err
+}
+
+// Snapshot returns the current statistics
+func (p *StatsProjection) Snapshot(ctx context.Context) (*Stats, error) {
+	var stats Stats
+	var updatedAt sql.NullTime
+	err := p.db.QueryRowContext(ctx, `SELECT created_total, completed_total, reopened_total, deleted_total,
+		open_count, completed_count, updated_at FROM todo_stats WHERE id = 1`).
+		Scan(&stats.CreatedTotal, &stats.CompletedTotal, &stats.ReopenedTotal, &stats.DeletedTotal,
+			&stats.OpenCount, &stats.CompletedCount, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	stats.UpdatedAt = updatedAt.Time
+	return &stats, nil
+}
@@ -0,0 +1,127 @@
+// Package requestctx carries request correlation data (request ID, W3C trace context,
+// actor and authenticated user) through context.Context, from HTTP handlers to event consumers.
+// The request ID, actor and user ID are also attached to the context logger (logger.FromContext).
+package requestctx
+
+import (
+	"context"
+	"strings"
+
+	"todo_app_go/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// HTTP-заголовки, из которых берутся значения
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceParent = "traceparent"
+	HeaderActor       = "X-Actor"
+)
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	traceParentKey
+	actorKey
+	userIDKey
+	scopesKey
+	workspaceIDKey
+)
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	ctx = logger.WithFields(ctx, zap.String("request_id", requestID))
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request ID stored in ctx or an empty string
+func RequestID(ctx context.Context) string {
+	v, _ := ctx.Value(requestIDKey).(string)
+	return v
+}
+
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentKey, traceParent)
+}
+
+// TraceParent returns the W3C traceparent stored in ctx or an empty string
+func TraceParent(ctx context.Context) string {
+	v, _ := ctx.Value(traceParentKey).(string)
+	return v
+}
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	ctx = logger.WithFields(ctx, zap.String("actor", actor))
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns the identity of the user or service that caused the request
+func Actor(ctx context.Context) string {
+	v, _ := ctx.Value(actorKey).(string)
+	return v
+}
+
+// WithUserID stores the ID of the authenticated user; set by the auth middleware
+func WithUserID(ctx context.Context, userID string) context.Context {
+	ctx = logger.WithFields(ctx, zap.String("user_id", userID))
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserID returns the authenticated user ID stored in ctx or an empty string
+func UserID(ctx context.Context) string {
+	v, _ := ctx.Value(userIDKey).(string)
+	return v
+}
+
+// WithScopes stores the scopes granted to the request credentials; set by the auth middleware
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesKey, scopes)
+}
+
+// Scopes returns the scopes stored in ctx or nil
+func Scopes(ctx context.Context) []string {
+	v, _ := ctx.Value(scopesKey).([]string)
+	return v
+}
+
+// WithWorkspaceID stores the workspace the request acts on; todos of the request belong to it
+// instead of the user's personal list
+func WithWorkspaceID(ctx context.Context, workspaceID string) context.Context {
+	ctx = logger.WithFields(ctx, zap.String("workspace_id", workspaceID))
+	return context.WithValue(ctx, workspaceIDKey, workspaceID)
+}
+
+// WorkspaceID returns the workspace ID stored in ctx or an empty string for personal todos
+func WorkspaceID(ctx context.Context) string {
+	v, _ := ctx.Value(workspaceIDKey).(string)
+	return v
+}
+
+// ParseTraceParent validates a version 00 traceparent and returns its trace ID and flags
+func ParseTraceParent(traceParent string) (traceID, flags string, ok bool) {
+	parts := strings.Split(traceParent, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return "", "", false
+	}
+	if !isHex(parts[1], 32) || !isHex(parts[2], 16) || !isHex(parts[3], 2) {
+		return "", "", false
+	}
+	// Нулевые trace-id и parent-id недопустимы
+	if parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return "", "", false
+	}
+	return parts[1], parts[3], true
+}
+
+func isHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/requestctx"
+	"todo_app_go/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// apiKeyTouchInterval ограничивает запись last_used_at: ключ, которым пользуются
+// на каждом запросе, не должен превращать каждое чтение в запись
+const apiKeyTouchInterval = time.Minute
+
+var (
+	// ErrInvalidAPIKey is returned for an unknown, revoked or expired API key
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyNotFound is returned when the user has no active key with the ID
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrAPIKeyExpiryInPast is returned when a key is created already expired
+	ErrAPIKeyExpiryInPast = errors.New("expires_at must be in the future")
+	// ErrUnknownScope is returned when a key is requested with a scope that does not exist
+	ErrUnknownScope = errors.New("unknown scope")
+)
+
+// APIKeyService mints, lists, revokes and verifies API keys of the current user
+type APIKeyService struct {
+	keys models.APIKeyRepository
+
+	mu      sync.Mutex
+	touched map[string]time.Time
+	now     func() time.Time
+}
+
+func NewAPIKeyService(keys models.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		keys:    keys,
+		touched: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// CreateAPIKey mints a key for the current user. The full key is set only on the
+// returned value and cannot be retrieved later.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req models.APIKeyCreateRequest) (_ *models.APIKey, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "APIKeyService.CreateAPIKey")
+	defer func() { tracing.End(span, err) }()
+
+	now := s.now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, ErrAPIKeyExpiryInPast
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+		}
+	}
+
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	apiKey := &models.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    requestctx.UserID(ctx),
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   auth.HashToken(key),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: now,
+	}
+	if err := s.keys.CreateAPIKey(ctx, apiKey); err != nil {
+		metrics.AuthOperationsTotal.WithLabelValues("apikey_create", "error").Inc()
+		return nil, err
+	}
+
+	metrics.AuthOperationsTotal.WithLabelValues("apikey_create", "success").Inc()
+	logger.FromContext(ctx).Info("API key created",
+		zap.String("key_id", apiKey.ID),
+		zap.String("prefix", prefix),
+		zap.Strings("scopes", apiKey.Scopes))
+	apiKey.Key = key
+	return apiKey, nil
+}
+
+// ListAPIKeys returns the current user's keys without their secrets
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) (_ []models.APIKey, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "APIKeyService.ListAPIKeys")
+	defer func() { tracing.End(span, err) }()
+
+	return s.keys.ListAPIKeys(ctx, requestctx.UserID(ctx))
+}
+
+// RevokeAPIKey revokes the current user's key; the key stops working immediately
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "APIKeyService.RevokeAPIKey")
+	defer func() { tracing.End(span, err) }()
+
+	revoked, err := s.keys.RevokeAPIKey(ctx, requestctx.UserID(ctx), id)
+	if err != nil {
+		metrics.AuthOperationsTotal.WithLabelValues("apikey_revoke", "error").Inc()
+		return err
+	}
+	if !revoked {
+		metrics.AuthOperationsTotal.WithLabelValues("apikey_revoke", "not_found").Inc()
+		return ErrAPIKeyNotFound
+	}
+
+	metrics.AuthOperationsTotal.WithLabelValues("apikey_revoke", "success").Inc()
+	logger.FromContext(ctx).Info("API key revoked", zap.String("key_id", id))
+	return nil
+}
+
+// VerifyAPIKey returns the owner and scopes of an active key and records its use
+func (s *APIKeyService) VerifyAPIKey(ctx context.Context, key string) (userID string, scopes []string, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "APIKeyService.VerifyAPIKey")
+	defer func() { tracing.End(span, err) }()
+
+	apiKey, err := s.keys.GetAPIKeyByHash(ctx, auth.HashToken(key))
+	if err != nil {
+		return "", nil, err
+	}
+	now := s.now()
+	if apiKey == nil || apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt)) {
+		metrics.AuthOperationsTotal.WithLabelValues("apikey_verify", "invalid").Inc()
+		return "", nil, ErrInvalidAPIKey
+	}
+
+	if s.shouldTouch(apiKey.ID, now) {
+		// Ошибка записи времени использования не должна отклонять запрос
+		if err := s.keys.TouchAPIKey(ctx, apiKey.ID, now); err != nil {
+			logger.FromContext(ctx).Warn("Failed to record API key use", zap.String("key_id", apiKey.ID), zap.Error(err))
+		}
+	}
+	return apiKey.UserID, apiKey.Scopes, nil
+}
+
+func (s *APIKeyService) shouldTouch(id string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.touched[id]; ok && now.Sub(last) < apiKeyTouchInterval {
+		return false
+	}
+	s.touched[id] = now
+	// Ключей у пользователей немного, но карта не должна расти бесконечно
+	if len(s.touched) > 10000 {
+		clear(s.touched)
+		s.touched[id] = now
+	}
+	return true
+}
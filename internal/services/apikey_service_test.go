@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/requestctx"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIKeyService(t *testing.T) *APIKeyService {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	require.NoError(t, database.EnsureAPIKeysTable(db))
+	return NewAPIKeyService(models.NewSQLiteAPIKeyRepository(db))
+}
+
+func TestAPIKeyService_CreateVerifyRevoke(t *testing.T) {
+	service := newTestAPIKeyService(t)
+	alice := requestctx.WithUserID(context.Background(), "alice")
+	bob := requestctx.WithUserID(context.Background(), "bob")
+
+	created, err := service.CreateAPIKey(alice, models.APIKeyCreateRequest{Name: "ci", Scopes: []string{auth.ScopeTodosRead}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix+"_"))
+	assert.True(t, auth.IsAPIKey(created.Key))
+
+	userID, scopes, err := service.VerifyAPIKey(context.Background(), created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", userID)
+	assert.Equal(t, []string{auth.ScopeTodosRead}, scopes)
+
+	_, _, err = service.VerifyAPIKey(context.Background(), created.Key+"x")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	// Секрет не возвращается в списке, время использования записано
+	keys, err := service.ListAPIKeys(alice)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Empty(t, keys[0].Key)
+	assert.NotNil(t, keys[0].LastUsedAt)
+	keys, err = service.ListAPIKeys(bob)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	assert.ErrorIs(t, service.RevokeAPIKey(bob, created.ID), ErrAPIKeyNotFound)
+	require.NoError(t, service.RevokeAPIKey(alice, created.ID))
+	assert.ErrorIs(t, service.RevokeAPIKey(alice, created.ID), ErrAPIKeyNotFound)
+
+	_, _, err = service.VerifyAPIKey(context.Background(), created.Key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAPIKeyService_Expiry(t *testing.T) {
+	service := newTestAPIKeyService(t)
+	ctx := requestctx.WithUserID(context.Background(), "alice")
+
+	past := time.Now().Add(-time.Minute)
+	_, err := service.CreateAPIKey(ctx, models.APIKeyCreateRequest{Name: "old", Scopes: []string{auth.ScopeAdmin}, ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrAPIKeyExpiryInPast)
+
+	_, err = service.CreateAPIKey(ctx, models.APIKeyCreateRequest{Name: "bad", Scopes: []string{"todos:delete"}})
+	assert.ErrorIs(t, err, ErrUnknownScope)
+
+	expires := time.Now().Add(time.Hour)
+	created, err := service.CreateAPIKey(ctx, models.APIKeyCreateRequest{Name: "tmp", Scopes: []string{auth.ScopeAdmin}, ExpiresAt: &expires})
+	require.NoError(t, err)
+	_, _, err = service.VerifyAPIKey(context.Background(), created.Key)
+	require.NoError(t, err)
+
+	service.now = func() time.Time { return expires.Add(time.Second) }
+	_, _, err = service.VerifyAPIKey(context.Background(), created.Key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/notify"
+	"todo_app_go/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxPasswordBytes — bcrypt учитывает только первые 72 байта пароля
+const maxPasswordBytes = 72
+
+var (
+	// ErrInvalidCredentials is returned for an unknown email or a wrong password
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrInvalidRefreshToken is returned for an unknown, expired or revoked refresh token
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrInvalidResetToken is returned for an unknown, used or expired password reset token
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	// ErrPasswordTooLong is returned for passwords that bcrypt would truncate
+	ErrPasswordTooLong = errors.New("password must not exceed 72 bytes")
+	// ErrTooManyLoginAttempts is wrapped by LoginRateLimitError
+	ErrTooManyLoginAttempts = errors.New("too many login attempts")
+)
+
+// LoginRateLimitError is returned when login attempts for the email or client IP are blocked
+type LoginRateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginRateLimitError) Error() string { return ErrTooManyLoginAttempts.Error() }
+
+func (e *LoginRateLimitError) Unwrap() error { return ErrTooManyLoginAttempts }
+
+// AuthService manages local accounts: registration, password login and token rotation
+type AuthService struct {
+	users    models.UserRepository
+	tokens   *auth.TokenIssuer
+	limiter  *auth.LoginLimiter
+	notifier notify.Notifier
+	cfg      config.LocalAuthConfig
+}
+
+func NewAuthService(users models.UserRepository, tokens *auth.TokenIssuer, cfg config.LocalAuthConfig) *AuthService {
+	return &AuthService{
+		users:    users,
+		tokens:   tokens,
+		limiter:  auth.NewLoginLimiter(cfg.RateLimit.MaxAttempts, cfg.RateLimit.Window),
+		notifier: notify.LogNotifier{},
+		cfg:      cfg,
+	}
+}
+
+// WithNotifier задаёт канал доставки токенов сброса пароля
+func (s *AuthService) WithNotifier(notifier notify.Notifier) *AuthService {
+	s.notifier = notifier
+	return s
+}
+
+// Register creates an account; the email is case-insensitive
+func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest) (_ *models.User, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AuthService.Register")
+	defer func() { tracing.End(span, err) }()
+
+	if len(req.Password) > maxPasswordBytes {
+		return nil, ErrPasswordTooLong
+	}
+	hash, err := auth.HashPassword(req.Password, s.cfg.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        normalizeEmail(req.Email),
+		PasswordHash: hash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.users.CreateUser(ctx, user); err != nil {
+		if errors.Is(err, models.ErrEmailTaken) {
+			metrics.AuthOperationsTotal.WithLabelValues("register", "email_taken").Inc()
+		} else {
+			metrics.AuthOperationsTotal.WithLabelValues("register", "error").Inc()
+		}
+		return nil, err
+	}
+
+	metrics.AuthOperationsTotal.WithLabelValues("register", "success").Inc()
+	logger.FromContext(ctx).Info("User registered", zap.String("user_id", user.ID))
+	return user, nil
+}
+
+// Login checks the password and issues a token pair. Failed attempts are counted per
+// email and per client IP; a successful login clears the email counter only, so that
+// logging into one's own account does not unlock guessing other passwords from that IP.
+func (s *AuthService) Login(ctx context.Context, req models.LoginRequest, clientIP string) (_ *models.TokenResponse, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AuthService.Login")
+	defer func() { tracing.End(span, err) }()
+
+	emailKey := "email:" + normalizeEmail(req.Email)
+	ipKey := "ip:" + clientIP
+	if retry := max(s.limiter.RetryAfter(emailKey), s.limiter.RetryAfter(ipKey)); retry > 0 {
+		metrics.AuthOperationsTotal.WithLabelValues("login", "rate_limited").Inc()
+		return nil, &LoginRateLimitError{RetryAfter: retry}
+	}
+
+	user, err := s.users.GetUserByEmail(ctx, normalizeEmail(req.Email))
+	if err != nil {
+		metrics.AuthOperationsTotal.WithLabelValues("login", "error").Inc()
+		return nil, err
+	}
+	var hash string
+	if user != nil {
+		hash = user.PasswordHash
+	}
+	if !auth.CheckPassword(hash, req.Password) {
+		s.limiter.Fail(emailKey)
+		s.limiter.Fail(ipKey)
+		metrics.AuthOperationsTotal.WithLabelValues("login", "invalid_credentials").Inc()
+		return nil, ErrInvalidCredentials
+	}
+	s.limiter.Reset(emailKey)
+
+	tokens, err := s.issueTokens(ctx, user.ID)
+	if err != nil {
+		metrics.AuthOperationsTotal.WithLabelValues("login", "error").Inc()
+		return nil, err
+	}
+
+	metrics.AuthOperationsTotal.WithLabelValues("login", "success").Inc()
+	logger.FromContext(ctx).Info("User logged in", zap.String("user_id", user.ID))
+	return tokens, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The old refresh token is
+// revoked; presenting a revoked token again means it leaked, so all sessions of the
+// user are revoked.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (_ *models.TokenResponse, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AuthService.Refresh")
+	defer func() { tracing.End(span, err) }()
+
+	next, raw, err := s.newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	old, err := s.users.RotateRefreshToken(ctx, auth.HashToken(refreshToken), next)
+	if err != nil {
+		metrics.AuthOperationsTotal.WithLabelValues("refresh", "error").Inc()
+		return nil, err
+	}
+
+	switch {
+	case old == nil:
+		metrics.AuthOperationsTotal.WithLabelValues("refresh", "invalid").Inc()
+		return nil, ErrInvalidRefreshToken
+	case old.RevokedAt != nil:
+		logger.FromContext(ctx).Warn("Revoked refresh token reused, revoking all sessions of the user",
+			zap.String("user_id", old.UserID))
+		if err := s.users.RevokeUserRefreshTokens(ctx, old.UserID); err != nil {
+			return nil, err
+		}
+		metrics.AuthOperationsTotal.WithLabelValues("refresh", "reused").Inc()
+		return nil, ErrInvalidRefreshToken
+	case !time.Now().Before(old.ExpiresAt):
+		metrics.AuthOperationsTotal.WithLabelValues("refresh", "expired").Inc()
+		return nil, ErrInvalidRefreshToken
+	}
+
+	access, expiresAt, err := s.tokens.Issue(old.UserID)
+	if err != nil {
+		metrics.AuthOperationsTotal.WithLabelValues("refresh", "error").Inc()
+		return nil, err
+	}
+
+	metrics.AuthOperationsTotal.WithLabelValues("refresh", "success").Inc()
+	return tokenResponse(access, expiresAt, raw), nil
+}
+
+// Logout revokes the refresh token. Access tokens stay valid until they expire,
+// which is why their lifetime is short.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AuthService.Logout")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.users.RevokeRefreshToken(ctx, auth.HashToken(refreshToken)); err != nil {
+		metrics.AuthOperationsTotal.WithLabelValues("logout", "error").Inc()
+		return err
+	}
+	metrics.AuthOperationsTotal.WithLabelValues("logout", "success").Inc()
+	return nil
+}
+
+// RequestPasswordReset sends a one-time reset token through the notifier. The result
+// does not depend on whether the email is registered, so it cannot be used to find accounts.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AuthService.RequestPasswordReset")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.users.GetUserByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	reset := &models.PasswordReset{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(s.cfg.ResetTokenTTL),
+		CreatedAt: now,
+	}
+	if err := s.users.CreatePasswordReset(ctx, reset); err != nil {
+		return err
+	}
+
+	// Ошибка доставки не возвращается клиенту: иначе ответ выдавал бы существование email
+	if err := s.notifier.SendPasswordReset(ctx, user.Email, token, reset.ExpiresAt); err != nil {
+		logger.FromContext(ctx).Error("Failed to send password reset", zap.String("user_id", user.ID), zap.Error(err))
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and revokes all sessions of the user
+func (s *AuthService) ResetPassword(ctx context.Context, req models.PasswordResetConfirmRequest) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AuthService.ResetPassword")
+	defer func() { tracing.End(span, err) }()
+
+	if len(req.Password) > maxPasswordBytes {
+		return ErrPasswordTooLong
+	}
+	hash, err := auth.HashPassword(req.Password, s.cfg.BcryptCost)
+	if err != nil {
+		return err
+	}
+
+	userID, err := s.users.ResetPassword(ctx, auth.HashToken(req.Token), hash)
+	if err != nil {
+		metrics.AuthOperationsTotal.WithLabelValues("password_reset", "error").Inc()
+		return err
+	}
+	if userID == "" {
+		metrics.AuthOperationsTotal.WithLabelValues("password_reset", "invalid").Inc()
+		return ErrInvalidResetToken
+	}
+
+	metrics.AuthOperationsTotal.WithLabelValues("password_reset", "success").Inc()
+	logger.FromContext(ctx).Info("Password reset", zap.String("user_id", userID))
+	return nil
+}
+
+// issueTokens creates an access token and a stored refresh token for the user
+func (s *AuthService) issueTokens(ctx context.Context, userID string) (*models.TokenResponse, error) {
+	access, expiresAt, err := s.tokens.Issue(userID)
+	if err != nil {
+		return nil, err
+	}
+	refresh, raw, err := s.newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	refresh.UserID = userID
+	if err := s.users.CreateRefreshToken(ctx, refresh); err != nil {
+		return nil, err
+	}
+	return tokenResponse(access, expiresAt, raw), nil
+}
+
+// newRefreshToken generates a refresh token; the record keeps only its hash
+func (s *AuthService) newRefreshToken() (*models.RefreshToken, string, error) {
+	raw, err := auth.GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	return &models.RefreshToken{
+		TokenHash: auth.HashToken(raw),
+		ExpiresAt: now.Add(s.cfg.RefreshTokenTTL),
+		CreatedAt: now,
+	}, raw, nil
+}
+
+func tokenResponse(access string, expiresAt time.Time, refresh string) *models.TokenResponse {
+	return &models.TokenResponse{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(expiresAt).Round(time.Second).Seconds()),
+		RefreshToken: refresh,
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type recordingNotifier struct {
+	email string
+	token string
+}
+
+func (n *recordingNotifier) SendPasswordReset(ctx context.Context, email, token string, expiresAt time.Time) error {
+	n.email, n.token = email, token
+	return nil
+}
+
+func newTestAuthService(t *testing.T) (*AuthService, *auth.Validator, *recordingNotifier) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	require.NoError(t, database.EnsureUserTables(db))
+
+	jwtCfg := config.JWTConfig{Secret: "secret", Issuer: "todo-app", Audience: "todo-api"}
+	issuer, err := auth.NewTokenIssuer(jwtCfg, 15*time.Minute)
+	require.NoError(t, err)
+	validator, err := auth.NewValidator(jwtCfg)
+	require.NoError(t, err)
+
+	notifier := &recordingNotifier{}
+	service := NewAuthService(models.NewSQLiteUserRepository(db), issuer, config.LocalAuthConfig{
+		RefreshTokenTTL: time.Hour,
+		ResetTokenTTL:   time.Hour,
+		BcryptCost:      bcrypt.MinCost,
+		RateLimit:       config.LoginRateLimitConfig{MaxAttempts: 3, Window: time.Minute},
+	}).WithNotifier(notifier)
+	return service, validator, notifier
+}
+
+func TestAuthService_RegisterLoginRefreshLogout(t *testing.T) {
+	service, validator, _ := newTestAuthService(t)
+	ctx := context.Background()
+
+	user, err := service.Register(ctx, models.RegisterRequest{Email: " Alice@Example.com", Password: "correct horse"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", user.Email)
+	assert.NotEqual(t, "correct horse", user.PasswordHash)
+
+	_, err = service.Register(ctx, models.RegisterRequest{Email: "alice@example.com", Password: "another one"})
+	assert.ErrorIs(t, err, models.ErrEmailTaken)
+
+	_, err = service.Login(ctx, models.LoginRequest{Email: "alice@example.com", Password: "wrong"}, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = service.Login(ctx, models.LoginRequest{Email: "bob@example.com", Password: "correct horse"}, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	tokens, err := service.Login(ctx, models.LoginRequest{Email: "ALICE@example.com", Password: "correct horse"}, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, int64(900), tokens.ExpiresIn)
+
+	// Выданный access-токен принимает тот же валидатор, что и middleware
+	claims, err := validator.Validate(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.Subject)
+
+	// Refresh-токен одноразовый: после обмена старый больше не действует
+	rotated, err := service.Refresh(ctx, tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshToken, rotated.RefreshToken)
+
+	// Повторное использование старого токена отзывает все сессии, включая новую
+	_, err = service.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = service.Refresh(ctx, rotated.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Logout отзывает refresh-токен
+	tokens, err = service.Login(ctx, models.LoginRequest{Email: "alice@example.com", Password: "correct horse"}, "10.0.0.1")
+	require.NoError(t, err)
+	require.NoError(t, service.Logout(ctx, tokens.RefreshToken))
+	_, err = service.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	_, err = service.Refresh(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestAuthService_LoginRateLimit(t *testing.T) {
+	service, _, _ := newTestAuthService(t)
+	ctx := context.Background()
+
+	_, err := service.Register(ctx, models.RegisterRequest{Email: "alice@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = service.Login(ctx, models.LoginRequest{Email: "alice@example.com", Password: "guess"}, "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	// Заблокирован и email, и IP: правильный пароль тоже отклоняется
+	_, err = service.Login(ctx, models.LoginRequest{Email: "alice@example.com", Password: "correct horse"}, "10.0.0.2")
+	var limited *LoginRateLimitError
+	require.ErrorAs(t, err, &limited)
+	assert.Greater(t, limited.RetryAfter, time.Duration(0))
+	assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+
+	_, err = service.Login(ctx, models.LoginRequest{Email: "bob@example.com", Password: "guess"}, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+}
+
+func TestAuthService_PasswordReset(t *testing.T) {
+	service, _, notifier := newTestAuthService(t)
+	ctx := context.Background()
+
+	_, err := service.Register(ctx, models.RegisterRequest{Email: "alice@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+	session, err := service.Login(ctx, models.LoginRequest{Email: "alice@example.com", Password: "correct horse"}, "10.0.0.1")
+	require.NoError(t, err)
+
+	// Неизвестный email не отличается от известного
+	require.NoError(t, service.RequestPasswordReset(ctx, "nobody@example.com"))
+	assert.Empty(t, notifier.token)
+
+	require.NoError(t, service.RequestPasswordReset(ctx, "Alice@example.com"))
+	assert.Equal(t, "alice@example.com", notifier.email)
+	require.NotEmpty(t, notifier.token)
+
+	assert.ErrorIs(t, service.ResetPassword(ctx, models.PasswordResetConfirmRequest{Token: "wrong", Password: "battery staple"}), ErrInvalidResetToken)
+	require.NoError(t, service.ResetPassword(ctx, models.PasswordResetConfirmRequest{Token: notifier.token, Password: "battery staple"}))
+	// Токен одноразовый
+	assert.ErrorIs(t, service.ResetPassword(ctx, models.PasswordResetConfirmRequest{Token: notifier.token, Password: "third password"}), ErrInvalidResetToken)
+
+	_, err = service.Login(ctx, models.LoginRequest{Email: "alice@example.com", Password: "correct horse"}, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = service.Login(ctx, models.LoginRequest{Email: "alice@example.com", Password: "battery staple"}, "10.0.0.1")
+	assert.NoError(t, err)
+
+	// Сессии со старым паролем отозваны
+	_, err = service.Refresh(ctx, session.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
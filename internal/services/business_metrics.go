@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// SyncTodoGauges sets the open and completed gauges from the repository counts.
+// Between syncs the gauges are updated incrementally by the service methods;
+// repositories that cannot count are skipped.
+func (s *TodoService) SyncTodoGauges(ctx context.Context) error {
+	counter, ok := s.repo.(models.TodoCounter)
+	if !ok {
+		return nil
+	}
+
+	open, completed, err := counter.CountByStatus(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.TodosOpen.Set(float64(open))
+	metrics.TodosCompleted.Set(float64(completed))
+	return nil
+}
+
+// RunTodoGaugeSync syncs the gauges immediately and then every interval until ctx is cancelled.
+// Periodic sync corrects drift from changes made by other replicas.
+func (s *TodoService) RunTodoGaugeSync(ctx context.Context, interval time.Duration) {
+	refresh := func() {
+		if err := s.SyncTodoGauges(ctx); err != nil && ctx.Err() == nil {
+			logger.Warn("Failed to sync todo gauges", zap.Error(err))
+		}
+	}
+
+	refresh()
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// recordTodoCreated учитывает новую todo в бизнес-метриках
+func recordTodoCreated(todo *models.Todo) {
+	metrics.TodosCreatedTotal.Inc()
+	statusGauge(todo.Completed).Inc()
+}
+
+// recordTodoUpdated учитывает смену статуса: выполнение или повторное открытие
+func recordTodoUpdated(before, after *models.Todo) {
+	if before == nil || before.Completed == after.Completed {
+		return
+	}
+	statusGauge(before.Completed).Dec()
+	statusGauge(after.Completed).Inc()
+
+	if after.Completed {
+		metrics.TodosCompletedTotal.Inc()
+		metrics.TodoTimeToComplete.Observe(after.UpdatedAt.Sub(after.CreatedAt).Seconds())
+	}
+}
+
+// recordTodoDeleted убирает удалённую todo из gauge её статуса
+func recordTodoDeleted(todo *models.Todo) {
+	if todo != nil {
+		statusGauge(todo.Completed).Dec()
+	}
+}
+
+func statusGauge(completed bool) prometheus.Gauge {
+	if completed {
+		return metrics.TodosCompleted
+	}
+	return metrics.TodosOpen
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTodoService_BusinessMetrics(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE todos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		owner_id TEXT NOT NULL DEFAULT '',
+		task TEXT NOT NULL,
+		completed BOOLEAN NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	)`)
+	require.NoError(t, err)
+
+	repo := models.NewSQLiteTodoRepository(db)
+	service := NewTodoService(repo, nil, nil)
+	ctx := context.Background()
+
+	// Уже существующие todo попадают в gauge при сверке
+	existing, err := repo.Create(ctx, "", "Existing")
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateStatus(ctx, "", existing.ID, true))
+	require.NoError(t, service.SyncTodoGauges(ctx))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.TodosOpen))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.TodosCompleted))
+
+	created := testutil.ToFloat64(metrics.TodosCreatedTotal)
+	completedTotal := testutil.ToFloat64(metrics.TodosCompletedTotal)
+	observations := timeToCompleteCount(t)
+
+	todo, err := service.CreateTodo(ctx, models.TodoCreateRequest{Task: "Ship it"})
+	require.NoError(t, err)
+	assert.Equal(t, created+1, testutil.ToFloat64(metrics.TodosCreatedTotal))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.TodosOpen))
+
+	// Изменение текста не меняет статус
+	task := "Ship it today"
+	_, err = service.UpdateTodo(ctx, todo.ID, models.TodoUpdateRequest{Task: &task})
+	require.NoError(t, err)
+	assert.Equal(t, completedTotal, testutil.ToFloat64(metrics.TodosCompletedTotal))
+
+	done := true
+	_, err = service.UpdateTodo(ctx, todo.ID, models.TodoUpdateRequest{Completed: &done})
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.TodosOpen))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.TodosCompleted))
+	assert.Equal(t, completedTotal+1, testutil.ToFloat64(metrics.TodosCompletedTotal))
+	assert.Equal(t, observations+1, timeToCompleteCount(t))
+
+	require.NoError(t, service.DeleteTodo(ctx, todo.ID))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.TodosCompleted))
+
+	// Инкрементальные значения совпадают с базой
+	require.NoError(t, service.SyncTodoGauges(ctx))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.TodosOpen))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.TodosCompleted))
+}
+
+func timeToCompleteCount(t *testing.T) uint64 {
+	var m dto.Metric
+	require.NoError(t, metrics.TodoTimeToComplete.Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidOIDCState is returned for an unknown, used or expired login state
+	ErrInvalidOIDCState = errors.New("invalid or expired login state")
+	// ErrOIDCAccountNotAllowed is returned when the provider account has no usable email,
+	// the provider says the email is not verified or its domain is not allowed
+	ErrOIDCAccountNotAllowed = errors.New("provider account is not allowed to sign in")
+	// ErrOIDCEmailNotVerified is returned when a local account with the email exists,
+	// but the provider does not confirm that the email is verified
+	ErrOIDCEmailNotVerified = errors.New("email is already registered and is not verified by the provider")
+)
+
+// OIDCService signs users in through an external OIDC provider. A provider account is
+// mapped to a local user by issuer and subject; on first sign-in it is linked to the local
+// account with the same verified email or a new account without a password is created.
+type OIDCService struct {
+	auth       *AuthService
+	identities models.IdentityRepository
+	provider   *auth.OIDCProvider
+	cfg        config.OIDCConfig
+}
+
+func NewOIDCService(authService *AuthService, identities models.IdentityRepository, provider *auth.OIDCProvider, cfg config.OIDCConfig) *OIDCService {
+	return &OIDCService{
+		auth:       authService,
+		identities: identities,
+		provider:   provider,
+		cfg:        cfg,
+	}
+}
+
+// StartLogin stores a new login and returns the provider URL to redirect the user to
+// and the state that the callback must present
+func (s *OIDCService) StartLogin(ctx context.Context) (authURL, state string, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "OIDCService.StartLogin")
+	defer func() { tracing.End(span, err) }()
+
+	state, err = auth.GenerateToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := auth.GenerateToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := auth.GeneratePKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err = s.provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	if err := s.identities.CreateOIDCLogin(ctx, &models.OIDCLogin{
+		StateHash:    auth.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(s.cfg.StateTTL),
+		CreatedAt:    now,
+	}); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// Callback completes the login: it redeems the code, maps the provider account to
+// a local user and issues a token pair
+func (s *OIDCService) Callback(ctx context.Context, state, code string) (_ *models.TokenResponse, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "OIDCService.Callback")
+	defer func() { tracing.End(span, err) }()
+
+	login, err := s.identities.ConsumeOIDCLogin(ctx, auth.HashToken(state))
+	if err != nil {
+		metrics.AuthOperationsTotal.WithLabelValues("oidc_login", "error").Inc()
+		return nil, err
+	}
+	if login == nil {
+		metrics.AuthOperationsTotal.WithLabelValues("oidc_login", "invalid_state").Inc()
+		return nil, ErrInvalidOIDCState
+	}
+
+	idToken, err := s.provider.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		metrics.AuthOperationsTotal.WithLabelValues("oidc_login", "exchange_failed").Inc()
+		return nil, err
+	}
+
+	user, err := s.resolveUser(ctx, idToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrOIDCAccountNotAllowed):
+			metrics.AuthOperationsTotal.WithLabelValues("oidc_login", "not_allowed").Inc()
+		case errors.Is(err, ErrOIDCEmailNotVerified):
+			metrics.AuthOperationsTotal.WithLabelValues("oidc_login", "email_not_verified").Inc()
+		default:
+			metrics.AuthOperationsTotal.WithLabelValues("oidc_login", "error").Inc()
+		}
+		return nil, err
+	}
+
+	tokens, err := s.auth.issueTokens(ctx, user.ID)
+	if err != nil {
+		metrics.AuthOperationsTotal.WithLabelValues("oidc_login", "error").Inc()
+		return nil, err
+	}
+
+	metrics.AuthOperationsTotal.WithLabelValues("oidc_login", "success").Inc()
+	logger.FromContext(ctx).Info("User logged in with OIDC",
+		zap.String("user_id", user.ID),
+		zap.String("issuer", idToken.Issuer))
+	return tokens, nil
+}
+
+// resolveUser maps the provider account to a local user, linking or creating one on first sign-in
+func (s *OIDCService) resolveUser(ctx context.Context, idToken *auth.IDToken) (*models.User, error) {
+	email := normalizeEmail(idToken.Email)
+	if email == "" || (idToken.EmailVerified != nil && !*idToken.EmailVerified) || !s.domainAllowed(email) {
+		return nil, ErrOIDCAccountNotAllowed
+	}
+
+	user, err := s.identities.GetUserByIdentity(ctx, idToken.Issuer, idToken.Subject)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	now := time.Now()
+	identity := &models.UserIdentity{
+		Issuer:    idToken.Issuer,
+		Subject:   idToken.Subject,
+		Email:     email,
+		CreatedAt: now,
+	}
+
+	user, err = s.auth.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		// Привязка к существующей учётной записи только по подтверждённому провайдером email,
+		// иначе чужой аккаунт у провайдера получил бы доступ к локальному
+		if idToken.EmailVerified == nil {
+			return nil, ErrOIDCEmailNotVerified
+		}
+		identity.UserID = user.ID
+		if err := s.identities.CreateIdentity(ctx, identity); err != nil {
+			return nil, err
+		}
+		logger.FromContext(ctx).Info("OIDC identity linked", zap.String("user_id", user.ID))
+		return user, nil
+	}
+
+	// Пароля нет: такой пользователь входит только через провайдер или после сброса пароля
+	user = &models.User{
+		ID:        uuid.New().String(),
+		Email:     email,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.identities.CreateUserWithIdentity(ctx, user, identity); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Info("User registered with OIDC", zap.String("user_id", user.ID))
+	return user, nil
+}
+
+func (s *OIDCService) domainAllowed(email string) bool {
+	if len(s.cfg.AllowedDomains) == 0 {
+		return true
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return slices.ContainsFunc(s.cfg.AllowedDomains, func(allowed string) bool {
+		return strings.EqualFold(allowed, domain)
+	})
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/auth/oidctest"
+	"todo_app_go/internal/config"
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestOIDCService(t *testing.T, provider *oidctest.Provider, allowedDomains ...string) (*OIDCService, *AuthService, *auth.Validator) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	require.NoError(t, database.EnsureUserTables(db))
+
+	jwtCfg := config.JWTConfig{Secret: "secret"}
+	issuer, err := auth.NewTokenIssuer(jwtCfg, time.Minute)
+	require.NoError(t, err)
+	validator, err := auth.NewValidator(jwtCfg)
+	require.NoError(t, err)
+
+	cfg := config.OIDCConfig{
+		Issuer:         provider.Issuer(),
+		ClientID:       provider.ClientID,
+		ClientSecret:   provider.ClientSecret,
+		RedirectURL:    "http://todo.test/auth/oidc/callback",
+		Scopes:         []string{"openid", "email"},
+		AllowedDomains: allowedDomains,
+		StateTTL:       time.Minute,
+	}
+	oidcProvider, err := auth.NewOIDCProvider(cfg)
+	require.NoError(t, err)
+
+	users := models.NewSQLiteUserRepository(db)
+	authService := NewAuthService(users, issuer, config.LocalAuthConfig{RefreshTokenTTL: time.Hour, BcryptCost: bcrypt.MinCost})
+	return NewOIDCService(authService, users, oidcProvider, cfg), authService, validator
+}
+
+// oidcLogin runs the whole flow for the user currently set on the provider
+func oidcLogin(t *testing.T, service *OIDCService) (*models.TokenResponse, error) {
+	t.Helper()
+	ctx := context.Background()
+	authURL, state, err := service.StartLogin(ctx)
+	require.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, state, callback.Query().Get("state"))
+
+	return service.Callback(ctx, state, callback.Query().Get("code"))
+}
+
+func TestOIDCService_LinksVerifiedEmail(t *testing.T) {
+	provider := oidctest.NewProvider("todo-app", "client-secret")
+	defer provider.Close()
+	service, authService, validator := newTestOIDCService(t, provider)
+
+	local, err := authService.Register(context.Background(), models.RegisterRequest{Email: "alice@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+
+	// Без email_verified привязка к существующей учётной записи запрещена
+	provider.SetUser(map[string]interface{}{"sub": "alice-sso", "email": "Alice@example.com"})
+	_, err = oidcLogin(t, service)
+	assert.ErrorIs(t, err, ErrOIDCEmailNotVerified)
+
+	provider.SetUser(map[string]interface{}{"sub": "alice-sso", "email": "Alice@example.com", "email_verified": true})
+	tokens, err := oidcLogin(t, service)
+	require.NoError(t, err)
+	claims, err := validator.Validate(context.Background(), tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, local.ID, claims.Subject)
+
+	// Новый аккаунт провайдера создаёт нового пользователя без пароля
+	provider.SetUser(map[string]interface{}{"sub": "bob-sso", "email": "bob@example.com"})
+	tokens, err = oidcLogin(t, service)
+	require.NoError(t, err)
+	claims, err = validator.Validate(context.Background(), tokens.AccessToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, local.ID, claims.Subject)
+	_, err = authService.Login(context.Background(), models.LoginRequest{Email: "bob@example.com", Password: ""}, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestOIDCService_RejectsAccounts(t *testing.T) {
+	provider := oidctest.NewProvider("todo-app", "")
+	defer provider.Close()
+	service, _, _ := newTestOIDCService(t, provider, "example.com")
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{"no email", map[string]interface{}{"sub": "1"}},
+		{"unverified email", map[string]interface{}{"sub": "2", "email": "carol@example.com", "email_verified": false}},
+		{"other domain", map[string]interface{}{"sub": "3", "email": "mallory@evil.test", "email_verified": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.SetUser(tt.claims)
+			_, err := oidcLogin(t, service)
+			assert.ErrorIs(t, err, ErrOIDCAccountNotAllowed)
+		})
+	}
+}
+
+func TestOIDCService_InvalidState(t *testing.T) {
+	provider := oidctest.NewProvider("todo-app", "")
+	defer provider.Close()
+	service, _, _ := newTestOIDCService(t, provider)
+
+	_, err := service.Callback(context.Background(), "unknown", "code")
+	assert.ErrorIs(t, err, ErrInvalidOIDCState)
+
+	// Код не подходит к другому входу: PKCE verifier не совпадает с challenge
+	authURL, _, err := service.StartLogin(context.Background())
+	require.NoError(t, err)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+
+	_, otherState, err := service.StartLogin(context.Background())
+	require.NoError(t, err)
+	_, err = service.Callback(context.Background(), otherState, callback.Query().Get("code"))
+	assert.ErrorIs(t, err, auth.ErrOIDCExchange)
+}
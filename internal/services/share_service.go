@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/policy"
+	"todo_app_go/internal/requestctx"
+	"todo_app_go/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidShare is returned for an unknown, revoked or expired share token, and when
+	// the shared todo no longer exists
+	ErrInvalidShare = errors.New("share link not found or expired")
+	// ErrShareNotFound is returned when the list has no active share with the ID
+	ErrShareNotFound = errors.New("share not found")
+	// ErrShareExpiryInPast is returned when a share is created already expired
+	ErrShareExpiryInPast = errors.New("expires_at must be in the future")
+	// ErrShareReadOnly is returned when a read-only share link is used to comment
+	ErrShareReadOnly = errors.New("share link does not allow comments")
+)
+
+// ShareService shares todos and todo lists through public links and keeps the comments
+// left on todos. Members are authorized the same way as in TodoService; the holder of a
+// link is limited to what the link's scope allows.
+type ShareService struct {
+	todos    *TodoService
+	shares   models.ShareRepository
+	comments models.CommentRepository
+	now      func() time.Time
+}
+
+func NewShareService(todos *TodoService, shares models.ShareRepository, comments models.CommentRepository) *ShareService {
+	return &ShareService{
+		todos:    todos,
+		shares:   shares,
+		comments: comments,
+		now:      time.Now,
+	}
+}
+
+// ShareTodo creates a link to a single todo. The token is set only on the returned value.
+func (s *ShareService) ShareTodo(ctx context.Context, todoID int64, req models.ShareCreateRequest) (_ *models.Share, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ShareService.ShareTodo", trace.WithAttributes(attribute.Int64("todo.id", todoID)))
+	defer func() { tracing.End(span, err) }()
+
+	return s.createShare(ctx, &todoID, req)
+}
+
+// ShareList creates a link to the whole todo list: the user's or the workspace's
+func (s *ShareService) ShareList(ctx context.Context, req models.ShareCreateRequest) (_ *models.Share, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ShareService.ShareList")
+	defer func() { tracing.End(span, err) }()
+
+	return s.createShare(ctx, nil, req)
+}
+
+func (s *ShareService) createShare(ctx context.Context, todoID *int64, req models.ShareCreateRequest) (*models.Share, error) {
+	ownerID, err := s.todos.authorize(ctx, policy.ActionTodoShare)
+	if err != nil {
+		metrics.ShareOperationsTotal.WithLabelValues("create", authzStatus(err)).Inc()
+		return nil, err
+	}
+	now := s.now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, ErrShareExpiryInPast
+	}
+	if todoID != nil {
+		todo, err := s.todos.repo.GetByID(ctx, ownerID, *todoID)
+		if err != nil {
+			return nil, err
+		}
+		if todo == nil {
+			metrics.ShareOperationsTotal.WithLabelValues("create", "not_found").Inc()
+			return nil, ErrTodoNotFound
+		}
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	share := &models.Share{
+		ID:        uuid.New().String(),
+		OwnerID:   ownerID,
+		TodoID:    todoID,
+		Scope:     req.Scope,
+		TokenHash: auth.HashToken(token),
+		CreatedBy: requestctx.UserID(ctx),
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: now,
+	}
+	if err := s.shares.CreateShare(ctx, share); err != nil {
+		metrics.ShareOperationsTotal.WithLabelValues("create", "error").Inc()
+		return nil, err
+	}
+
+	metrics.ShareOperationsTotal.WithLabelValues("create", "success").Inc()
+	logger.FromContext(ctx).Info("Share link created",
+		zap.String("share_id", share.ID),
+		zap.String("scope", share.Scope))
+	share.Token = token
+	return share, nil
+}
+
+// ListShares returns the links to the list and its todos, without their tokens
+func (s *ShareService) ListShares(ctx context.Context) (_ []models.Share, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ShareService.ListShares")
+	defer func() { tracing.End(span, err) }()
+
+	ownerID, err := s.todos.authorize(ctx, policy.ActionTodoShare)
+	if err != nil {
+		return nil, err
+	}
+	return s.shares.ListShares(ctx, ownerID)
+}
+
+// RevokeShare revokes a link to the list or one of its todos; the link stops working immediately
+func (s *ShareService) RevokeShare(ctx context.Context, id string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ShareService.RevokeShare")
+	defer func() { tracing.End(span, err) }()
+
+	ownerID, err := s.todos.authorize(ctx, policy.ActionTodoShare)
+	if err != nil {
+		metrics.ShareOperationsTotal.WithLabelValues("revoke", authzStatus(err)).Inc()
+		return err
+	}
+	revoked, err := s.shares.RevokeShare(ctx, ownerID, id)
+	if err != nil {
+		metrics.ShareOperationsTotal.WithLabelValues("revoke", "error").Inc()
+		return err
+	}
+	if !revoked {
+		metrics.ShareOperationsTotal.WithLabelValues("revoke", "not_found").Inc()
+		return ErrShareNotFound
+	}
+
+	metrics.ShareOperationsTotal.WithLabelValues("revoke", "success").Inc()
+	logger.FromContext(ctx).Info("Share link revoked", zap.String("share_id", id))
+	return nil
+}
+
+// ListComments returns the comments on a todo of the list
+func (s *ShareService) ListComments(ctx context.Context, todoID int64) (_ []models.Comment, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ShareService.ListComments", trace.WithAttributes(attribute.Int64("todo.id", todoID)))
+	defer func() { tracing.End(span, err) }()
+
+	ownerID, err := s.todos.authorize(ctx, policy.ActionTodoRead)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireTodo(ctx, ownerID, todoID); err != nil {
+		return nil, err
+	}
+	return s.comments.ListComments(ctx, ownerID, &todoID)
+}
+
+// AddComment adds the caller's comment to a todo of the list
+func (s *ShareService) AddComment(ctx context.Context, todoID int64, req models.CommentCreateRequest) (_ *models.Comment, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ShareService.AddComment", trace.WithAttributes(attribute.Int64("todo.id", todoID)))
+	defer func() { tracing.End(span, err) }()
+
+	ownerID, err := s.todos.authorize(ctx, policy.ActionTodoComment)
+	if err != nil {
+		metrics.ShareOperationsTotal.WithLabelValues("comment", authzStatus(err)).Inc()
+		return nil, err
+	}
+	if err := s.requireTodo(ctx, ownerID, todoID); err != nil {
+		return nil, err
+	}
+	return s.createComment(ctx, &models.Comment{
+		TodoID:  todoID,
+		OwnerID: ownerID,
+		Author:  requestctx.UserID(ctx),
+		Body:    req.Body,
+	})
+}
+
+// GetShared returns what the link gives access to: the shared todo or the whole list,
+// with the comments on it
+func (s *ShareService) GetShared(ctx context.Context, token string) (_ *models.SharedView, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ShareService.GetShared")
+	defer func() { tracing.End(span, err) }()
+
+	share, err := s.resolveShare(ctx, token)
+	if err != nil {
+		metrics.ShareOperationsTotal.WithLabelValues("view", shareStatus(err)).Inc()
+		return nil, err
+	}
+
+	var todos []models.Todo
+	if share.TodoID != nil {
+		todo, err := s.todos.repo.GetByID(ctx, share.OwnerID, *share.TodoID)
+		if err != nil {
+			return nil, err
+		}
+		if todo == nil {
+			metrics.ShareOperationsTotal.WithLabelValues("view", "invalid").Inc()
+			return nil, ErrInvalidShare
+		}
+		todos = []models.Todo{*todo}
+	} else if todos, err = s.todos.repo.GetAll(ctx, share.OwnerID); err != nil {
+		return nil, err
+	} else if todos == nil {
+		todos = []models.Todo{}
+	}
+	comments, err := s.comments.ListComments(ctx, share.OwnerID, share.TodoID)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.ShareOperationsTotal.WithLabelValues("view", "success").Inc()
+	return &models.SharedView{
+		Scope:     share.Scope,
+		ExpiresAt: share.ExpiresAt,
+		Todos:     todos,
+		Comments:  comments,
+	}, nil
+}
+
+// AddSharedComment adds a comment through a link with the comment scope
+func (s *ShareService) AddSharedComment(ctx context.Context, token string, req models.SharedCommentCreateRequest) (_ *models.Comment, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ShareService.AddSharedComment")
+	defer func() { tracing.End(span, err) }()
+
+	share, err := s.resolveShare(ctx, token)
+	if err == nil && share.Scope != models.ShareScopeComment {
+		err = ErrShareReadOnly
+	}
+	if err != nil {
+		metrics.ShareOperationsTotal.WithLabelValues("comment", shareStatus(err)).Inc()
+		return nil, err
+	}
+
+	// Ссылка на одну todo разрешает комментировать только её
+	todoID := req.TodoID
+	if share.TodoID != nil {
+		if todoID != 0 && todoID != *share.TodoID {
+			return nil, ErrTodoNotFound
+		}
+		todoID = *share.TodoID
+	}
+	if err := s.requireTodo(ctx, share.OwnerID, todoID); err != nil {
+		return nil, err
+	}
+	return s.createComment(ctx, &models.Comment{
+		TodoID:  todoID,
+		OwnerID: share.OwnerID,
+		Author:  req.Author,
+		ShareID: share.ID,
+		Body:    req.Body,
+	})
+}
+
+// resolveShare returns the active share with the token
+func (s *ShareService) resolveShare(ctx context.Context, token string) (*models.Share, error) {
+	share, err := s.shares.GetShareByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if share == nil || !share.Active(s.now()) {
+		return nil, ErrInvalidShare
+	}
+	return share, nil
+}
+
+func (s *ShareService) requireTodo(ctx context.Context, ownerID string, todoID int64) error {
+	todo, err := s.todos.repo.GetByID(ctx, ownerID, todoID)
+	if err != nil {
+		return err
+	}
+	if todo == nil {
+		return ErrTodoNotFound
+	}
+	return nil
+}
+
+func (s *ShareService) createComment(ctx context.Context, comment *models.Comment) (*models.Comment, error) {
+	comment.CreatedAt = s.now()
+	if err := s.comments.CreateComment(ctx, comment); err != nil {
+		metrics.ShareOperationsTotal.WithLabelValues("comment", "error").Inc()
+		return nil, err
+	}
+
+	metrics.ShareOperationsTotal.WithLabelValues("comment", "success").Inc()
+	logger.FromContext(ctx).Info("Comment added",
+		zap.Int64("todo_id", comment.TodoID),
+		zap.Int64("comment_id", comment.ID))
+	return comment, nil
+}
+
+// shareStatus returns the metric status of a rejected share token
+func shareStatus(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidShare):
+		return "invalid"
+	case errors.Is(err, ErrShareReadOnly):
+		return "forbidden"
+	}
+	return "error"
+}
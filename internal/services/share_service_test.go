@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"todo_app_go/internal/database"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/requestctx"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestShareService(t *testing.T) (*ShareService, *TodoService) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	require.NoError(t, database.EnsureTodosTableAndColumn(db))
+	require.NoError(t, database.EnsureShareTables(db))
+
+	todos := NewTodoService(models.NewSQLiteTodoRepository(db), nil, nil)
+	return NewShareService(todos, models.NewSQLiteShareRepository(db), models.NewSQLiteCommentRepository(db)), todos
+}
+
+func TestShareService_ExpiryAndRevocation(t *testing.T) {
+	service, todos := newTestShareService(t)
+	alice := requestctx.WithUserID(context.Background(), "alice")
+	bob := requestctx.WithUserID(context.Background(), "bob")
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	todo, err := todos.CreateTodo(alice, models.TodoCreateRequest{Task: "Shared"})
+	require.NoError(t, err)
+
+	past := now.Add(-time.Minute)
+	_, err = service.ShareTodo(alice, todo.ID, models.ShareCreateRequest{Scope: models.ShareScopeRead, ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrShareExpiryInPast)
+	// Чужую todo поделиться нельзя: для bob её не существует
+	_, err = service.ShareTodo(bob, todo.ID, models.ShareCreateRequest{Scope: models.ShareScopeRead})
+	assert.ErrorIs(t, err, ErrTodoNotFound)
+
+	expires := now.Add(time.Hour)
+	share, err := service.ShareTodo(alice, todo.ID, models.ShareCreateRequest{Scope: models.ShareScopeRead, ExpiresAt: &expires})
+	require.NoError(t, err)
+	require.NotEmpty(t, share.Token)
+
+	view, err := service.GetShared(context.Background(), share.Token)
+	require.NoError(t, err)
+	require.Len(t, view.Todos, 1)
+	assert.Equal(t, "Shared", view.Todos[0].Task)
+
+	_, err = service.AddSharedComment(context.Background(), share.Token, models.SharedCommentCreateRequest{Author: "guest", Body: "hi"})
+	assert.ErrorIs(t, err, ErrShareReadOnly)
+
+	// Истёкшая ссылка не работает
+	service.now = func() time.Time { return expires }
+	_, err = service.GetShared(context.Background(), share.Token)
+	assert.ErrorIs(t, err, ErrInvalidShare)
+	service.now = func() time.Time { return now }
+
+	// Токен в списке не возвращается; отозвать может только владелец списка
+	shares, err := service.ListShares(alice)
+	require.NoError(t, err)
+	require.Len(t, shares, 1)
+	assert.Empty(t, shares[0].Token)
+	assert.ErrorIs(t, service.RevokeShare(bob, share.ID), ErrShareNotFound)
+	require.NoError(t, service.RevokeShare(alice, share.ID))
+	_, err = service.GetShared(context.Background(), share.Token)
+	assert.ErrorIs(t, err, ErrInvalidShare)
+}
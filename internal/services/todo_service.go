@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"todo_app_go/internal/cache"
@@ -9,17 +11,32 @@ import (
 	"todo_app_go/internal/logger"
 	"todo_app_go/internal/metrics"
 	"todo_app_go/internal/models"
+	"todo_app_go/internal/policy"
+	"todo_app_go/internal/requestctx"
+	"todo_app_go/internal/tracing"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 type TodoService struct {
 	repo     models.TodoRepository
 	cache    *cache.RedisCache
-	producer *events.KafkaProducer
+	producer events.Publisher
+	outbox   models.OutboxTodoRepository
+	history  models.TodoHistoryRepository
+	authz    workspaceAuthorizer
 }
 
-func NewTodoService(repo models.TodoRepository, cache *cache.RedisCache, producer *events.KafkaProducer) *TodoService {
+var (
+	// ErrHistoryUnavailable is returned for time-travel queries when event sourcing is disabled
+	ErrHistoryUnavailable = errors.New("todo history requires event sourcing mode")
+	// ErrTodoNotFound is returned when the todo does not exist or belongs to another user
+	ErrTodoNotFound = errors.New("todo not found")
+)
+
+func NewTodoService(repo models.TodoRepository, cache *cache.RedisCache, producer events.Publisher) *TodoService {
 	return &TodoService{
 		repo:     repo,
 		cache:    cache,
@@ -27,14 +44,69 @@ func NewTodoService(repo models.TodoRepository, cache *cache.RedisCache, produce
 	}
 }
 
-func (s *TodoService) CreateTodo(ctx context.Context, req models.TodoCreateRequest) (*models.Todo, error) {
+// WithOutbox включает запись событий в outbox в одной транзакции с изменением todo.
+// Публикацией в шину событий в этом режиме занимается events.OutboxRelay.
+func (s *TodoService) WithOutbox(outbox models.OutboxTodoRepository) *TodoService {
+	s.outbox = outbox
+	return s
+}
+
+// WithWorkspaces включает todo общих пространств: запросы с requestctx.WorkspaceID
+// работают с todo пространства, если роль участника разрешает действие
+func (s *TodoService) WithWorkspaces(repo models.WorkspaceRepository, p *policy.Policy) *TodoService {
+	s.authz = workspaceAuthorizer{repo: repo, policy: p}
+	return s
+}
+
+// authorize checks that the caller may perform the action and returns the owner key of the
+// todos it acts on: the user ID for personal todos or the workspace for workspace todos
+func (s *TodoService) authorize(ctx context.Context, action policy.Action) (string, error) {
+	workspaceID := requestctx.WorkspaceID(ctx)
+	if workspaceID == "" {
+		// Личные todo: пользователь — владелец своего списка, без аутентификации список общий.
+		// ID пользователя не может совпасть с ключом пространства, даже если его пропустила аутентификация
+		userID := requestctx.UserID(ctx)
+		if models.IsWorkspaceOwner(userID) {
+			return "", fmt.Errorf("%w: user ID %q is reserved for workspaces", policy.ErrForbidden, userID)
+		}
+		return userID, s.authz.policy.Authorize(policy.RoleOwner, action)
+	}
+	if _, err := s.authz.authorize(ctx, workspaceID, action); err != nil {
+		return "", err
+	}
+	return models.WorkspaceOwner(workspaceID), nil
+}
+
+// WithHistory включает запросы состояния todo на момент времени (режим event sourcing)
+func (s *TodoService) WithHistory(history models.TodoHistoryRepository) *TodoService {
+	s.history = history
+	return s
+}
+
+func (s *TodoService) CreateTodo(ctx context.Context, req models.TodoCreateRequest) (_ *models.Todo, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TodoService.CreateTodo")
+	defer func() { tracing.End(span, err) }()
+
 	start := time.Now()
 	defer func() {
 		metrics.TodoOperationsDuration.WithLabelValues("create").Observe(time.Since(start).Seconds())
 	}()
 
+	ownerID, err := s.authorize(ctx, policy.ActionTodoCreate)
+	if err != nil {
+		metrics.TodoOperationsTotal.WithLabelValues("create", authzStatus(err)).Inc()
+		return nil, err
+	}
+
 	// Создаем todo в базе данных
-	todo, err := s.repo.Create(req.Task)
+	var todo *models.Todo
+	if s.outbox != nil {
+		todo, err = s.outbox.CreateWithEvent(ctx, ownerID, req.Task, func(t *models.Todo) (*models.OutboxMessage, error) {
+			return events.NewOutboxMessage(events.WithRequestMetadata(ctx, events.CreateTodoCreatedEvent(*t)))
+		})
+	} else {
+		todo, err = s.repo.Create(ctx, ownerID, req.Task)
+	}
 	if err != nil {
 		metrics.TodoOperationsTotal.WithLabelValues("create", "error").Inc()
 		return nil, err
@@ -42,45 +114,55 @@ func (s *TodoService) CreateTodo(ctx context.Context, req models.TodoCreateReque
 
 	// Сохраняем в кэш
 	if s.cache != nil {
-		if err := s.cache.SetTodo(todo, 30*time.Minute); err != nil {
-			logger.Warn("Failed to cache todo", zap.Error(err))
+		if err := s.cache.SetTodo(ctx, todo, 30*time.Minute); err != nil {
+			logger.FromContext(ctx).Warn("Failed to cache todo", zap.Error(err))
 		}
 		// Инвалидируем список todos
-		if err := s.cache.InvalidateTodos(); err != nil {
-			logger.Warn("Failed to invalidate todos cache", zap.Error(err))
+		if err := s.cache.InvalidateTodos(ctx, ownerID); err != nil {
+			logger.FromContext(ctx).Warn("Failed to invalidate todos cache", zap.Error(err))
 		}
 	}
 
 	// Публикуем событие
-	if s.producer != nil {
-		event := events.CreateTodoCreatedEvent(*todo)
-		if err := s.producer.PublishTodoEvent(event); err != nil {
-			logger.Error("Failed to publish todo created event", zap.Error(err))
+	if s.outbox == nil && s.producer != nil {
+		event := events.WithRequestMetadata(ctx, events.CreateTodoCreatedEvent(*todo))
+		if err := s.producer.Publish(ctx, event); err != nil {
+			logger.FromContext(ctx).Error("Failed to publish todo created event", zap.Error(err))
 		}
 	}
 
+	recordTodoCreated(todo)
 	metrics.TodoOperationsTotal.WithLabelValues("create", "success").Inc()
-	logger.Info("Todo created successfully", zap.Int64("todo_id", todo.ID))
+	logger.FromContext(ctx).Info("Todo created successfully", zap.Int64("todo_id", todo.ID))
 
 	return todo, nil
 }
 
-func (s *TodoService) GetTodo(ctx context.Context, id int64) (*models.Todo, error) {
+func (s *TodoService) GetTodo(ctx context.Context, id int64) (_ *models.Todo, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TodoService.GetTodo", trace.WithAttributes(attribute.Int64("todo.id", id)))
+	defer func() { tracing.End(span, err) }()
+
 	start := time.Now()
 	defer func() {
 		metrics.TodoOperationsDuration.WithLabelValues("get").Observe(time.Since(start).Seconds())
 	}()
 
+	ownerID, err := s.authorize(ctx, policy.ActionTodoRead)
+	if err != nil {
+		metrics.TodoOperationsTotal.WithLabelValues("get", authzStatus(err)).Inc()
+		return nil, err
+	}
+
 	// Пытаемся получить из кэша
 	if s.cache != nil {
-		if todo, err := s.cache.GetTodo(id); err == nil && todo != nil {
+		if todo, err := s.cache.GetTodo(ctx, ownerID, id); err == nil && todo != nil {
 			metrics.TodoOperationsTotal.WithLabelValues("get", "cache_hit").Inc()
 			return todo, nil
 		}
 	}
 
-	// Получаем из базы данных
-	todo, err := s.repo.GetByID(id)
+	// Получаем из базы данных; чужая todo для пользователя не существует
+	todo, err := s.repo.GetByID(ctx, ownerID, id)
 	if err != nil {
 		metrics.TodoOperationsTotal.WithLabelValues("get", "error").Inc()
 		return nil, err
@@ -93,8 +175,8 @@ func (s *TodoService) GetTodo(ctx context.Context, id int64) (*models.Todo, erro
 
 	// Сохраняем в кэш
 	if s.cache != nil {
-		if err := s.cache.SetTodo(todo, 30*time.Minute); err != nil {
-			logger.Warn("Failed to cache todo", zap.Error(err))
+		if err := s.cache.SetTodo(ctx, todo, 30*time.Minute); err != nil {
+			logger.FromContext(ctx).Warn("Failed to cache todo", zap.Error(err))
 		}
 	}
 
@@ -102,22 +184,67 @@ func (s *TodoService) GetTodo(ctx context.Context, id int64) (*models.Todo, erro
 	return todo, nil
 }
 
-func (s *TodoService) GetAllTodos(ctx context.Context) ([]models.Todo, error) {
+// GetTodoAsOf returns the todo as it was at the given time, replayed from the event log
+func (s *TodoService) GetTodoAsOf(ctx context.Context, id int64, asOf time.Time) (_ *models.Todo, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TodoService.GetTodoAsOf", trace.WithAttributes(attribute.Int64("todo.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	start := time.Now()
+	defer func() {
+		metrics.TodoOperationsDuration.WithLabelValues("get_as_of").Observe(time.Since(start).Seconds())
+	}()
+
+	if s.history == nil {
+		return nil, ErrHistoryUnavailable
+	}
+
+	ownerID, err := s.authorize(ctx, policy.ActionTodoRead)
+	if err != nil {
+		metrics.TodoOperationsTotal.WithLabelValues("get_as_of", authzStatus(err)).Inc()
+		return nil, err
+	}
+
+	// Исторические состояния не кэшируются: они не меняются, но запрашиваются редко
+	todo, err := s.history.GetByIDAsOf(ctx, ownerID, id, asOf)
+	if err != nil {
+		metrics.TodoOperationsTotal.WithLabelValues("get_as_of", "error").Inc()
+		return nil, err
+	}
+
+	if todo == nil {
+		metrics.TodoOperationsTotal.WithLabelValues("get_as_of", "not_found").Inc()
+		return nil, nil
+	}
+
+	metrics.TodoOperationsTotal.WithLabelValues("get_as_of", "success").Inc()
+	return todo, nil
+}
+
+func (s *TodoService) GetAllTodos(ctx context.Context) (_ []models.Todo, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TodoService.GetAllTodos")
+	defer func() { tracing.End(span, err) }()
+
 	start := time.Now()
 	defer func() {
 		metrics.TodoOperationsDuration.WithLabelValues("get_all").Observe(time.Since(start).Seconds())
 	}()
 
+	ownerID, err := s.authorize(ctx, policy.ActionTodoRead)
+	if err != nil {
+		metrics.TodoOperationsTotal.WithLabelValues("get_all", authzStatus(err)).Inc()
+		return nil, err
+	}
+
 	// Пытаемся получить из кэша
 	if s.cache != nil {
-		if todos, err := s.cache.GetTodos(); err == nil && todos != nil {
+		if todos, err := s.cache.GetTodos(ctx, ownerID); err == nil && todos != nil {
 			metrics.TodoOperationsTotal.WithLabelValues("get_all", "cache_hit").Inc()
 			return todos, nil
 		}
 	}
 
 	// Получаем из базы данных
-	todos, err := s.repo.GetAll()
+	todos, err := s.repo.GetAll(ctx, ownerID)
 	if err != nil {
 		metrics.TodoOperationsTotal.WithLabelValues("get_all", "error").Inc()
 		return nil, err
@@ -125,8 +252,8 @@ func (s *TodoService) GetAllTodos(ctx context.Context) ([]models.Todo, error) {
 
 	// Сохраняем в кэш
 	if s.cache != nil {
-		if err := s.cache.SetTodos(todos, 5*time.Minute); err != nil {
-			logger.Warn("Failed to cache todos", zap.Error(err))
+		if err := s.cache.SetTodos(ctx, ownerID, todos, 5*time.Minute); err != nil {
+			logger.FromContext(ctx).Warn("Failed to cache todos", zap.Error(err))
 		}
 	}
 
@@ -134,14 +261,34 @@ func (s *TodoService) GetAllTodos(ctx context.Context) ([]models.Todo, error) {
 	return todos, nil
 }
 
-func (s *TodoService) UpdateTodo(ctx context.Context, id int64, req models.TodoUpdateRequest) (*models.Todo, error) {
+func (s *TodoService) UpdateTodo(ctx context.Context, id int64, req models.TodoUpdateRequest) (_ *models.Todo, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TodoService.UpdateTodo", trace.WithAttributes(attribute.Int64("todo.id", id)))
+	defer func() { tracing.End(span, err) }()
+
 	start := time.Now()
 	defer func() {
 		metrics.TodoOperationsDuration.WithLabelValues("update").Observe(time.Since(start).Seconds())
 	}()
 
-	// Обновляем в базе данных
-	todo, err := s.repo.Update(id, req)
+	ownerID, err := s.authorize(ctx, policy.ActionTodoUpdate)
+	if err != nil {
+		metrics.TodoOperationsTotal.WithLabelValues("update", authzStatus(err)).Inc()
+		return nil, err
+	}
+
+	// Обновляем в базе данных. Состояние до обновления нужно для списка изменений в событии
+	var before, todo *models.Todo
+	if s.outbox != nil {
+		todo, err = s.outbox.UpdateWithEvent(ctx, ownerID, id, req, func(old, updated *models.Todo) (*models.OutboxMessage, error) {
+			before = old
+			return events.NewOutboxMessage(events.WithRequestMetadata(ctx, events.CreateTodoUpdatedEvent(*old, *updated)))
+		})
+	} else {
+		before, err = s.repo.GetByID(ctx, ownerID, id)
+		if err == nil && before != nil {
+			todo, err = s.repo.Update(ctx, ownerID, id, req)
+		}
+	}
 	if err != nil {
 		metrics.TodoOperationsTotal.WithLabelValues("update", "error").Inc()
 		return nil, err
@@ -154,37 +301,64 @@ func (s *TodoService) UpdateTodo(ctx context.Context, id int64, req models.TodoU
 
 	// Обновляем кэш
 	if s.cache != nil {
-		if err := s.cache.SetTodo(todo, 30*time.Minute); err != nil {
-			logger.Warn("Failed to cache updated todo", zap.Error(err))
+		if err := s.cache.SetTodo(ctx, todo, 30*time.Minute); err != nil {
+			logger.FromContext(ctx).Warn("Failed to cache updated todo", zap.Error(err))
 		}
 		// Инвалидируем список todos
-		if err := s.cache.InvalidateTodos(); err != nil {
-			logger.Warn("Failed to invalidate todos cache", zap.Error(err))
+		if err := s.cache.InvalidateTodos(ctx, ownerID); err != nil {
+			logger.FromContext(ctx).Warn("Failed to invalidate todos cache", zap.Error(err))
 		}
 	}
 
 	// Публикуем событие
-	if s.producer != nil {
-		event := events.CreateTodoUpdatedEvent(*todo)
-		if err := s.producer.PublishTodoEvent(event); err != nil {
-			logger.Error("Failed to publish todo updated event", zap.Error(err))
+	if s.outbox == nil && s.producer != nil {
+		event := events.WithRequestMetadata(ctx, events.CreateTodoUpdatedEvent(*before, *todo))
+		if err := s.producer.Publish(ctx, event); err != nil {
+			logger.FromContext(ctx).Error("Failed to publish todo updated event", zap.Error(err))
 		}
 	}
 
+	recordTodoUpdated(before, todo)
 	metrics.TodoOperationsTotal.WithLabelValues("update", "success").Inc()
-	logger.Info("Todo updated successfully", zap.Int64("todo_id", todo.ID))
+	logger.FromContext(ctx).Info("Todo updated successfully", zap.Int64("todo_id", todo.ID))
 
 	return todo, nil
 }
 
-func (s *TodoService) DeleteTodo(ctx context.Context, id int64) error {
+func (s *TodoService) DeleteTodo(ctx context.Context, id int64) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TodoService.DeleteTodo", trace.WithAttributes(attribute.Int64("todo.id", id)))
+	defer func() { tracing.End(span, err) }()
+
 	start := time.Now()
 	defer func() {
 		metrics.TodoOperationsDuration.WithLabelValues("delete").Observe(time.Since(start).Seconds())
 	}()
 
+	ownerID, err := s.authorize(ctx, policy.ActionTodoDelete)
+	if err != nil {
+		metrics.TodoOperationsTotal.WithLabelValues("delete", authzStatus(err)).Inc()
+		return err
+	}
+
+	// Статус удаляемой todo нужен для бизнес-метрик
+	before, err := s.repo.GetByID(ctx, ownerID, id)
+	if err != nil {
+		metrics.TodoOperationsTotal.WithLabelValues("delete", "error").Inc()
+		return err
+	}
+	if before == nil {
+		metrics.TodoOperationsTotal.WithLabelValues("delete", "not_found").Inc()
+		return ErrTodoNotFound
+	}
+
 	// Удаляем из базы данных
-	err := s.repo.Delete(id)
+	if s.outbox != nil {
+		err = s.outbox.DeleteWithEvent(ctx, ownerID, id, func(t *models.Todo) (*models.OutboxMessage, error) {
+			return events.NewOutboxMessage(events.WithRequestMetadata(ctx, events.CreateTodoDeletedEvent(t.ID, t.OwnerID)))
+		})
+	} else {
+		err = s.repo.Delete(ctx, ownerID, id)
+	}
 	if err != nil {
 		metrics.TodoOperationsTotal.WithLabelValues("delete", "error").Inc()
 		return err
@@ -192,25 +366,26 @@ func (s *TodoService) DeleteTodo(ctx context.Context, id int64) error {
 
 	// Удаляем из кэша
 	if s.cache != nil {
-		if err := s.cache.DeleteTodo(id); err != nil {
-			logger.Warn("Failed to delete todo from cache", zap.Error(err))
+		if err := s.cache.DeleteTodo(ctx, ownerID, id); err != nil {
+			logger.FromContext(ctx).Warn("Failed to delete todo from cache", zap.Error(err))
 		}
 		// Инвалидируем список todos
-		if err := s.cache.InvalidateTodos(); err != nil {
-			logger.Warn("Failed to invalidate todos cache", zap.Error(err))
+		if err := s.cache.InvalidateTodos(ctx, ownerID); err != nil {
+			logger.FromContext(ctx).Warn("Failed to invalidate todos cache", zap.Error(err))
 		}
 	}
 
 	// Публикуем событие
-	if s.producer != nil {
-		event := events.CreateTodoDeletedEvent(id)
-		if err := s.producer.PublishTodoEvent(event); err != nil {
-			logger.Error("Failed to publish todo deleted event", zap.Error(err))
+	if s.outbox == nil && s.producer != nil {
+		event := events.WithRequestMetadata(ctx, events.CreateTodoDeletedEvent(id, ownerID))
+		if err := s.producer.Publish(ctx, event); err != nil {
+			logger.FromContext(ctx).Error("Failed to publish todo deleted event", zap.Error(err))
 		}
 	}
 
+	recordTodoDeleted(before)
 	metrics.TodoOperationsTotal.WithLabelValues("delete", "success").Inc()
-	logger.Info("Todo deleted successfully", zap.Int64("todo_id", id))
+	logger.FromContext(ctx).Info("Todo deleted successfully", zap.Int64("todo_id", id))
 
 	return nil
 }
@@ -4,8 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
+	"todo_app_go/internal/database"
 	"todo_app_go/internal/models"
+	"todo_app_go/internal/requestctx"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
@@ -18,6 +21,7 @@ func TestTodoService_SQLiteIntegration(t *testing.T) {
 
 	_, err = db.Exec(`CREATE TABLE todos (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		owner_id TEXT NOT NULL DEFAULT '',
 		task TEXT NOT NULL,
 		completed BOOLEAN NOT NULL,
 		created_at DATETIME NOT NULL,
@@ -56,3 +60,90 @@ func TestTodoService_SQLiteIntegration(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Nil(t, deleted)
 }
+
+func TestTodoService_OutboxIntegration(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	assert.NoError(t, database.EnsureTodosTableAndColumn(db))
+	assert.NoError(t, database.EnsureOutboxTable(db))
+
+	repo := models.NewSQLiteTodoRepository(db)
+	service := NewTodoService(repo, nil, nil).WithOutbox(repo)
+
+	ctx := context.Background()
+	todo, err := service.CreateTodo(ctx, models.TodoCreateRequest{Task: "Outbox task"})
+	assert.NoError(t, err)
+
+	done := true
+	_, err = service.UpdateTodo(ctx, todo.ID, models.TodoUpdateRequest{Completed: &done})
+	assert.NoError(t, err)
+	assert.NoError(t, service.DeleteTodo(ctx, todo.ID))
+
+	store := models.NewSQLiteOutboxStore(db)
+	pending, err := store.FetchPending(10, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, pending, 3)
+	assert.Equal(t, "created", pending[0].EventType)
+	assert.Equal(t, "completed", pending[1].EventType)
+	assert.Equal(t, "deleted", pending[2].EventType)
+	assert.Equal(t, todo.ID, pending[0].AggregateID)
+
+	assert.NoError(t, store.MarkSent(pending[0].ID, time.Now()))
+	stats, err := store.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Pending)
+	assert.NotNil(t, stats.OldestCreatedAt)
+}
+
+func TestTodoService_OwnerIsolation(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	assert.NoError(t, database.EnsureTodosTableAndColumn(db))
+	assert.NoError(t, database.EnsureOutboxTable(db))
+
+	repo := models.NewSQLiteTodoRepository(db)
+	service := NewTodoService(repo, nil, nil).WithOutbox(repo)
+
+	alice := requestctx.WithUserID(context.Background(), "alice")
+	bob := requestctx.WithUserID(context.Background(), "bob")
+
+	todo, err := service.CreateTodo(alice, models.TodoCreateRequest{Task: "Alice's task"})
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", todo.OwnerID)
+
+	// Чужая todo для bob не существует
+	got, err := service.GetTodo(bob, todo.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	todos, err := service.GetAllTodos(bob)
+	assert.NoError(t, err)
+	assert.Empty(t, todos)
+
+	task := "Hijacked"
+	updated, err := service.UpdateTodo(bob, todo.ID, models.TodoUpdateRequest{Task: &task})
+	assert.NoError(t, err)
+	assert.Nil(t, updated)
+
+	assert.ErrorIs(t, service.DeleteTodo(bob, todo.ID), ErrTodoNotFound)
+
+	// Владелец видит todo без изменений, а события несут владельца
+	got, err = service.GetTodo(alice, todo.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice's task", got.Task)
+
+	todos, err = service.GetAllTodos(alice)
+	assert.NoError(t, err)
+	assert.Len(t, todos, 1)
+
+	pending, err := models.NewSQLiteOutboxStore(db).FetchPending(10, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	assert.Contains(t, string(pending[0].Payload), `"owner_id":"alice"`)
+}
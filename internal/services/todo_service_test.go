@@ -5,21 +5,25 @@ import (
 	"testing"
 	"time"
 
+	"todo_app_go/internal/config"
 	"todo_app_go/internal/logger"
 	"todo_app_go/internal/models"
+	"todo_app_go/internal/policy"
+	"todo_app_go/internal/requestctx"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func init() {
-	_ = logger.Init("debug", "console")
+	_ = logger.Init(config.LogConfig{Level: "debug", Format: "console"})
 }
 
 type mockRepo struct{}
 
-func (m *mockRepo) Create(task string) (*models.Todo, error) {
+func (m *mockRepo) Create(ctx context.Context, ownerID, task string) (*models.Todo, error) {
 	return &models.Todo{
 		ID:        1,
+		OwnerID:   ownerID,
 		Task:      task,
 		Completed: false,
 		CreatedAt: time.Now(),
@@ -27,7 +31,7 @@ func (m *mockRepo) Create(task string) (*models.Todo, error) {
 	}, nil
 }
 
-func (m *mockRepo) GetByID(id int64) (*models.Todo, error) {
+func (m *mockRepo) GetByID(ctx context.Context, ownerID string, id int64) (*models.Todo, error) {
 	if id == 42 {
 		return &models.Todo{ID: 42, Task: "Answer"}, nil
 	}
@@ -40,8 +44,10 @@ func (m *mockRepo) GetByID(id int64) (*models.Todo, error) {
 	return nil, nil
 }
 
-func (m *mockRepo) GetAll() ([]models.Todo, error) { return nil, nil }
-func (m *mockRepo) Update(id int64, req models.TodoUpdateRequest) (*models.Todo, error) {
+func (m *mockRepo) GetAll(ctx context.Context, ownerID string) ([]models.Todo, error) {
+	return nil, nil
+}
+func (m *mockRepo) Update(ctx context.Context, ownerID string, id int64, req models.TodoUpdateRequest) (*models.Todo, error) {
 	if id == 42 {
 		return &models.Todo{ID: 42, Task: "Updated"}, nil
 	}
@@ -53,8 +59,10 @@ func (m *mockRepo) Update(id int64, req models.TodoUpdateRequest) (*models.Todo,
 	}
 	return nil, nil
 }
-func (m *mockRepo) UpdateStatus(id int64, completed bool) error { return nil }
-func (m *mockRepo) Delete(id int64) error {
+func (m *mockRepo) UpdateStatus(ctx context.Context, ownerID string, id int64, completed bool) error {
+	return nil
+}
+func (m *mockRepo) Delete(ctx context.Context, ownerID string, id int64) error {
 	if id == 500 {
 		return assert.AnError
 	}
@@ -72,6 +80,17 @@ func TestCreateTodo(t *testing.T) {
 	assert.Equal(t, int64(1), todo.ID)
 }
 
+func TestTodoService_RejectsWorkspaceUserID(t *testing.T) {
+	service := &TodoService{repo: &mockRepo{}}
+	// Пользователь с ID-ключом пространства не получает todo пространства как личные
+	ctx := requestctx.WithUserID(context.Background(), models.WorkspaceOwner("team"))
+
+	_, err := service.CreateTodo(ctx, models.TodoCreateRequest{Task: "Spoofed"})
+	assert.ErrorIs(t, err, policy.ErrForbidden)
+	_, err = service.GetAllTodos(ctx)
+	assert.ErrorIs(t, err, policy.ErrForbidden)
+}
+
 func TestGetTodo_Success(t *testing.T) {
 	repo := &mockRepo{}
 	service := &TodoService{repo: repo}
@@ -128,10 +147,17 @@ func TestUpdateTodo_Error(t *testing.T) {
 func TestDeleteTodo_Success(t *testing.T) {
 	repo := &mockRepo{}
 	service := &TodoService{repo: repo}
-	err := service.DeleteTodo(context.Background(), 1)
+	err := service.DeleteTodo(context.Background(), 42)
 	assert.NoError(t, err)
 }
 
+func TestDeleteTodo_NotFound(t *testing.T) {
+	repo := &mockRepo{}
+	service := &TodoService{repo: repo}
+	err := service.DeleteTodo(context.Background(), 99)
+	assert.ErrorIs(t, err, ErrTodoNotFound)
+}
+
 func TestDeleteTodo_Error(t *testing.T) {
 	repo := &mockRepo{}
 	service := &TodoService{repo: repo}
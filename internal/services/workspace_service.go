@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo_app_go/internal/auth"
+	"todo_app_go/internal/logger"
+	"todo_app_go/internal/metrics"
+	"todo_app_go/internal/models"
+	"todo_app_go/internal/policy"
+	"todo_app_go/internal/requestctx"
+	"todo_app_go/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// inviteTTL — сколько действует приглашение в пространство
+const inviteTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrWorkspaceNotFound is returned when the workspace does not exist or the caller is not
+	// a member; non-members cannot tell the two apart
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	// ErrMemberNotFound is returned when the user is not a member of the workspace
+	ErrMemberNotFound = errors.New("member not found")
+	// ErrInvalidInvite is returned for an unknown, accepted or expired invite token
+	ErrInvalidInvite = errors.New("invalid or expired invite")
+)
+
+// workspaceAuthorizer resolves the caller's role in a workspace and checks it against the policy
+type workspaceAuthorizer struct {
+	repo   models.WorkspaceRepository
+	policy *policy.Policy
+}
+
+// authorize returns the caller's role if it allows the action. A caller who is not a member
+// gets ErrWorkspaceNotFound, a member whose role does not allow the action gets policy.ErrForbidden.
+func (a workspaceAuthorizer) authorize(ctx context.Context, workspaceID string, action policy.Action) (policy.Role, error) {
+	if a.repo == nil {
+		return "", ErrWorkspaceNotFound
+	}
+	role, err := a.repo.GetMemberRole(ctx, workspaceID, requestctx.UserID(ctx))
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", ErrWorkspaceNotFound
+	}
+	if err := a.policy.Authorize(policy.Role(role), action); err != nil {
+		return "", err
+	}
+	return policy.Role(role), nil
+}
+
+// WorkspaceService manages workspaces, their members and invites. Every method that acts on
+// an existing workspace authorizes the caller with the policy.
+type WorkspaceService struct {
+	repo  models.WorkspaceRepository
+	authz workspaceAuthorizer
+}
+
+func NewWorkspaceService(repo models.WorkspaceRepository, p *policy.Policy) *WorkspaceService {
+	return &WorkspaceService{
+		repo:  repo,
+		authz: workspaceAuthorizer{repo: repo, policy: p},
+	}
+}
+
+// CreateWorkspace creates a workspace owned by the caller
+func (s *WorkspaceService) CreateWorkspace(ctx context.Context, req models.WorkspaceCreateRequest) (_ *models.Workspace, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WorkspaceService.CreateWorkspace")
+	defer func() { tracing.End(span, err) }()
+
+	workspace := &models.Workspace{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		CreatedBy: requestctx.UserID(ctx),
+		CreatedAt: time.Now(),
+		Role:      string(policy.RoleOwner),
+	}
+	if err := s.repo.CreateWorkspace(ctx, workspace); err != nil {
+		metrics.WorkspaceOperationsTotal.WithLabelValues("create", "error").Inc()
+		return nil, err
+	}
+
+	metrics.WorkspaceOperationsTotal.WithLabelValues("create", "success").Inc()
+	logger.FromContext(ctx).Info("Workspace created", zap.String("workspace_id", workspace.ID))
+	return workspace, nil
+}
+
+// ListWorkspaces returns the caller's workspaces with the caller's role
+func (s *WorkspaceService) ListWorkspaces(ctx context.Context) (_ []models.Workspace, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WorkspaceService.ListWorkspaces")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.ListWorkspaces(ctx, requestctx.UserID(ctx))
+}
+
+// ListMembers returns the members of the workspace
+func (s *WorkspaceService) ListMembers(ctx context.Context, workspaceID string) (_ []models.WorkspaceMember, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WorkspaceService.ListMembers")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.authz.authorize(ctx, workspaceID, policy.ActionWorkspaceRead); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, workspaceID)
+}
+
+// CreateInvite creates a one-time invite with the role. The token is set only on the
+// returned value and must be passed to the invitee out of band.
+func (s *WorkspaceService) CreateInvite(ctx context.Context, workspaceID string, req models.InviteCreateRequest) (_ *models.WorkspaceInvite, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WorkspaceService.CreateInvite")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.authz.authorize(ctx, workspaceID, policy.ActionMemberInvite); err != nil {
+		metrics.WorkspaceOperationsTotal.WithLabelValues("invite", authzStatus(err)).Inc()
+		return nil, err
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	invite := &models.WorkspaceInvite{
+		ID:          uuid.New().String(),
+		WorkspaceID: workspaceID,
+		Role:        req.Role,
+		InvitedBy:   requestctx.UserID(ctx),
+		TokenHash:   auth.HashToken(token),
+		ExpiresAt:   now.Add(inviteTTL),
+		CreatedAt:   now,
+	}
+	if err := s.repo.CreateInvite(ctx, invite); err != nil {
+		metrics.WorkspaceOperationsTotal.WithLabelValues("invite", "error").Inc()
+		return nil, err
+	}
+
+	metrics.WorkspaceOperationsTotal.WithLabelValues("invite", "success").Inc()
+	logger.FromContext(ctx).Info("Workspace invite created",
+		zap.String("invite_id", invite.ID),
+		zap.String("role", invite.Role))
+	invite.Token = token
+	return invite, nil
+}
+
+// AcceptInvite adds the caller to the invite's workspace
+func (s *WorkspaceService) AcceptInvite(ctx context.Context, token string) (_ *models.WorkspaceInvite, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WorkspaceService.AcceptInvite")
+	defer func() { tracing.End(span, err) }()
+
+	invite, err := s.repo.AcceptInvite(ctx, auth.HashToken(token), requestctx.UserID(ctx))
+	if err != nil {
+		metrics.WorkspaceOperationsTotal.WithLabelValues("accept", "error").Inc()
+		return nil, err
+	}
+	if invite == nil {
+		metrics.WorkspaceOperationsTotal.WithLabelValues("accept", "invalid").Inc()
+		return nil, ErrInvalidInvite
+	}
+
+	metrics.WorkspaceOperationsTotal.WithLabelValues("accept", "success").Inc()
+	logger.FromContext(ctx).Info("Workspace invite accepted",
+		zap.String("invite_id", invite.ID),
+		zap.String("workspace_id", invite.WorkspaceID))
+	return invite, nil
+}
+
+// UpdateMemberRole changes the role of a member; a workspace always keeps at least one owner
+func (s *WorkspaceService) UpdateMemberRole(ctx context.Context, workspaceID, userID string, req models.MemberUpdateRequest) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WorkspaceService.UpdateMemberRole")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.authz.authorize(ctx, workspaceID, policy.ActionMemberUpdate); err != nil {
+		metrics.WorkspaceOperationsTotal.WithLabelValues("update_role", authzStatus(err)).Inc()
+		return err
+	}
+	return s.changeMember(ctx, "update_role", func() (bool, error) {
+		return s.repo.UpdateMemberRole(ctx, workspaceID, userID, req.Role)
+	})
+}
+
+// RemoveMember removes a member from the workspace. Any member may leave on their own.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, workspaceID, userID string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WorkspaceService.RemoveMember")
+	defer func() { tracing.End(span, err) }()
+
+	action := policy.ActionMemberRemove
+	if userID == requestctx.UserID(ctx) {
+		action = policy.ActionWorkspaceRead
+	}
+	if _, err := s.authz.authorize(ctx, workspaceID, action); err != nil {
+		metrics.WorkspaceOperationsTotal.WithLabelValues("remove_member", authzStatus(err)).Inc()
+		return err
+	}
+	return s.changeMember(ctx, "remove_member", func() (bool, error) {
+		return s.repo.RemoveMember(ctx, workspaceID, userID)
+	})
+}
+
+func (s *WorkspaceService) changeMember(ctx context.Context, operation string, change func() (bool, error)) error {
+	found, err := change()
+	switch {
+	case errors.Is(err, models.ErrLastOwner):
+		metrics.WorkspaceOperationsTotal.WithLabelValues(operation, "last_owner").Inc()
+		return err
+	case err != nil:
+		metrics.WorkspaceOperationsTotal.WithLabelValues(operation, "error").Inc()
+		return err
+	case !found:
+		metrics.WorkspaceOperationsTotal.WithLabelValues(operation, "not_found").Inc()
+		return ErrMemberNotFound
+	}
+
+	metrics.WorkspaceOperationsTotal.WithLabelValues(operation, "success").Inc()
+	return nil
+}
+
+// authzStatus returns the metric status of an authorization failure
+func authzStatus(err error) string {
+	switch {
+	case errors.Is(err, policy.ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, ErrWorkspaceNotFound):
+		return "not_found"
+	}
+	return "error"
+}